@@ -3,6 +3,7 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
 )
 
 type Config struct {
@@ -10,6 +11,10 @@ type Config struct {
 	DBPath      string
 	UploadDir   string
 	GenerateDir string
+
+	WorkerPoolSize        int // 同时执行的任务数
+	DataSourceConcurrency int // 同时使用同一数据源的任务数，数据源可单独设置
+	LogRetentionDays      int // 执行日志的保留天数，0表示不清理
 }
 
 var AppConfig *Config
@@ -20,6 +25,10 @@ func InitConfig() {
 		DBPath:      getEnv("DB_PATH", "./data.db"),
 		UploadDir:   getEnv("UPLOAD_DIR", "./uploads"),
 		GenerateDir: getEnv("GENERATE_DIR", "./generate_files"),
+
+		WorkerPoolSize:        getEnvInt("WORKER_POOL_SIZE", 4),
+		DataSourceConcurrency: getEnvInt("DATASOURCE_CONCURRENCY", 2),
+		LogRetentionDays:      getEnvInt("LOG_RETENTION_DAYS", 30),
 	}
 
 	// 创建上传目录
@@ -41,3 +50,10 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil && value > 0 {
+		return value
+	}
+	return defaultValue
+}
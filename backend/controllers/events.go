@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"generateTestData/backend/services"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 心跳间隔，避免代理关闭空闲连接
+const eventHeartbeatInterval = 15 * time.Second
+
+// 以 Server-Sent Events 推送一个任务的状态、阶段、进度、警告和结果，连接建立时先推送任务当前状态
+func (c *TaskController) Events(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "无效的ID"})
+		return
+	}
+
+	// 先订阅再读取当前状态，避免错过两者之间的事件
+	events, unsubscribe := services.SubscribeTaskEvents(uint(id))
+	defer unsubscribe()
+	task, err := c.taskService.GetTaskStatus(uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return
+	}
+
+	current := services.TaskEvent{
+		Type:     services.TaskEventStatus,
+		TaskID:   task.ID,
+		Status:   task.Status,
+		Progress: task.Progress,
+		Message:  task.ErrorMsg,
+		Time:     time.Now(),
+	}
+	streamEvents(ctx, events, current)
+}
+
+// 以 Server-Sent Events 推送全部任务的事件，供任务列表和仪表盘使用
+func (c *TaskController) AllEvents(ctx *gin.Context) {
+	events, unsubscribe := services.SubscribeTaskEvents(0)
+	defer unsubscribe()
+	streamEvents(ctx, events)
+}
+
+// 写出事件流，直到客户端断开
+func streamEvents(ctx *gin.Context, events <-chan services.TaskEvent, initial ...services.TaskEvent) {
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+
+	for _, event := range initial {
+		ctx.SSEvent(event.Type, event)
+	}
+	ctx.Writer.Flush()
+
+	heartbeat := time.NewTicker(eventHeartbeatInterval)
+	defer heartbeat.Stop()
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Request.Context().Done():
+			return false
+		case event := <-events:
+			ctx.SSEvent(event.Type, event)
+		case <-heartbeat.C:
+			// 以冒号开头的注释行，客户端会忽略
+			w.Write([]byte(": ping\n\n"))
+		}
+		return true
+	})
+}
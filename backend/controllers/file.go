@@ -1,9 +1,11 @@
 package controllers
 
 import (
+	"generateTestData/backend/config"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -39,4 +41,32 @@ func (c *FileController) Download(ctx *gin.Context) {
 
 	// 发送文件
 	ctx.File(filePath)
-}
\ No newline at end of file
+}
+
+// 允许上传的结构定义文件类型
+var uploadExtensions = map[string]bool{
+	".proto": true, // Protobuf 输出的消息定义
+	".avsc":  true, // Avro 输出的结构定义
+}
+
+// 上传结构定义文件，保存到上传目录，同名文件会被覆盖
+func (c *FileController) Upload(ctx *gin.Context) {
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "请选择要上传的文件"})
+		return
+	}
+
+	filename := filepath.Base(fileHeader.Filename)
+	if !uploadExtensions[strings.ToLower(filepath.Ext(filename))] {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "不支持的文件类型，仅支持 .proto 和 .avsc"})
+		return
+	}
+
+	if err := ctx.SaveUploadedFile(fileHeader, filepath.Join(config.AppConfig.UploadDir, filename)); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "保存文件失败: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": gin.H{"filename": filename}})
+}
@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"generateTestData/backend/services"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GenerateController struct {
+	taskService *services.TaskService
+}
+
+func NewGenerateController() *GenerateController {
+	return &GenerateController{
+		taskService: services.NewTaskService(),
+	}
+}
+
+// 流式生成数据，以分块传输直接返回 NDJSON、CSV 或 SQL，不保存任务也不写文件。
+// 输出开始后无法再修改状态码，生成的记录数和错误信息通过响应尾部的 X-Generated-Count、X-Generate-Error 返回。
+func (c *GenerateController) Stream(ctx *gin.Context) {
+	var req services.StreamRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	stream, err := c.taskService.NewGenerationStream(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Header("Content-Type", stream.ContentType())
+	ctx.Header("X-Content-Type-Options", "nosniff")
+	ctx.Header("Trailer", "X-Generated-Count, X-Generate-Error")
+	ctx.Status(http.StatusOK)
+
+	count, err := stream.Run(ctx.Request.Context(), ctx.Writer, ctx.Writer.Flush)
+	ctx.Writer.Header().Set("X-Generated-Count", strconv.FormatInt(count, 10))
+	if err != nil {
+		// 客户端断开时只记录日志
+		if ctx.Request.Context().Err() == nil {
+			ctx.Writer.Header().Set("X-Generate-Error", err.Error())
+		}
+		log.Printf("流式生成中止，已输出 %d 条: %v", count, err)
+	}
+}
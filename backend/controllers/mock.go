@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"errors"
+	"generateTestData/backend/services"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MockController struct {
+	mockService *services.MockService
+}
+
+func NewMockController() *MockController {
+	return &MockController{
+		mockService: services.NewMockService(),
+	}
+}
+
+// 获取已发布的数据集
+func (c *MockController) Collections(ctx *gin.Context) {
+	collections, err := c.mockService.Collections()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取数据集失败: " + err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": collections})
+}
+
+// 删除数据集
+func (c *MockController) DeleteCollection(ctx *gin.Context) {
+	if err := c.mockService.DeleteCollection(ctx.Param("name")); err != nil {
+		c.fail(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "删除成功"})
+}
+
+// 模拟延迟和错误，数据集不存在或注入错误时中止请求
+func (c *MockController) Simulate(ctx *gin.Context) {
+	status, err := c.mockService.Simulate(ctx.Param("name"))
+	if err != nil {
+		c.fail(ctx, err)
+		ctx.Abort()
+		return
+	}
+	if status != 0 {
+		ctx.AbortWithStatusJSON(status, gin.H{"error": "模拟错误"})
+	}
+}
+
+// 查询记录，总数通过 X-Total-Count 响应头返回
+func (c *MockController) List(ctx *gin.Context) {
+	records, total, err := c.mockService.List(ctx.Param("name"), ctx.Request.URL.Query())
+	if err != nil {
+		c.fail(ctx, err)
+		return
+	}
+	ctx.Header("X-Total-Count", strconv.Itoa(total))
+	ctx.Header("Access-Control-Expose-Headers", "X-Total-Count")
+	ctx.JSON(http.StatusOK, records)
+}
+
+// 获取单条记录
+func (c *MockController) Get(ctx *gin.Context) {
+	record, err := c.mockService.Get(ctx.Param("name"), ctx.Param("id"))
+	if err != nil {
+		c.fail(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, record)
+}
+
+// 新增记录
+func (c *MockController) Create(ctx *gin.Context) {
+	body, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	record, err := c.mockService.Create(ctx.Param("name"), body)
+	if err != nil {
+		c.fail(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, record)
+}
+
+// 替换记录（PUT）或合并字段（PATCH）
+func (c *MockController) Update(ctx *gin.Context) {
+	body, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	merge := ctx.Request.Method == http.MethodPatch
+	record, err := c.mockService.Update(ctx.Param("name"), ctx.Param("id"), body, merge)
+	if err != nil {
+		c.fail(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, record)
+}
+
+// 删除记录
+func (c *MockController) Delete(ctx *gin.Context) {
+	if err := c.mockService.Delete(ctx.Param("name"), ctx.Param("id")); err != nil {
+		c.fail(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// 按错误类型返回状态码
+func (c *MockController) fail(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrMockNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrMockConflict):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"generateTestData/backend/models"
+	"generateTestData/backend/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ScheduleController struct {
+	scheduler *services.SchedulerService
+}
+
+func NewScheduleController(scheduler *services.SchedulerService) *ScheduleController {
+	return &ScheduleController{
+		scheduler: scheduler,
+	}
+}
+
+// 获取定时调度列表，可按 taskId 过滤
+func (c *ScheduleController) List(ctx *gin.Context) {
+	var taskID uint64
+	if value := ctx.Query("taskId"); value != "" {
+		var err error
+		if taskID, err = strconv.ParseUint(value, 10, 32); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务ID"})
+			return
+		}
+	}
+
+	schedules, err := c.scheduler.List(uint(taskID))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取定时调度失败: " + err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": schedules})
+}
+
+// 创建定时调度
+func (c *ScheduleController) Create(ctx *gin.Context) {
+	var schedule models.TaskSchedule
+	if err := ctx.ShouldBindJSON(&schedule); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.scheduler.Create(&schedule); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": schedule})
+}
+
+// 更新定时调度
+func (c *ScheduleController) Update(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "无效的ID"})
+		return
+	}
+
+	var schedule models.TaskSchedule
+	if err := ctx.ShouldBindJSON(&schedule); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	schedule.ID = uint(id)
+	if err := c.scheduler.Update(&schedule); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": schedule})
+}
+
+// 删除定时调度
+func (c *ScheduleController) Delete(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "无效的ID"})
+		return
+	}
+
+	if err := c.scheduler.Delete(uint(id)); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "删除定时调度失败: " + err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "删除成功"})
+}
@@ -97,12 +97,13 @@ func (c *TaskController) Execute(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.taskService.ExecuteTask(uint(id)); err != nil {
+	run, err := c.taskService.ExecuteTask(uint(id), runTrigger(ctx))
+	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"message": "任务已开始执行"})
+	ctx.JSON(http.StatusOK, gin.H{"message": "任务已加入执行队列", "data": run})
 }
 
 // 获取任务状态
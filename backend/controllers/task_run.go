@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"fmt"
+	"generateTestData/backend/models"
+	"generateTestData/backend/services"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 获取任务的执行记录
+func (c *TaskController) Runs(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "无效的ID"})
+		return
+	}
+
+	runs, err := c.taskService.GetTaskRuns(uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取执行记录失败: " + err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": runs})
+}
+
+// 获取一次执行记录
+func (c *TaskController) GetRun(ctx *gin.Context) {
+	id, runID, ok := parseRunParams(ctx)
+	if !ok {
+		return
+	}
+
+	run, err := c.taskService.GetTaskRun(id, runID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": run})
+}
+
+// 按历史执行的配置重新执行
+func (c *TaskController) Rerun(ctx *gin.Context) {
+	id, runID, ok := parseRunParams(ctx)
+	if !ok {
+		return
+	}
+
+	run, err := c.taskService.RerunTask(id, runID, runTrigger(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "任务已加入执行队列", "data": run})
+}
+
+// 获取一次执行的日志，支持按最低级别(level)、字段(field)和关键字(q)过滤，download=true 时下载为文本文件
+func (c *TaskController) RunLogs(ctx *gin.Context) {
+	id, runID, ok := parseRunParams(ctx)
+	if !ok {
+		return
+	}
+
+	filter := &services.RunLogFilter{
+		Level:   models.LogLevel(ctx.Query("level")),
+		Field:   ctx.Query("field"),
+		Keyword: ctx.Query("q"),
+	}
+	logs, err := c.taskService.GetRunLogs(id, runID, filter)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if ctx.Query("download") != "true" {
+		ctx.JSON(http.StatusOK, gin.H{"data": logs})
+		return
+	}
+	var builder strings.Builder
+	for _, entry := range logs {
+		fmt.Fprintf(&builder, "%s %-5s ", entry.CreatedAt.Format("2006-01-02 15:04:05.000"), strings.ToUpper(string(entry.Level)))
+		if entry.Field != "" {
+			fmt.Fprintf(&builder, "[%s] ", entry.Field)
+		}
+		builder.WriteString(entry.Message)
+		if entry.Data != "" {
+			builder.WriteString(" " + entry.Data)
+		}
+		builder.WriteString("\n")
+	}
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=task-%d-run-%d.log", id, runID))
+	ctx.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(builder.String()))
+}
+
+// 获取执行队列中排队和执行中的记录
+func (c *TaskController) Queue(ctx *gin.Context) {
+	runs, err := c.taskService.GetQueue()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取执行队列失败: " + err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": runs})
+}
+
+// 解析任务ID和执行记录ID
+func parseRunParams(ctx *gin.Context) (uint, uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "无效的ID"})
+		return 0, 0, false
+	}
+	runID, err := strconv.ParseUint(ctx.Param("run"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "无效的执行记录ID"})
+		return 0, 0, false
+	}
+	return uint(id), uint(runID), true
+}
+
+// 触发方式：界面请求带 trigger=manual，其他调用方记为接口调用
+func runTrigger(ctx *gin.Context) models.RunTrigger {
+	if ctx.Query("trigger") == string(models.RunTriggerManual) {
+		return models.RunTriggerManual
+	}
+	return models.RunTriggerAPI
+}
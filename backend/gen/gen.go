@@ -0,0 +1,192 @@
+// Package gen 在进程内按字段规则生成数据，不依赖Web服务、平台数据库（data.db）和全局配置，
+// Go集成测试可以直接用它生成测试夹具：
+//
+//	schema, _ := gen.ParseSchema(`[{"name":"id","type":"int"},{"name":"email","type":"varchar"}]`)
+//	g := gen.New(schema, map[string]gen.Rule{
+//		"id":    {Type: "sequence"},
+//		"email": {Type: "regex", Parameters: map[string]interface{}{"pattern": "[a-z]{8}@test\\.com"}},
+//	}, gen.Options{Seed: 42, UniqueFields: []string{"email"}})
+//	for row, err := range g.Rows(100) {
+//		...
+//	}
+//
+// 规则与任务的 fieldRules 相同。
+package gen
+
+import (
+	"encoding/json"
+	"fmt"
+	"generateTestData/backend/models"
+	"generateTestData/backend/services"
+	"iter"
+	"time"
+)
+
+// 字段生成规则，与任务的 fieldRules 相同
+type Rule = models.FieldRule
+
+// 表结构中的列
+type Column = models.ColumnInfo
+
+// 唯一字段的取值记录，多个生成器共享同一个记录时生成的值互不重复
+type UniqueStore = services.UniqueStore
+
+// 创建内存中的唯一值记录
+func NewUniqueStore() UniqueStore {
+	return services.NewMemoryUniqueStore()
+}
+
+// 数据结构：按列生成（数据库、CSV任务）或按JSON结构生成（JSON任务）
+type Schema struct {
+	columns []Column
+	object  map[string]interface{}
+}
+
+// 按列生成，字段规则以列名为键
+func Columns(columns ...Column) Schema {
+	return Schema{columns: columns}
+}
+
+// 按JSON结构生成，字段规则以字段路径为键（如 user.email、items[].price）
+func Object(structure map[string]interface{}) Schema {
+	return Schema{object: structure}
+}
+
+// 解析任务的 jsonSchema：数组为列定义，对象为JSON结构
+func ParseSchema(text string) (Schema, error) {
+	var columns []Column
+	if err := json.Unmarshal([]byte(text), &columns); err == nil {
+		return Columns(columns...), nil
+	}
+	var structure map[string]interface{}
+	if err := json.Unmarshal([]byte(text), &structure); err != nil {
+		return Schema{}, fmt.Errorf("解析数据结构失败: %v", err)
+	}
+	return Object(structure), nil
+}
+
+// db_lookup 规则的取值来源
+type Lookup interface {
+	Values(table, column string) ([]interface{}, error)
+}
+
+// 函数形式的取值来源
+type LookupFunc func(table, column string) ([]interface{}, error)
+
+func (f LookupFunc) Values(table, column string) ([]interface{}, error) {
+	return f(table, column)
+}
+
+// 固定的取值来源，键为 "表名.列名"
+type StaticLookup map[string][]interface{}
+
+func (l StaticLookup) Values(table, column string) ([]interface{}, error) {
+	values, ok := l[table+"."+column]
+	if !ok {
+		return nil, fmt.Errorf("未提供 %s.%s 的取值", table, column)
+	}
+	return values, nil
+}
+
+// 生成选项
+type Options struct {
+	Seed         int64       // 随机种子，相同种子和规则生成相同的数据；为0时使用随机种子
+	Lookup       Lookup      // db_lookup 规则的取值来源，未设置时 db_lookup 规则返回错误
+	Unique       UniqueStore // 唯一值记录，未设置时每个生成器单独记录
+	UniqueFields []string    // 不允许重复的字段
+	Now          time.Time   // 固定当前时间（影响默认的随机日期范围），为零值时使用系统时间
+}
+
+// 生成器，非并发安全
+type Generator struct {
+	schema       Schema
+	rules        map[string]models.FieldRule
+	uniqueFields []string
+	generator    *services.GeneratorService
+	rowIndex     int64
+}
+
+// 创建生成器，规则会被复制，调用方可以继续修改或复用
+func New(schema Schema, rules map[string]Rule, options Options) *Generator {
+	generatorOptions := services.GeneratorOptions{Unique: options.Unique}
+	if options.Seed != 0 {
+		generatorOptions.Seed = &options.Seed
+	}
+	if options.Lookup != nil {
+		generatorOptions.Lookup = lookupProvider{options.Lookup}
+	}
+	if !options.Now.IsZero() {
+		now := options.Now
+		generatorOptions.Now = func() time.Time { return now }
+	}
+
+	copied := make(map[string]models.FieldRule, len(rules))
+	for field, rule := range rules {
+		if rule.Parameters != nil {
+			parameters := make(map[string]interface{}, len(rule.Parameters))
+			for key, value := range rule.Parameters {
+				parameters[key] = value
+			}
+			rule.Parameters = parameters
+		}
+		copied[field] = rule
+	}
+
+	return &Generator{
+		schema:       schema,
+		rules:        copied,
+		uniqueFields: options.UniqueFields,
+		generator:    services.NewGenerator(generatorOptions),
+	}
+}
+
+// 生成下一条记录
+func (g *Generator) Next() (map[string]interface{}, error) {
+	context := map[string]interface{}{"rowIndex": g.rowIndex}
+	var record map[string]interface{}
+	var err error
+	if g.schema.object != nil {
+		record, err = g.generator.GenerateJSON(g.schema.object, g.rules, g.uniqueFields, context)
+	} else {
+		tableInfo := &models.TableInfo{Columns: g.schema.columns}
+		record, err = g.generator.GenerateRecord(tableInfo, g.rules, g.uniqueFields, context)
+	}
+	if err != nil {
+		return nil, err
+	}
+	g.rowIndex++
+	return record, nil
+}
+
+// 依次生成 n 条记录，出错时返回错误并结束
+func (g *Generator) Rows(n int) iter.Seq2[map[string]interface{}, error] {
+	return func(yield func(map[string]interface{}, error) bool) {
+		for i := 0; i < n; i++ {
+			record, err := g.Next()
+			if !yield(record, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// 生成 n 条记录
+func (g *Generator) Generate(n int) ([]map[string]interface{}, error) {
+	records := make([]map[string]interface{}, 0, n)
+	for record, err := range g.Rows(n) {
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// 适配 services.LookupProvider，规则中的 dataSourceId 不参与取值
+type lookupProvider struct {
+	lookup Lookup
+}
+
+func (p lookupProvider) LookupValues(dataSourceID uint, table, column string, context map[string]interface{}) ([]interface{}, error) {
+	return p.lookup.Values(table, column)
+}
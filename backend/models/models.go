@@ -6,6 +6,7 @@ import (
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
 var DB *gorm.DB
@@ -15,6 +16,7 @@ type TaskStatus string
 
 const (
 	TaskStatusPending   TaskStatus = "pending"
+	TaskStatusQueued    TaskStatus = "queued" // 已加入执行队列，等待空闲的执行线程
 	TaskStatusRunning   TaskStatus = "running"
 	TaskStatusCompleted TaskStatus = "completed"
 	TaskStatusFailed    TaskStatus = "failed"
@@ -27,6 +29,8 @@ const (
 	TaskTypeDatabase TaskType = "database"
 	TaskTypeJSON     TaskType = "json"
 	TaskTypeCSV      TaskType = "csv"
+	TaskTypeSubset   TaskType = "subset" // 从生产数据中抽取保持引用完整性的子集
+	TaskTypeMask     TaskType = "mask"   // 复制生产数据并对敏感字段脱敏
 )
 
 // 输出类型枚举
@@ -37,27 +41,39 @@ const (
 	OutputTypeSQL        OutputType = "sql"
 	OutputTypeJSON       OutputType = "json"
 	OutputTypeTXT        OutputType = "txt"
+	OutputTypeJSONL      OutputType = "jsonl" // JSON Lines，每行一个JSON对象
 	OutputTypeCSV        OutputType = "csv"
 	OutputTypeMockServer OutputType = "mock_server"
+	OutputTypeParquet    OutputType = "parquet"
+	OutputTypeAvro       OutputType = "avro"
+	OutputTypeProtobuf   OutputType = "protobuf"
+	OutputTypeXLSX       OutputType = "xlsx"
+	OutputTypeXML        OutputType = "xml"
+	OutputTypeYAML       OutputType = "yaml"
+	OutputTypeTemplate   OutputType = "template"
+	OutputTypeKafka      OutputType = "kafka"
+	OutputTypeHTTP       OutputType = "http"
+	OutputTypeMockAPI    OutputType = "mock_api" // 发布到内置Mock接口 /mock/<名称>
 )
 
 // 数据源配置
 type DataSource struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	Name      string    `json:"name" gorm:"not null"`
-	Type      string    `json:"type" gorm:"not null"` // mysql, postgresql, sqlite
-	Host      string    `json:"host"`
-	Port      int       `json:"port"`
-	Database  string    `json:"database"`
-	Username  string    `json:"username"`
-	Password  string    `json:"password"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	Name           string    `json:"name" gorm:"not null"`
+	Type           string    `json:"type" gorm:"not null"` // mysql, postgresql, sqlite
+	Host           string    `json:"host"`
+	Port           int       `json:"port"`
+	Database       string    `json:"database"`
+	Username       string    `json:"username"`
+	Password       string    `json:"password"`
+	MaxConcurrency int       `json:"maxConcurrency"` // 同时使用该数据源的任务数上限，为0时使用全局配置
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
 }
 
 // 字段生成规则
 type FieldRule struct {
-	Type       string                 `json:"type"`       // fixed, sequence, random, range, regex, enum, reference, custom
+	Type       string                 `json:"type"`       // fixed, sequence, random, range, regex, enum, reference, custom；脱敏任务: keep, hash, tokenize, faker, partial, date_shift, null
 	Value      interface{}            `json:"value"`      // 具体的值或配置
 	Parameters map[string]interface{} `json:"parameters"` // 额外参数
 }
@@ -77,9 +93,11 @@ type Task struct {
 	OutputPath    string      `json:"outputPath"`    // 输出文件名（不含路径，会自动保存到配置的生成目录）
 	Configuration string      `json:"configuration"` // 额外配置，JSON格式 (如Mock Server地址等)
 	UniqueFields  string      `json:"unique_fields"` // 不允许重复的字段，JSON数组格式
+	Priority      int         `json:"priority"`      // 执行队列中的优先级，数值大的先执行
 	Status        TaskStatus  `json:"status" gorm:"default:pending"`
 	Progress      float64     `json:"progress" gorm:"default:0"`
 	ErrorMsg      string      `json:"error_msg"`
+	Result        string      `json:"result"` // 最近一次执行结果，JSON格式
 	CreatedAt     time.Time   `json:"created_at"`
 	UpdatedAt     time.Time   `json:"updated_at"`
 	CompletedAt   *time.Time  `json:"completed_at"`
@@ -102,6 +120,15 @@ type ColumnInfo struct {
 	MaxLength       int    `json:"max_length"`
 }
 
+// 外键信息（支持复合外键）
+type ForeignKey struct {
+	Name       string   `json:"name"`
+	Table      string   `json:"table"`
+	Columns    []string `json:"columns"`
+	RefTable   string   `json:"ref_table"`
+	RefColumns []string `json:"ref_columns"`
+}
+
 // 任务执行结果
 type TaskResult struct {
 	TaskID         uint          `json:"task_id"`
@@ -109,6 +136,13 @@ type TaskResult struct {
 	FilePath       string        `json:"file_path"`
 	Duration       time.Duration `json:"duration"`
 	CreatedAt      time.Time     `json:"created_at"`
+
+	RowsPerSecond float64          `json:"rows_per_second"`        // 吞吐量（行/秒）
+	RejectedCount int64            `json:"rejected_count"`         // 按错误策略被跳过的行数
+	TableCounts   map[string]int64 `json:"table_counts,omitempty"` // 多表任务（如子集抽取）每张表的行数
+	Files         []string         `json:"files,omitempty"`        // 生成的文件（分片和清单），相对于生成目录
+
+	PartitionCounts map[int32]int64 `json:"partition_counts,omitempty"` // Kafka输出每个分区确认的消息数
 }
 
 // 解析字段规则
@@ -151,6 +185,24 @@ func (t *Task) SetUniqueFields(fields []string) error {
 	return nil
 }
 
+// 解析额外配置到指定结构体
+func (t *Task) GetConfiguration(v interface{}) error {
+	if t.Configuration == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(t.Configuration), v)
+}
+
+// 保存执行结果
+func (t *Task) SetResult(result *TaskResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	t.Result = string(data)
+	return nil
+}
+
 // 任务规则模板
 type TaskTemplate struct {
 	ID          uint      `json:"id" gorm:"primaryKey"`
@@ -163,6 +215,156 @@ type TaskTemplate struct {
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
+// 定时调度的重叠策略：上次执行尚未结束时如何处理
+const (
+	OverlapSkip = "skip" // 跳过本次（默认）
+	OverlapWait = "wait" // 等待上次结束后再执行，多次触发合并为一次
+)
+
+// 定时调度的补跑策略：服务停机期间错过的执行如何处理
+const (
+	CatchUpNone = "none" // 不补跑（默认）
+	CatchUpOnce = "once" // 启动后补跑一次
+	CatchUpAll  = "all"  // 启动后逐次补跑，最多10次
+)
+
+// 任务的定时调度
+type TaskSchedule struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	TaskID        uint       `json:"taskId" gorm:"index;not null"`
+	Cron          string     `json:"cron" gorm:"not null"` // 5段cron表达式（分 时 日 月 周），或 @daily、@every 5m 等
+	TimeZone      string     `json:"timeZone"`             // IANA时区，如 Asia/Shanghai，为空时使用服务器时区
+	Enabled       bool       `json:"enabled"`
+	OverlapPolicy string     `json:"overlapPolicy"` // skip, wait
+	CatchUpPolicy string     `json:"catchUpPolicy"` // none, once, all
+	LastRunAt     *time.Time `json:"lastRunAt"`     // 最近一次触发时间
+	NextRunAt     *time.Time `json:"nextRunAt"`     // 下次计划执行时间，重启后据此判断错过的执行
+	LastError     string     `json:"lastError"`     // 最近一次执行的错误或跳过原因
+	RunCount      int64      `json:"runCount"`      // 已执行次数（含补跑）
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// 任务执行的触发方式
+type RunTrigger string
+
+const (
+	RunTriggerManual   RunTrigger = "manual"   // 在界面上手动执行
+	RunTriggerSchedule RunTrigger = "schedule" // 定时调度
+	RunTriggerAPI      RunTrigger = "api"      // 通过接口调用执行
+)
+
+// 任务的一次执行记录
+type TaskRun struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	TaskID         uint       `json:"taskId" gorm:"index;not null"`
+	Trigger        RunTrigger `json:"trigger"`
+	Status         TaskStatus `json:"status"`
+	Priority       int        `json:"priority"`
+	RerunOf        *uint      `json:"rerunOf"`  // 重新执行时对应的历史执行记录
+	Seed           int64      `json:"seed"`     // 随机种子，重新执行时使用相同的种子
+	Snapshot       string     `json:"snapshot"` // 执行时的任务配置，JSON格式
+	GeneratedCount int64      `json:"generatedCount"`
+	RejectedCount  int64      `json:"rejectedCount"`
+	RowsPerSecond  float64    `json:"rowsPerSecond"`
+	Files          string     `json:"files"` // 输出文件及其大小和校验和，JSON格式
+	ErrorMsg       string     `json:"errorMsg"`
+	QueuedAt       time.Time  `json:"queuedAt"`
+	StartedAt      *time.Time `json:"startedAt"` // 排队中的执行为空
+	FinishedAt     *time.Time `json:"finishedAt"`
+}
+
+// 执行记录中的输出文件
+type RunFile struct {
+	Name   string `json:"name"` // 相对于生成目录
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// 解析输出文件
+func (r *TaskRun) GetFiles() ([]RunFile, error) {
+	var files []RunFile
+	if r.Files == "" {
+		return files, nil
+	}
+	err := json.Unmarshal([]byte(r.Files), &files)
+	return files, err
+}
+
+// 保存任务配置快照，不包含执行状态和结果
+func (r *TaskRun) SetSnapshot(task *Task) error {
+	snapshot := *task
+	snapshot.DataSource = nil
+	snapshot.Status = ""
+	snapshot.Progress = 0
+	snapshot.ErrorMsg = ""
+	snapshot.Result = ""
+	snapshot.CompletedAt = nil
+	data, err := json.Marshal(&snapshot)
+	if err != nil {
+		return err
+	}
+	r.Snapshot = string(data)
+	return nil
+}
+
+// 从配置快照还原任务
+func (r *TaskRun) GetSnapshot() (*Task, error) {
+	var task Task
+	if err := json.Unmarshal([]byte(r.Snapshot), &task); err != nil {
+		return nil, err
+	}
+	task.ID = r.TaskID
+	return &task, nil
+}
+
+// 执行日志级别
+type LogLevel string
+
+const (
+	LogLevelDebug LogLevel = "debug" // 批次耗时等明细
+	LogLevelInfo  LogLevel = "info"  // 配置摘要、阶段和执行结果
+	LogLevelWarn  LogLevel = "warn"  // 重试、被拒绝的行等不影响执行结果的问题
+	LogLevelError LogLevel = "error" // 导致执行失败的错误
+)
+
+// 级别的严重程度，用于按最低级别过滤
+func (l LogLevel) Severity() int {
+	switch l {
+	case LogLevelDebug:
+		return 0
+	case LogLevelInfo:
+		return 1
+	case LogLevelWarn:
+		return 2
+	case LogLevelError:
+		return 3
+	}
+	return -1
+}
+
+// 执行日志：一次执行过程中的一条结构化日志
+type TaskRunLog struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	TaskID    uint      `json:"taskId" gorm:"index;not null"`
+	RunID     uint      `json:"runId" gorm:"index;not null"`
+	Level     LogLevel  `json:"level"`
+	Field     string    `json:"field"` // 相关的字段，如脚本出错的字段
+	Message   string    `json:"message"`
+	Data      string    `json:"data"` // 附加信息，JSON格式，如配置摘要、被拒绝的记录
+	CreatedAt time.Time `json:"createdAt" gorm:"index"`
+}
+
+// 规则库：可在任务和模板之间复用的一组字段规则，按名称管理
+type RuleLibrary struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Name        string    `json:"name" gorm:"uniqueIndex;not null"`
+	Description string    `json:"description"`
+	FieldRules  string    `json:"fieldRules"` // 存储字段规则的JSON字符串
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
 // 解析字段规则
 func (tt *TaskTemplate) GetFieldRules() (map[string]FieldRule, error) {
 	var rules map[string]FieldRule
@@ -183,16 +385,50 @@ func (tt *TaskTemplate) SetFieldRules(rules map[string]FieldRule) error {
 	return nil
 }
 
+// 内置Mock接口发布的数据集
+type MockCollection struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Name        string    `json:"name" gorm:"uniqueIndex;not null"` // 访问路径 /mock/<name>
+	TaskID      uint      `json:"taskId"`                           // 发布该数据集的任务
+	IDField     string    `json:"idField"`                          // 作为记录ID的字段
+	Storage     string    `json:"storage"`                          // memory（仅保存在内存中，重启后清空）, sqlite
+	LatencyMs   int       `json:"latencyMs"`                        // 每个请求的固定延迟
+	JitterMs    int       `json:"jitterMs"`                         // 额外的随机延迟上限
+	ErrorRate   float64   `json:"errorRate"`                        // 随机返回错误的比例（0-1）
+	ErrorStatus int       `json:"errorStatus"`                      // 注入错误时的状态码
+	Count       int64     `json:"count"`                            // 发布时的记录数
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Mock数据集中的一条记录（sqlite 存储）
+type MockRecord struct {
+	ID         uint   `gorm:"primaryKey"`
+	Collection string `gorm:"not null;uniqueIndex:idx_mock_record"`
+	RecordID   string `gorm:"not null;uniqueIndex:idx_mock_record"`
+	Data       string `gorm:"not null"` // 记录的JSON
+}
+
 // 初始化数据库
 func InitDB() {
+	openDB("./data.db", &gorm.Config{})
+}
+
+// 初始化内存数据库，命令行模式使用，不读写 data.db
+func InitMemoryDB() {
+	// 标准输出留给预览数据，不打印SQL日志
+	openDB("file:cli?mode=memory&cache=shared", &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+}
+
+func openDB(dsn string, config *gorm.Config) {
 	var err error
-	DB, err = gorm.Open(sqlite.Open("./data.db"), &gorm.Config{})
+	DB, err = gorm.Open(sqlite.Open(dsn), config)
 	if err != nil {
 		panic("连接数据库失败: " + err.Error())
 	}
 
 	// 自动迁移
-	err = DB.AutoMigrate(&DataSource{}, &Task{}, &TaskTemplate{})
+	err = DB.AutoMigrate(&DataSource{}, &Task{}, &TaskTemplate{}, &TaskSchedule{}, &TaskRun{}, &TaskRunLog{}, &RuleLibrary{}, &MockCollection{}, &MockRecord{})
 	if err != nil {
 		panic("数据库迁移失败: " + err.Error())
 	}
@@ -0,0 +1,406 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"generateTestData/backend/config"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hamba/avro/v2"
+	"github.com/hamba/avro/v2/ocf"
+)
+
+// Avro导出选项（存储在 Task.Configuration 中）
+type AvroOptions struct {
+	Schema     string `json:"avroSchema"`     // 用户提供的Avro结构（JSON），为空时根据任务结构生成
+	SchemaFile string `json:"avroSchemaFile"` // 上传目录中的 .avsc 文件名，avroSchema 为空时使用
+	Codec      string `json:"avroCodec"`      // null（默认）, deflate, snappy, zstandard
+	RecordName string `json:"avroRecordName"` // 生成结构时的记录名，默认 Record
+	Namespace  string `json:"avroNamespace"`  // 生成结构时的命名空间
+}
+
+// 获取压缩编码
+func (o *AvroOptions) codec() (ocf.CodecName, error) {
+	codec := ""
+	if o != nil {
+		codec = strings.ToLower(o.Codec)
+	}
+	switch codec {
+	case "", "null":
+		return ocf.Null, nil
+	case "deflate":
+		return ocf.Deflate, nil
+	case "snappy":
+		return ocf.Snappy, nil
+	case "zstandard", "zstd":
+		return ocf.ZStandard, nil
+	default:
+		return "", fmt.Errorf("不支持的Avro压缩方式: %s", o.Codec)
+	}
+}
+
+// 获取Avro结构：优先使用用户提供的结构，否则根据记录结构生成
+func (s *ExportService) AvroSchema(recordSchema *RecordSchema, options *AvroOptions) (avro.Schema, error) {
+	if options != nil && options.Schema != "" {
+		schema, err := avro.Parse(options.Schema)
+		if err != nil {
+			return nil, fmt.Errorf("解析Avro结构失败: %v", err)
+		}
+		return schema, nil
+	}
+	if options != nil && options.SchemaFile != "" {
+		data, err := os.ReadFile(filepath.Join(config.AppConfig.UploadDir, filepath.Base(options.SchemaFile)))
+		if err != nil {
+			return nil, fmt.Errorf("读取Avro结构文件失败: %v", err)
+		}
+		schema, err := avro.Parse(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("解析Avro结构失败: %v", err)
+		}
+		return schema, nil
+	}
+
+	name, namespace := "Record", ""
+	if options != nil {
+		if options.RecordName != "" {
+			name = options.RecordName
+		}
+		namespace = options.Namespace
+	}
+	definition := avroRecordDefinition(recordSchema.root, name)
+	if namespace != "" {
+		definition["namespace"] = namespace
+	}
+	data, err := json.Marshal(definition)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := avro.Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("生成Avro结构失败: %v", err)
+	}
+	return schema, nil
+}
+
+// 生成记录类型定义，嵌套记录以 父记录名_字段名 命名保证唯一
+func avroRecordDefinition(field *schemaField, name string) map[string]interface{} {
+	fields := make([]interface{}, 0, len(field.order))
+	for _, fieldName := range field.order {
+		fields = append(fields, map[string]interface{}{
+			"name":    fieldName,
+			"type":    avroTypeDefinition(field.fields[fieldName], name+"_"+fieldName),
+			"default": nil,
+		})
+	}
+	return map[string]interface{}{
+		"type":   "record",
+		"name":   name,
+		"fields": fields,
+	}
+}
+
+// 生成字段类型定义，所有字段均为可空的联合类型
+func avroTypeDefinition(field *schemaField, name string) interface{} {
+	var definition interface{}
+	switch field.kind {
+	case fieldInt32:
+		definition = "int"
+	case fieldInt64:
+		definition = "long"
+	case fieldDouble:
+		definition = "double"
+	case fieldBoolean:
+		definition = "boolean"
+	case fieldBytes:
+		definition = "bytes"
+	case fieldDate:
+		definition = map[string]interface{}{"type": "int", "logicalType": "date"}
+	case fieldTimestamp:
+		definition = map[string]interface{}{"type": "long", "logicalType": "timestamp-micros"}
+	case fieldGroup:
+		definition = avroRecordDefinition(field, name)
+	case fieldList:
+		definition = map[string]interface{}{"type": "array", "items": avroTypeDefinition(field.elem, name+"_item")}
+	default:
+		definition = "string"
+	}
+	return []interface{}{"null", definition}
+}
+
+// AvroWriter 在整个任务期间保持Avro容器文件打开，按批追加数据块
+type AvroWriter struct {
+	path    string
+	schema  avro.Schema
+	codec   ocf.CodecName
+	file    *tempFile
+	encoder *ocf.Encoder
+	name    string
+}
+
+// 创建Avro容器文件输出
+func (s *ExportService) NewAvroWriter(fileName string, schema avro.Schema, options *AvroOptions) (*AvroWriter, error) {
+	codec, err := options.codec()
+	if err != nil {
+		return nil, err
+	}
+	if fileName == "" {
+		return nil, fmt.Errorf("必须指定输出路径")
+	}
+
+	// 确保文件名有正确的后缀
+	fileName = ensureFileExtension(fileName, "avro")
+	return &AvroWriter{
+		path:   filepath.Join(config.AppConfig.GenerateDir, fileName),
+		schema: schema,
+		codec:  codec,
+		name:   fileName,
+	}, nil
+}
+
+// 创建文件并写入文件头
+func (w *AvroWriter) Open() error {
+	file, err := createTempFile(w.path)
+	if err != nil {
+		return err
+	}
+	encoder, err := ocf.NewEncoderWithSchema(w.schema, file, ocf.WithCodec(w.codec))
+	if err != nil {
+		file.discard()
+		return fmt.Errorf("创建Avro编码器失败: %v", err)
+	}
+	w.file = file
+	w.encoder = encoder
+	return nil
+}
+
+// 写入一批记录，编码器按块大小自动写出数据块
+func (w *AvroWriter) WriteBatch(records []map[string]interface{}) error {
+	for _, record := range records {
+		value, err := avroValue(record, w.schema)
+		if err != nil {
+			return err
+		}
+		if err := w.encoder.Encode(value); err != nil {
+			return fmt.Errorf("写入Avro失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// 写入最后的数据块并保存文件
+func (w *AvroWriter) Close() error {
+	if w.file == nil {
+		return nil
+	}
+	if err := w.encoder.Close(); err != nil {
+		return fmt.Errorf("写入Avro失败: %v", err)
+	}
+	file := w.file
+	w.file = nil
+	return file.commit()
+}
+
+// 放弃输出，删除临时文件
+func (w *AvroWriter) Abort() error {
+	if w.file != nil {
+		w.file.discard()
+		w.file = nil
+	}
+	return nil
+}
+
+// 生成的文件（相对于生成目录）
+func (w *AvroWriter) Files() []string {
+	return []string{w.name}
+}
+
+// 按Avro结构转换值
+func avroValue(value interface{}, schema avro.Schema) (interface{}, error) {
+	switch s := schema.(type) {
+	case *avro.RefSchema:
+		return avroValue(value, s.Schema())
+	case *avro.UnionSchema:
+		return avroUnionValue(value, s)
+	}
+	if value == nil {
+		if schema.Type() == avro.Null {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("值不能为空")
+	}
+
+	switch s := schema.(type) {
+	case *avro.RecordSchema:
+		record, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("期望对象，实际为 %T", value)
+		}
+		fields := make(map[string]*avro.Field, len(s.Fields()))
+		for _, field := range s.Fields() {
+			fields[field.Name()] = field
+		}
+		for name := range record {
+			if _, ok := fields[name]; !ok {
+				return nil, fmt.Errorf("记录 %s 中不存在字段 %s", s.FullName(), name)
+			}
+		}
+
+		result := make(map[string]interface{}, len(fields))
+		for name, field := range fields {
+			fieldValue, exists := record[name]
+			if !exists && field.HasDefault() {
+				// 由编码器写入默认值
+				continue
+			}
+			converted, err := avroValue(fieldValue, field.Type())
+			if err != nil {
+				return nil, fmt.Errorf("字段 %s: %v", name, err)
+			}
+			result[name] = converted
+		}
+		return result, nil
+	case *avro.ArraySchema:
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("期望数组，实际为 %T", value)
+		}
+		result := make([]interface{}, len(items))
+		for i, item := range items {
+			converted, err := avroValue(item, s.Items())
+			if err != nil {
+				return nil, err
+			}
+			result[i] = converted
+		}
+		return result, nil
+	case *avro.MapSchema:
+		entries, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("期望对象，实际为 %T", value)
+		}
+		result := make(map[string]interface{}, len(entries))
+		for key, entry := range entries {
+			converted, err := avroValue(entry, s.Values())
+			if err != nil {
+				return nil, err
+			}
+			result[key] = converted
+		}
+		return result, nil
+	case *avro.EnumSchema:
+		symbol := toText(value)
+		for _, candidate := range s.Symbols() {
+			if candidate == symbol {
+				return symbol, nil
+			}
+		}
+		return nil, fmt.Errorf("枚举 %s 中不存在值 %s", s.FullName(), symbol)
+	case *avro.FixedSchema:
+		data, ok := value.([]byte)
+		if !ok {
+			data = []byte(toText(value))
+		}
+		if len(data) != s.Size() {
+			return nil, fmt.Errorf("fixed 类型长度应为 %d，实际为 %d", s.Size(), len(data))
+		}
+		return data, nil
+	case *avro.PrimitiveSchema:
+		return avroPrimitiveValue(value, s)
+	default:
+		return nil, fmt.Errorf("不支持的Avro类型: %s", schema.Type())
+	}
+}
+
+// 联合类型：依次尝试各个非空类型，复杂类型按编码器要求以 {类型名: 值} 包装
+func avroUnionValue(value interface{}, schema *avro.UnionSchema) (interface{}, error) {
+	if value == nil {
+		if schema.Nullable() {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("值不能为空")
+	}
+
+	var lastErr error
+	for _, member := range schema.Types() {
+		if member.Type() == avro.Null {
+			continue
+		}
+		converted, err := avroValue(value, member)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		resolved := member
+		if ref, ok := member.(*avro.RefSchema); ok {
+			resolved = ref.Schema()
+		}
+		switch named := resolved.(type) {
+		case *avro.RecordSchema:
+			return map[string]interface{}{named.FullName(): converted}, nil
+		case *avro.EnumSchema:
+			return map[string]interface{}{named.FullName(): converted}, nil
+		case *avro.FixedSchema:
+			return map[string]interface{}{named.FullName(): converted}, nil
+		case *avro.MapSchema:
+			return map[string]interface{}{string(avro.Map): converted}, nil
+		}
+		return converted, nil
+	}
+	return nil, lastErr
+}
+
+// 基本类型及其逻辑类型
+func avroPrimitiveValue(value interface{}, schema *avro.PrimitiveSchema) (interface{}, error) {
+	var logical avro.LogicalType
+	if schema.Logical() != nil {
+		logical = schema.Logical().Type()
+	}
+
+	switch schema.Type() {
+	case avro.Int:
+		if logical == avro.Date {
+			return toTime(value)
+		}
+		n, err := toInt64(value)
+		if err != nil {
+			return nil, err
+		}
+		if n < math.MinInt32 || n > math.MaxInt32 {
+			return nil, fmt.Errorf("整数 %d 超出int范围", n)
+		}
+		return int(n), nil
+	case avro.Long:
+		if logical == avro.TimestampMillis || logical == avro.TimestampMicros {
+			return toTime(value)
+		}
+		return toInt64(value)
+	case avro.Float:
+		f, err := toFloat64(value)
+		return float32(f), err
+	case avro.Double:
+		return toFloat64(value)
+	case avro.Boolean:
+		return toBool(value)
+	case avro.Bytes:
+		if logical == avro.Decimal {
+			rat, ok := new(big.Rat).SetString(toText(value))
+			if !ok {
+				return nil, fmt.Errorf("无法将 %v 转换为decimal", value)
+			}
+			return rat, nil
+		}
+		if data, ok := value.([]byte); ok {
+			return data, nil
+		}
+		return []byte(toText(value)), nil
+	case avro.String:
+		return toText(value), nil
+	default:
+		return nil, fmt.Errorf("不支持的Avro类型: %s", schema.Type())
+	}
+}
@@ -0,0 +1,301 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"generateTestData/backend/utils"
+	"io"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+// 数据库写入方式
+const (
+	LoadModeRow      = "row"       // 逐行插入
+	LoadModeBatch    = "batch"     // 多行INSERT，在显式事务中执行（默认）
+	LoadModeCopy     = "copy"      // PostgreSQL COPY FROM STDIN
+	LoadModeLoadData = "load_data" // MySQL LOAD DATA LOCAL INFILE
+)
+
+// 各数据库单条语句允许的最大参数数量
+var maxPlaceholders = map[string]int{
+	"mysql":      65535,
+	"postgresql": 65535,
+	"sqlite":     32766,
+}
+
+// LOAD DATA 读取器名称序号
+var loadDataSeq int64
+
+// 数据库写入选项（存储在 Task.Configuration 中）
+type InsertOptions struct {
+	LoadMode        string   `json:"loadMode"`        // row, batch, copy, load_data
+	BatchSize       int      `json:"insertBatchSize"` // batch 模式下每条INSERT包含的行数，默认500
+	WriteMode       string   `json:"writeMode"`       // append, truncate, delete_where, ignore, upsert
+	DeleteWhere     string   `json:"deleteWhere"`     // delete_where 模式下的删除条件（不含 WHERE 关键字）
+	ConflictColumns []string `json:"conflictColumns"` // ignore/upsert 判断冲突的列，默认为主键
+	ErrorPolicy     string   `json:"errorPolicy"`     // abort, skip, reject_file
+	RejectFile      string   `json:"rejectFile"`      // reject_file 策略下的拒绝记录文件名，默认 <表名>_rejects.jsonl
+	CommitEachBatch bool     `json:"commitEachBatch"` // 每批单独提交，任务失败时已提交的批次保留在目标表中；默认整个任务在一个事务中提交
+}
+
+// 补全默认值
+func (o *InsertOptions) withDefaults() InsertOptions {
+	opts := InsertOptions{}
+	if o != nil {
+		opts = *o
+	}
+	if opts.LoadMode == "" {
+		opts.LoadMode = LoadModeBatch
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+	if opts.WriteMode == "" {
+		opts.WriteMode = WriteModeAppend
+	}
+	if opts.ErrorPolicy == "" {
+		opts.ErrorPolicy = ErrorPolicyAbort
+	}
+	return opts
+}
+
+// 获取记录的列名（按名称排序，保证同一批内顺序稳定）
+func recordColumns(records []map[string]interface{}) []string {
+	columns := make([]string, 0, len(records[0]))
+	for column := range records[0] {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// 在事务中逐行插入，出错时按错误策略处理。
+// 错误策略不是 abort 时每行设置保存点，出错的行回滚到保存点后继续（PostgreSQL 出错后事务不可再用）。
+func (s *ExportService) insertRows(tx *sql.Tx, dbType, tableName string, columns []string, records []map[string]interface{}, opts InsertOptions) ([]rejectedRecord, error) {
+	sqlStr, err := buildMultiRowInsert(dbType, tableName, columns, 1, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// 准备语句
+	stmt, err := tx.Prepare(sqlStr)
+	if err != nil {
+		return nil, fmt.Errorf("准备SQL语句失败: %v", err)
+	}
+	defer stmt.Close()
+
+	var rejects []rejectedRecord
+	tolerant := opts.ErrorPolicy != ErrorPolicyAbort
+	for _, record := range records {
+		values := make([]interface{}, len(columns))
+		for i, column := range columns {
+			values[i] = record[column]
+		}
+
+		if !tolerant {
+			if _, err := stmt.Exec(values...); err != nil {
+				return nil, fmt.Errorf("插入数据失败: %v", err)
+			}
+			continue
+		}
+
+		if _, err := tx.Exec("SAVEPOINT generate_row"); err != nil {
+			return nil, fmt.Errorf("设置保存点失败: %v", err)
+		}
+		if _, err := stmt.Exec(values...); err != nil {
+			rejects = append(rejects, rejectedRecord{Record: record, Error: err.Error()})
+			if _, err := tx.Exec("ROLLBACK TO SAVEPOINT generate_row"); err != nil {
+				return nil, fmt.Errorf("回滚保存点失败: %v", err)
+			}
+			continue
+		}
+		if _, err := tx.Exec("RELEASE SAVEPOINT generate_row"); err != nil {
+			return nil, fmt.Errorf("释放保存点失败: %v", err)
+		}
+	}
+	return rejects, nil
+}
+
+// 在事务中以多行INSERT批量插入，每条语句的行数受 BatchSize 和单条语句参数数量限制。
+// 错误策略不是 abort 时，每个子批次设置保存点，失败后回滚到保存点并逐行重试以找出被拒绝的记录。
+func (s *ExportService) insertBatches(tx *sql.Tx, dbType, tableName string, columns []string, records []map[string]interface{}, opts InsertOptions) ([]rejectedRecord, error) {
+	// 受单条语句参数数量限制
+	batchSize := opts.BatchSize
+	if limit := maxPlaceholders[dbType] / len(columns); limit > 0 && batchSize > limit {
+		batchSize = limit
+	}
+
+	var rejects []rejectedRecord
+	tolerant := opts.ErrorPolicy != ErrorPolicyAbort
+	for start := 0; start < len(records); start += batchSize {
+		end := start + batchSize
+		if end > len(records) {
+			end = len(records)
+		}
+		chunk := records[start:end]
+
+		sqlStr, err := buildMultiRowInsert(dbType, tableName, columns, len(chunk), opts)
+		if err != nil {
+			return nil, err
+		}
+		args := make([]interface{}, 0, len(chunk)*len(columns))
+		for _, record := range chunk {
+			for _, column := range columns {
+				args = append(args, record[column])
+			}
+		}
+
+		if !tolerant {
+			if _, err := tx.Exec(sqlStr, args...); err != nil {
+				return nil, fmt.Errorf("插入数据失败: %v", err)
+			}
+			continue
+		}
+
+		chunkRejects, err := s.insertChunkWithSavepoint(tx, dbType, tableName, columns, chunk, sqlStr, args, opts)
+		if err != nil {
+			return nil, err
+		}
+		rejects = append(rejects, chunkRejects...)
+	}
+	return rejects, nil
+}
+
+// 在保存点内插入一个子批次，失败时逐行重试
+func (s *ExportService) insertChunkWithSavepoint(tx *sql.Tx, dbType, tableName string, columns []string, chunk []map[string]interface{}, sqlStr string, args []interface{}, opts InsertOptions) ([]rejectedRecord, error) {
+	if _, err := tx.Exec("SAVEPOINT generate_chunk"); err != nil {
+		return nil, fmt.Errorf("设置保存点失败: %v", err)
+	}
+	if _, err := tx.Exec(sqlStr, args...); err == nil {
+		_, err = tx.Exec("RELEASE SAVEPOINT generate_chunk")
+		return nil, err
+	}
+	if _, err := tx.Exec("ROLLBACK TO SAVEPOINT generate_chunk"); err != nil {
+		return nil, fmt.Errorf("回滚保存点失败: %v", err)
+	}
+
+	rowSQL, err := buildMultiRowInsert(dbType, tableName, columns, 1, opts)
+	if err != nil {
+		return nil, err
+	}
+	var rejects []rejectedRecord
+	for _, record := range chunk {
+		values := make([]interface{}, len(columns))
+		for i, column := range columns {
+			values[i] = record[column]
+		}
+
+		if _, err := tx.Exec("SAVEPOINT generate_row"); err != nil {
+			return nil, fmt.Errorf("设置保存点失败: %v", err)
+		}
+		if _, err := tx.Exec(rowSQL, values...); err != nil {
+			rejects = append(rejects, rejectedRecord{Record: record, Error: err.Error()})
+			if _, err := tx.Exec("ROLLBACK TO SAVEPOINT generate_row"); err != nil {
+				return nil, fmt.Errorf("回滚保存点失败: %v", err)
+			}
+			continue
+		}
+		if _, err := tx.Exec("RELEASE SAVEPOINT generate_row"); err != nil {
+			return nil, fmt.Errorf("释放保存点失败: %v", err)
+		}
+	}
+	if _, err := tx.Exec("RELEASE SAVEPOINT generate_chunk"); err != nil {
+		return nil, fmt.Errorf("释放保存点失败: %v", err)
+	}
+	return rejects, nil
+}
+
+// 构造多行INSERT语句，按写入模式添加忽略冲突或更新子句
+func buildMultiRowInsert(dbType, tableName string, columns []string, rows int, opts InsertOptions) (string, error) {
+	prefix, suffix, err := insertClauses(dbType, columns, opts)
+	if err != nil {
+		return "", err
+	}
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "%s %s (%s) VALUES ", prefix, tableName, strings.Join(columns, ", "))
+	n := 0
+	for i := 0; i < rows; i++ {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+		builder.WriteString("(")
+		for j := range columns {
+			if j > 0 {
+				builder.WriteString(", ")
+			}
+			n++
+			builder.WriteString(placeholder(dbType, n))
+		}
+		builder.WriteString(")")
+	}
+	builder.WriteString(suffix)
+	return builder.String(), nil
+}
+
+// PostgreSQL COPY FROM STDIN，在调用方的事务中执行
+func (s *ExportService) copyIn(tx *sql.Tx, tableName string, columns []string, records []map[string]interface{}) error {
+	stmt, err := tx.Prepare(pq.CopyIn(tableName, columns...))
+	if err != nil {
+		return fmt.Errorf("准备COPY语句失败: %v", err)
+	}
+
+	for _, record := range records {
+		values := make([]interface{}, len(columns))
+		for i, column := range columns {
+			values[i] = record[column]
+		}
+		if _, err := stmt.Exec(values...); err != nil {
+			stmt.Close()
+			return fmt.Errorf("COPY写入数据失败: %v", err)
+		}
+	}
+
+	// 无参数调用 Exec 结束 COPY
+	if _, err := stmt.Exec(); err != nil {
+		stmt.Close()
+		return fmt.Errorf("COPY写入数据失败: %v", err)
+	}
+	if err := stmt.Close(); err != nil {
+		return fmt.Errorf("结束COPY失败: %v", err)
+	}
+	return nil
+}
+
+// MySQL LOAD DATA LOCAL INFILE，在调用方的事务中执行，数据通过管道流式写入，不落地临时文件
+func (s *ExportService) loadData(tx *sql.Tx, tableName string, columns []string, records []map[string]interface{}) error {
+	name := fmt.Sprintf("generate_%d", atomic.AddInt64(&loadDataSeq, 1))
+	reader, writer := io.Pipe()
+	mysql.RegisterReaderHandler(name, func() io.Reader { return reader })
+	defer mysql.DeregisterReaderHandler(name)
+
+	go func() {
+		var err error
+		for _, record := range records {
+			fields := make([]string, len(columns))
+			for i, column := range columns {
+				fields[i] = utils.FormatLoadDataField(record[column])
+			}
+			if _, err = io.WriteString(writer, strings.Join(fields, "\t")+"\n"); err != nil {
+				break
+			}
+		}
+		writer.CloseWithError(err)
+	}()
+
+	query := fmt.Sprintf("LOAD DATA LOCAL INFILE 'Reader::%s' INTO TABLE %s CHARACTER SET utf8mb4 "+
+		"FIELDS TERMINATED BY '\\t' ESCAPED BY '\\\\' LINES TERMINATED BY '\\n' (%s)",
+		name, tableName, strings.Join(columns, ", "))
+	_, err := tx.Exec(query)
+	// 出错时驱动可能未读完数据，关闭读端让写协程退出
+	reader.Close()
+	if err != nil {
+		return fmt.Errorf("LOAD DATA写入数据失败: %v", err)
+	}
+	return nil
+}
@@ -317,3 +317,223 @@ func (s *DatabaseService) getSQLiteColumns(db *sql.DB, tableName string) ([]mode
 
 	return columns, nil
 }
+
+// 获取数据库中的所有外键
+func (s *DatabaseService) GetForeignKeys(ds *models.DataSource) ([]models.ForeignKey, error) {
+	db, err := s.openConnection(ds)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	switch strings.ToLower(ds.Type) {
+	case "mysql":
+		return s.getMySQLForeignKeys(db)
+	case "postgresql":
+		return s.getPostgreSQLForeignKeys(db)
+	case "sqlite":
+		return s.getSQLiteForeignKeys(db)
+	default:
+		return nil, fmt.Errorf("不支持的数据库类型: %s", ds.Type)
+	}
+}
+
+// 获取MySQL外键信息
+func (s *DatabaseService) getMySQLForeignKeys(db *sql.DB) ([]models.ForeignKey, error) {
+	query := `
+		SELECT
+			CONSTRAINT_NAME,
+			TABLE_NAME,
+			COLUMN_NAME,
+			REFERENCED_TABLE_NAME,
+			REFERENCED_COLUMN_NAME
+		FROM INFORMATION_SCHEMA.KEY_COLUMN_USAGE
+		WHERE TABLE_SCHEMA = DATABASE() AND REFERENCED_TABLE_NAME IS NOT NULL
+		ORDER BY TABLE_NAME, CONSTRAINT_NAME, ORDINAL_POSITION
+	`
+	return s.scanForeignKeys(db, query)
+}
+
+// 获取PostgreSQL外键信息
+func (s *DatabaseService) getPostgreSQLForeignKeys(db *sql.DB) ([]models.ForeignKey, error) {
+	query := `
+		SELECT
+			tc.constraint_name,
+			kcu.table_name,
+			kcu.column_name,
+			ccu.table_name,
+			ccu.column_name
+		FROM information_schema.table_constraints tc
+		JOIN information_schema.key_column_usage kcu
+			ON tc.constraint_name = kcu.constraint_name AND tc.table_schema = kcu.table_schema
+		JOIN information_schema.referential_constraints rc
+			ON tc.constraint_name = rc.constraint_name AND tc.table_schema = rc.constraint_schema
+		JOIN information_schema.key_column_usage ccu
+			ON rc.unique_constraint_name = ccu.constraint_name
+			AND rc.unique_constraint_schema = ccu.table_schema
+			AND kcu.position_in_unique_constraint = ccu.ordinal_position
+		WHERE tc.constraint_type = 'FOREIGN KEY' AND tc.table_schema = 'public'
+		ORDER BY kcu.table_name, tc.constraint_name, kcu.ordinal_position
+	`
+	return s.scanForeignKeys(db, query)
+}
+
+// 按约束名聚合外键列，查询需返回 约束名、表名、列名、引用表名、引用列名
+func (s *DatabaseService) scanForeignKeys(db *sql.DB, query string) ([]models.ForeignKey, error) {
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var foreignKeys []models.ForeignKey
+	index := make(map[string]int)
+	for rows.Next() {
+		var name, table, column, refTable, refColumn string
+		if err := rows.Scan(&name, &table, &column, &refTable, &refColumn); err != nil {
+			return nil, err
+		}
+
+		key := table + "." + name
+		if i, ok := index[key]; ok {
+			foreignKeys[i].Columns = append(foreignKeys[i].Columns, column)
+			foreignKeys[i].RefColumns = append(foreignKeys[i].RefColumns, refColumn)
+			continue
+		}
+		index[key] = len(foreignKeys)
+		foreignKeys = append(foreignKeys, models.ForeignKey{
+			Name:       name,
+			Table:      table,
+			Columns:    []string{column},
+			RefTable:   refTable,
+			RefColumns: []string{refColumn},
+		})
+	}
+
+	return foreignKeys, rows.Err()
+}
+
+// 获取SQLite外键信息
+func (s *DatabaseService) getSQLiteForeignKeys(db *sql.DB) ([]models.ForeignKey, error) {
+	rows, err := db.Query("SELECT name FROM sqlite_master WHERE type='table' AND name NOT LIKE 'sqlite_%'")
+	if err != nil {
+		return nil, err
+	}
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	rows.Close()
+
+	var foreignKeys []models.ForeignKey
+	for _, table := range tables {
+		fkRows, err := db.Query(fmt.Sprintf("PRAGMA foreign_key_list(%s)", table))
+		if err != nil {
+			return nil, err
+		}
+
+		// PRAGMA foreign_key_list 返回: id, seq, table, from, to, on_update, on_delete, match
+		index := make(map[int]int)
+		for fkRows.Next() {
+			var id, seq int
+			var refTable, from string
+			var to sql.NullString
+			var onUpdate, onDelete, match string
+			if err := fkRows.Scan(&id, &seq, &refTable, &from, &to, &onUpdate, &onDelete, &match); err != nil {
+				fkRows.Close()
+				return nil, err
+			}
+
+			refColumn := to.String
+			if i, ok := index[id]; ok {
+				foreignKeys[i].Columns = append(foreignKeys[i].Columns, from)
+				foreignKeys[i].RefColumns = append(foreignKeys[i].RefColumns, refColumn)
+				continue
+			}
+			index[id] = len(foreignKeys)
+			foreignKeys = append(foreignKeys, models.ForeignKey{
+				Name:       fmt.Sprintf("%s_fk_%d", table, id),
+				Table:      table,
+				Columns:    []string{from},
+				RefTable:   refTable,
+				RefColumns: []string{refColumn},
+			})
+		}
+		fkRows.Close()
+	}
+
+	// SQLite 允许省略被引用列，此时引用的是被引用表的主键
+	for i := range foreignKeys {
+		fk := &foreignKeys[i]
+		if len(fk.RefColumns) > 0 && fk.RefColumns[0] != "" {
+			continue
+		}
+		refColumns, err := s.getSQLiteColumns(db, fk.RefTable)
+		if err != nil {
+			return nil, err
+		}
+		fk.RefColumns = fk.RefColumns[:0]
+		for _, col := range refColumns {
+			if col.IsPrimaryKey {
+				fk.RefColumns = append(fk.RefColumns, col.Name)
+			}
+		}
+	}
+
+	return foreignKeys, nil
+}
+
+// 获取SQL占位符，PostgreSQL使用 $n，其余数据库使用 ?
+func placeholder(dbType string, n int) string {
+	if strings.ToLower(dbType) == "postgresql" {
+		return fmt.Sprintf("$%d", n)
+	}
+	return "?"
+}
+
+// 将查询结果扫描为记录列表
+func scanRecords(rows *sql.Rows) ([]map[string]interface{}, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var records []map[string]interface{}
+	for rows.Next() {
+		record, err := scanRecord(rows, columns)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
+
+// 扫描当前行为记录
+func scanRecord(rows *sql.Rows, columns []string) (map[string]interface{}, error) {
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	if err := rows.Scan(pointers...); err != nil {
+		return nil, err
+	}
+
+	record := make(map[string]interface{}, len(columns))
+	for i, column := range columns {
+		// 部分驱动以 []byte 返回字符串
+		if b, ok := values[i].([]byte); ok {
+			record[column] = string(b)
+		} else {
+			record[column] = values[i]
+		}
+	}
+	return record, nil
+}
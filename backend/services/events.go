@@ -0,0 +1,168 @@
+package services
+
+import (
+	"generateTestData/backend/models"
+	"math"
+	"sync"
+	"time"
+)
+
+// 任务执行阶段
+const (
+	PhaseIntrospecting = "introspecting" // 连接数据源、读取表结构
+	PhaseGenerating    = "generating"    // 生成并逐批输出数据
+	PhaseWriting       = "writing"       // 提交输出（刷新文件、提交事务、写清单）
+)
+
+// 任务事件类型
+const (
+	TaskEventStatus   = "status"   // 状态变化：排队中、执行中
+	TaskEventPhase    = "phase"    // 进入新的执行阶段
+	TaskEventProgress = "progress" // 进度、行数、吞吐量和预计剩余时间
+	TaskEventWarning  = "warning"  // 不影响执行结果的警告，如被拒绝的行
+	TaskEventResult   = "result"   // 执行结束：完成时附带结果，失败时附带错误信息
+)
+
+// 两次进度事件的最小间隔，进度100%不受限制
+const progressEventInterval = 200 * time.Millisecond
+
+// 两次写入任务表进度的最小间隔
+const progressSaveInterval = time.Second
+
+// 任务事件
+type TaskEvent struct {
+	Type          string             `json:"type"`
+	TaskID        uint               `json:"taskId"`
+	RunID         uint               `json:"runId,omitempty"`
+	Status        models.TaskStatus  `json:"status,omitempty"`
+	Phase         string             `json:"phase,omitempty"`
+	Progress      float64            `json:"progress"`
+	Rows          int64              `json:"rows,omitempty"`          // 已输出的行数
+	RowsPerSecond float64            `json:"rowsPerSecond,omitempty"` // 生成阶段开始以来的平均吞吐量
+	ETASeconds    float64            `json:"etaSeconds,omitempty"`    // 按当前进度估算的剩余秒数
+	Message       string             `json:"message,omitempty"`       // 警告或错误信息
+	Result        *models.TaskResult `json:"result,omitempty"`
+	Time          time.Time          `json:"time"`
+}
+
+// 事件订阅方，进程内共享
+var taskEvents = struct {
+	sync.Mutex
+	subscribers map[chan TaskEvent]uint // 订阅的任务ID，0表示全部任务
+}{subscribers: make(map[chan TaskEvent]uint)}
+
+// 订阅任务事件，taskID 为0时订阅全部任务。处理不及时的订阅方会丢失阶段、进度和警告事件，
+// 状态和结果事件总会送达。返回的函数用于取消订阅。
+func SubscribeTaskEvents(taskID uint) (<-chan TaskEvent, func()) {
+	events := make(chan TaskEvent, 256)
+	taskEvents.Lock()
+	taskEvents.subscribers[events] = taskID
+	taskEvents.Unlock()
+
+	var once sync.Once
+	return events, func() {
+		once.Do(func() {
+			taskEvents.Lock()
+			delete(taskEvents.subscribers, events)
+			taskEvents.Unlock()
+		})
+	}
+}
+
+// 发布任务事件，不会阻塞执行
+func publishTaskEvent(event TaskEvent) {
+	event.Time = time.Now()
+	taskEvents.Lock()
+	defer taskEvents.Unlock()
+	for events, taskID := range taskEvents.subscribers {
+		if taskID != 0 && taskID != event.TaskID {
+			continue
+		}
+		deliverTaskEvent(events, event)
+	}
+}
+
+// 投递事件，缓冲区已满时丢弃进度等事件；状态和结果事件则挤掉缓冲区中最早的一条进度等事件，
+// 全是状态和结果事件时挤掉最早的一条。调用方已持有锁，是唯一的发送方
+func deliverTaskEvent(events chan TaskEvent, event TaskEvent) {
+	select {
+	case events <- event:
+		return
+	default:
+	}
+	if event.Type != TaskEventStatus && event.Type != TaskEventResult {
+		return
+	}
+
+	// 取出缓冲区中的事件，订阅方可能同时在读取
+	buffered := make([]TaskEvent, 0, cap(events))
+	for drained := false; !drained; {
+		select {
+		case e := <-events:
+			buffered = append(buffered, e)
+		default:
+			drained = true
+		}
+	}
+	if len(buffered) == cap(events) {
+		evict := 0
+		for i, e := range buffered {
+			if e.Type != TaskEventStatus && e.Type != TaskEventResult {
+				evict = i
+				break
+			}
+		}
+		buffered = append(buffered[:evict], buffered[evict+1:]...)
+	}
+	for _, e := range buffered {
+		events <- e
+	}
+	events <- event
+}
+
+// 一次执行的进度上报：推送阶段和进度事件，并限制写入任务表的频率
+type runReporter struct {
+	taskID    uint
+	runID     uint
+	phase     string
+	started   time.Time // 生成阶段开始的时间，用于计算吞吐量和剩余时间
+	published time.Time
+	saved     time.Time
+}
+
+func newRunReporter(task *models.Task, run *models.TaskRun) *runReporter {
+	return &runReporter{taskID: task.ID, runID: run.ID, started: time.Now()}
+}
+
+func (r *runReporter) setPhase(phase string) {
+	if phase == r.phase {
+		return
+	}
+	r.phase = phase
+	if phase == PhaseGenerating {
+		r.started = time.Now()
+	}
+	publishTaskEvent(TaskEvent{Type: TaskEventPhase, TaskID: r.taskID, RunID: r.runID, Phase: phase})
+}
+
+func (r *runReporter) progress(progress float64, rows int64) {
+	now := time.Now()
+	if progress < 100 && now.Sub(r.published) < progressEventInterval {
+		return
+	}
+	r.published = now
+
+	event := TaskEvent{Type: TaskEventProgress, TaskID: r.taskID, RunID: r.runID, Phase: r.phase, Progress: progress, Rows: rows}
+	if elapsed := now.Sub(r.started).Seconds(); elapsed > 0 {
+		event.RowsPerSecond = math.Round(float64(rows) / elapsed)
+		if progress > 0 && progress < 100 {
+			event.ETASeconds = math.Round(elapsed * (100 - progress) / progress)
+		}
+	}
+	publishTaskEvent(event)
+
+	if progress >= 100 || now.Sub(r.saved) >= progressSaveInterval {
+		r.saved = now
+		models.DB.Model(&models.Task{}).Where("id = ?", r.taskID).Update("progress", progress)
+	}
+}
@@ -1,13 +1,14 @@
 package services
 
 import (
+	"bufio"
 	"database/sql"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"generateTestData/backend/config"
 	"generateTestData/backend/models"
-	"os"
+	"io"
 	"path/filepath"
 	"strings"
 )
@@ -50,272 +51,475 @@ func ensureFileExtension(fileName, extension string) string {
 	return fileName + "." + extension
 }
 
-// 插入数据到数据库
-func (s *ExportService) InsertToDatabase(dataSource *models.DataSource, tableName string, records []map[string]interface{}) error {
-	if len(records) == 0 {
-		return nil
+// DatabaseWriter 在整个任务期间使用同一个数据库连接和事务，按写入方式逐批写入：
+// Open 时开启事务，Close 时提交，Abort 时回滚，任务失败不会在目标表中留下部分数据。
+// 设置 CommitEachBatch 时每批在各自的事务中提交。多表任务通过 StartTable 切换目标表。
+type DatabaseWriter struct {
+	service    *ExportService
+	dataSource *models.DataSource
+	dbType     string
+	tableName  string
+	options    InsertOptions
+	conflicts  map[string][]string // 各表 upsert 判断冲突的列
+	db         *sql.DB
+	tx         *sql.Tx // 整个任务的事务，每批单独提交时为空
+	rejected   int64
+	rejects    []rejectedRecord // 等待事务提交后写入拒绝文件的记录
+	logger     *runLogger       // 记录被拒绝的行
+}
+
+// 创建数据库输出，options 为 nil 时使用默认的批量事务写入
+func (s *ExportService) NewDatabaseWriter(dataSource *models.DataSource, tableName string, options *InsertOptions) *DatabaseWriter {
+	return &DatabaseWriter{
+		service:    s,
+		dataSource: dataSource,
+		tableName:  tableName,
+		options:    options.withDefaults(),
+		conflicts:  make(map[string][]string),
 	}
+}
 
-	// 连接数据库
-	db, err := s.connectDatabase(dataSource)
+// 连接数据库并开启事务，指定了表名时按写入模式准备目标表
+func (w *DatabaseWriter) Open() error {
+	if w.dataSource == nil {
+		return fmt.Errorf("数据源不能为空")
+	}
+	w.dbType = strings.ToLower(w.dataSource.Type)
+	db, err := w.service.connectDatabase(w.dataSource)
 	if err != nil {
 		return fmt.Errorf("连接数据库失败: %v", err)
 	}
-	defer db.Close()
+	w.db = db
+
+	if w.dbType == "sqlite" {
+		// PRAGMA 作用于连接，限制为单连接保证事务使用同一连接
+		db.SetMaxOpenConns(1)
+		if w.options.LoadMode == LoadModeBatch {
+			for _, pragma := range []string{
+				"PRAGMA synchronous = OFF",
+				"PRAGMA journal_mode = MEMORY",
+				"PRAGMA temp_store = MEMORY",
+				"PRAGMA cache_size = -64000",
+			} {
+				if _, err := db.Exec(pragma); err != nil {
+					return fmt.Errorf("设置SQLite参数失败: %v", err)
+				}
+			}
+		}
+	}
 
-	// 构建插入SQL
-	firstRecord := records[0]
-	columns := make([]string, 0, len(firstRecord))
-	for column := range firstRecord {
-		columns = append(columns, column)
+	if !w.options.CommitEachBatch {
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("开启事务失败: %v", err)
+		}
+		w.tx = tx
 	}
 
-	// 构建占位符
-	placeholders := make([]string, len(columns))
-	for i := range placeholders {
-		placeholders[i] = "?"
+	if w.tableName != "" {
+		return w.PrepareTable(w.tableName)
 	}
+	return nil
+}
 
-	sqlStr := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
-		tableName,
-		strings.Join(columns, ", "),
-		strings.Join(placeholders, ", "))
+// 在任务的事务中执行语句，每批单独提交时直接执行
+func (w *DatabaseWriter) exec(query string, args ...interface{}) (sql.Result, error) {
+	if w.tx != nil {
+		return w.tx.Exec(query, args...)
+	}
+	return w.db.Exec(query, args...)
+}
 
-	// 准备语句
-	stmt, err := db.Prepare(sqlStr)
-	if err != nil {
-		return fmt.Errorf("准备SQL语句失败: %v", err)
+// 开始写入另一张表，之后的数据写入 tableName
+func (w *DatabaseWriter) StartTable(tableName string) {
+	w.tableName = tableName
+}
+
+// 写入一批记录，按错误策略被跳过的记录计入 Rejected
+func (w *DatabaseWriter) WriteBatch(records []map[string]interface{}) error {
+	if len(records) == 0 {
+		return nil
+	}
+	opts := w.options
+	if columns, ok := w.conflicts[w.tableName]; ok {
+		opts.ConflictColumns = columns
 	}
-	defer stmt.Close()
 
-	// 批量插入
-	for _, record := range records {
-		values := make([]interface{}, len(columns))
-		for i, column := range columns {
-			values[i] = record[column]
-		}
+	columns := recordColumns(records)
 
-		_, err = stmt.Exec(values...)
+	tx := w.tx
+	if tx == nil {
+		batchTx, err := w.db.Begin()
 		if err != nil {
-			return fmt.Errorf("插入数据失败: %v", err)
+			return fmt.Errorf("开启事务失败: %v", err)
+		}
+		defer batchTx.Rollback()
+		tx = batchTx
+	}
+
+	var rejects []rejectedRecord
+	var err error
+	switch opts.LoadMode {
+	case LoadModeRow:
+		rejects, err = w.service.insertRows(tx, w.dbType, w.tableName, columns, records, opts)
+	case LoadModeBatch:
+		rejects, err = w.service.insertBatches(tx, w.dbType, w.tableName, columns, records, opts)
+	case LoadModeCopy:
+		if w.dbType != "postgresql" {
+			return fmt.Errorf("COPY写入仅支持PostgreSQL")
+		}
+		err = w.service.copyIn(tx, w.tableName, columns, records)
+	case LoadModeLoadData:
+		if w.dbType != "mysql" {
+			return fmt.Errorf("LOAD DATA写入仅支持MySQL")
+		}
+		err = w.service.loadData(tx, w.tableName, columns, records)
+	default:
+		return fmt.Errorf("不支持的写入方式: %s", opts.LoadMode)
+	}
+	if err != nil {
+		return err
+	}
+	if tx != w.tx {
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("提交事务失败: %v", err)
 		}
 	}
 
+	// 拒绝文件只记录已提交的写入：整个任务在一个事务中时等到 Close 提交后再写
+	if len(rejects) > 0 && opts.ErrorPolicy == ErrorPolicyRejectFile {
+		if w.tx != nil {
+			for _, reject := range rejects {
+				reject.Table = w.tableName
+				w.rejects = append(w.rejects, reject)
+			}
+		} else if err := w.service.writeRejects(w.tableName, rejects, opts); err != nil {
+			return err
+		}
+	}
+	for _, reject := range rejects {
+		w.logger.rejectedRow(reject.Record, reject.Error)
+	}
+	w.rejected += int64(len(rejects))
 	return nil
 }
 
-// 导出为SQL文件（使用批量INSERT提高性能）
-func (s *ExportService) ExportToSQL(fileName, tableName string, records []map[string]interface{}, isFirst bool) error {
-	if len(records) == 0 {
+// 按错误策略被跳过的记录数
+func (w *DatabaseWriter) Rejected() int64 {
+	return w.rejected
+}
+
+func (w *DatabaseWriter) setLogger(logger *runLogger) {
+	w.logger = logger
+}
+
+// 提交任务的事务、写入拒绝文件并关闭数据库连接，可重复调用
+func (w *DatabaseWriter) Close() error {
+	if w.db == nil {
 		return nil
 	}
+	var err error
+	if w.tx != nil {
+		if commitErr := w.tx.Commit(); commitErr != nil {
+			err = fmt.Errorf("提交事务失败: %v", commitErr)
+		}
+		w.tx = nil
+	}
+	if err == nil {
+		err = w.writeRejects()
+	}
+	w.rejects = nil
+	db := w.db
+	w.db = nil
+	if closeErr := db.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
 
-	// 确保文件名有正确的后缀
-	fileName = ensureFileExtension(fileName, "sql")
+// 按表写入缓存的拒绝记录
+func (w *DatabaseWriter) writeRejects() error {
+	for start := 0; start < len(w.rejects); {
+		end := start + 1
+		for end < len(w.rejects) && w.rejects[end].Table == w.rejects[start].Table {
+			end++
+		}
+		if err := w.service.writeRejects(w.rejects[start].Table, w.rejects[start:end], w.options); err != nil {
+			return err
+		}
+		start = end
+	}
+	return nil
+}
 
-	// 自动拼接文件路径
-	filePath := filepath.Join(config.AppConfig.GenerateDir, fileName)
+// 回滚任务的事务并释放连接，丢弃未提交写入的拒绝记录；每批单独提交时已提交的批次无法撤销
+func (w *DatabaseWriter) Abort() error {
+	if w.tx != nil {
+		w.tx.Rollback()
+		w.tx = nil
+	}
+	w.rejects = nil
+	return w.Close()
+}
 
-	// 打开文件
-	var file *os.File
-	var err error
-	if isFirst {
-		file, err = os.Create(filePath)
-	} else {
-		file, err = os.OpenFile(filePath, os.O_APPEND|os.O_WRONLY, 0644)
+// SQLScriptWriter 将多张表写入同一个SQL文件（子集任务）：文件头尾语句只写一次，
+// 每张表在 StartTable 时输出 CREATE TABLE 等表级语句
+type SQLScriptWriter struct {
+	path    string
+	dialect sqlDialect
+	options SQLOptions
+	file    *tempFile
+	buffer  *bufio.Writer
+	table   *models.TableInfo
+	tables  []string
+	name    string
+}
+
+// 创建多表SQL文件
+func (s *ExportService) NewSQLScriptWriter(fileName string, options *SQLOptions) (*SQLScriptWriter, error) {
+	w := &SQLScriptWriter{}
+	if options != nil {
+		w.options = *options
 	}
+	dialect, err := w.options.dialect()
 	if err != nil {
-		return fmt.Errorf("打开文件失败: %v", err)
+		return nil, err
 	}
-	defer file.Close()
-
-	// 获取列名
-	firstRecord := records[0]
-	columns := make([]string, 0, len(firstRecord))
-	for column := range firstRecord {
-		columns = append(columns, column)
+	if fileName == "" {
+		return nil, fmt.Errorf("必须指定输出路径")
 	}
+	w.dialect = dialect
+	w.name = ensureFileExtension(fileName, "sql")
+	w.path = filepath.Join(config.AppConfig.GenerateDir, w.name)
+	return w, nil
+}
 
-	// 批量INSERT的每批大小（避免SQL语句过长）
-	batchSize := 1000
-	if len(records) < batchSize {
-		batchSize = len(records)
+// 创建文件并写入文件头部语句（关闭外键检查、开启事务）
+func (w *SQLScriptWriter) Open() error {
+	file, err := createTempFile(w.path)
+	if err != nil {
+		return err
 	}
+	w.file = file
+	w.buffer = bufio.NewWriterSize(file, 256*1024)
+	return writeSQLStatements(w.buffer, w.dialect.header(&w.options))
+}
 
-	// 分批写入批量INSERT语句
-	for i := 0; i < len(records); i += batchSize {
-		end := i + batchSize
-		if end > len(records) {
-			end = len(records)
+// 开始写入一张表，按选项输出 CREATE TABLE 和关闭约束检查的语句
+func (w *SQLScriptWriter) StartTable(tableInfo *models.TableInfo) error {
+	var statements []string
+	if w.options.CreateTable && len(tableInfo.Columns) > 0 {
+		statements = append(statements, w.dialect.createTable(tableInfo))
+	}
+	if w.options.DisableForeignKeys {
+		if stmt := w.dialect.disableTableConstraints(tableInfo.TableName); stmt != "" {
+			statements = append(statements, stmt)
 		}
+	}
+	w.table = tableInfo
+	w.tables = append(w.tables, tableInfo.TableName)
+	return writeSQLStatements(w.buffer, statements)
+}
 
-		// 构建批量INSERT语句
-		valuesList := make([]string, 0, end-i)
-		for j := i; j < end; j++ {
-			record := records[j]
-			values := make([]string, len(columns))
-			for k, column := range columns {
-				value := record[column]
-				if value == nil {
-					values[k] = "NULL"
-				} else {
-					switch v := value.(type) {
-					case string:
-						values[k] = fmt.Sprintf("'%s'", strings.ReplaceAll(v, "'", "''"))
-					default:
-						values[k] = fmt.Sprintf("%v", v)
-					}
-				}
-			}
-			valuesList = append(valuesList, fmt.Sprintf("(%s)", strings.Join(values, ", ")))
-		}
+// 以INSERT语句形式写入当前表的一批记录，列顺序与表结构一致
+func (w *SQLScriptWriter) WriteBatch(records []map[string]interface{}) error {
+	if w.table == nil {
+		return fmt.Errorf("写入数据前必须先指定表")
+	}
+	return writeSQLStatements(w.buffer, sqlInsertStatements(w.dialect, w.table, records))
+}
 
-		// 写入批量INSERT语句
-		sqlStr := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s;\n",
-			tableName,
-			strings.Join(columns, ", "),
-			strings.Join(valuesList, ", "))
+// 写入其他语句，如外键回填的 UPDATE
+func (w *SQLScriptWriter) WriteStatements(statements []string) error {
+	return writeSQLStatements(w.buffer, statements)
+}
 
-		_, err = file.WriteString(sqlStr)
-		if err != nil {
-			return fmt.Errorf("写入文件失败: %v", err)
+// 写入文件尾部语句（恢复约束检查、提交事务）并保存文件
+func (w *SQLScriptWriter) Close() error {
+	if w.file == nil {
+		return nil
+	}
+	var statements []string
+	if w.options.DisableForeignKeys {
+		for _, tableName := range w.tables {
+			if stmt := w.dialect.enableTableConstraints(tableName); stmt != "" {
+				statements = append(statements, stmt)
+			}
 		}
 	}
+	statements = append(statements, w.dialect.footer(&w.options)...)
+	if err := writeSQLStatements(w.buffer, statements); err != nil {
+		return err
+	}
+	if err := w.buffer.Flush(); err != nil {
+		return fmt.Errorf("写入文件失败: %v", err)
+	}
+	file := w.file
+	w.file = nil
+	return file.commit()
+}
 
+// 放弃输出，删除临时文件
+func (w *SQLScriptWriter) Abort() error {
+	if w.file != nil {
+		w.file.discard()
+		w.file = nil
+	}
 	return nil
 }
 
-// 导出为TXT文件（每行一个JSON字符串）
-func (s *ExportService) ExportToTXT(fileName string, jsonObjects []map[string]interface{}, isFirst bool) error {
-	if len(jsonObjects) == 0 {
-		return nil
+// 生成的文件（相对于生成目录）
+func (w *SQLScriptWriter) Files() []string {
+	return []string{w.name}
+}
+
+// 生成批量INSERT语句
+func sqlInsertStatements(dialect sqlDialect, tableInfo *models.TableInfo, records []map[string]interface{}) []string {
+	columns, columnTypes := sqlColumns(tableInfo, records)
+
+	// 批量INSERT的每批大小（避免SQL语句过长，SQL Server 单条最多1000行）
+	batchSize := 1000
+	var statements []string
+	for i := 0; i < len(records); i += batchSize {
+		end := i + batchSize
+		if end > len(records) {
+			end = len(records)
+		}
+		statements = append(statements, dialect.insertStatement(tableInfo.TableName, columns, columnTypes, records[i:end]))
 	}
+	return statements
+}
 
-	// 确保文件名有正确的后缀
-	fileName = ensureFileExtension(fileName, "txt")
+// 单表SQL文件的编码器，每个分片都带有文件头尾语句，CREATE TABLE 只出现在第一个分片
+type sqlEncoder struct {
+	dialect   sqlDialect
+	tableInfo *models.TableInfo
+	options   *SQLOptions
+}
 
-	// 自动拼接文件路径
-	filePath := filepath.Join(config.AppConfig.GenerateDir, fileName)
+func (e *sqlEncoder) extension() string { return "sql" }
 
-	// 打开文件
-	var file *os.File
-	var err error
-	if isFirst {
-		file, err = os.Create(filePath)
-	} else {
-		file, err = os.OpenFile(filePath, os.O_APPEND|os.O_WRONLY, 0644)
+func (e *sqlEncoder) begin(w io.Writer, part int) error {
+	statements := e.dialect.header(e.options)
+	if part == 1 && e.options.CreateTable && len(e.tableInfo.Columns) > 0 {
+		statements = append(statements, e.dialect.createTable(e.tableInfo))
 	}
-	if err != nil {
-		return fmt.Errorf("打开文件失败: %v", err)
+	if e.options.DisableForeignKeys {
+		if stmt := e.dialect.disableTableConstraints(e.tableInfo.TableName); stmt != "" {
+			statements = append(statements, stmt)
+		}
 	}
-	defer file.Close()
+	return writeSQLStatements(w, statements)
+}
 
-	// 写入JSON对象，每行一个
-	for i, obj := range jsonObjects {
-		jsonData, err := json.Marshal(obj)
-		if err != nil {
-			return fmt.Errorf("序列化JSON失败: %v", err)
-		}
+func (e *sqlEncoder) encode(w io.Writer, records []map[string]interface{}) error {
+	return writeSQLStatements(w, sqlInsertStatements(e.dialect, e.tableInfo, records))
+}
 
-		// 写入JSON字符串和换行符
-		if i == len(jsonObjects)-1 {
-			_, err = file.WriteString(string(jsonData))
-		} else {
-			_, err = file.WriteString(string(jsonData) + "\n")
-		}
-		if err != nil {
-			return fmt.Errorf("写入文件失败: %v", err)
+func (e *sqlEncoder) end(w io.Writer) error {
+	var statements []string
+	if e.options.DisableForeignKeys {
+		if stmt := e.dialect.enableTableConstraints(e.tableInfo.TableName); stmt != "" {
+			statements = append(statements, stmt)
 		}
 	}
-
-	return nil
+	return writeSQLStatements(w, append(statements, e.dialect.footer(e.options)...))
 }
 
-// 导出为JSON文件
-func (s *ExportService) ExportToJSON(fileName string, jsonObjects []map[string]interface{}, isFirst bool) error {
-	if len(jsonObjects) == 0 {
+// 写入SQL语句，每条语句一行
+func writeSQLStatements(w io.Writer, statements []string) error {
+	if len(statements) == 0 {
 		return nil
 	}
+	if _, err := io.WriteString(w, strings.Join(statements, "\n")+"\n"); err != nil {
+		return fmt.Errorf("写入文件失败: %v", err)
+	}
+	return nil
+}
 
-	// 确保文件名有正确的后缀
-	fileName = ensureFileExtension(fileName, "json")
+// JSON输出选项（存储在 Task.Configuration 中）
+type JSONOptions struct {
+	Pretty *bool `json:"pretty"` // JSON数组是否缩进输出，默认缩进；为false时每行一个紧凑的对象
+}
 
-	// 自动拼接文件路径
-	filePath := filepath.Join(config.AppConfig.GenerateDir, fileName)
+func (o *JSONOptions) pretty() bool {
+	return o.Pretty == nil || *o.Pretty
+}
 
-	// 打开文件
-	var file *os.File
-	var err error
-	if isFirst {
-		file, err = os.Create(filePath)
-		if err != nil {
-			return fmt.Errorf("创建文件失败: %v", err)
-		}
-		// 写入数组开始符号
-		_, err = file.WriteString("[\n")
-		if err != nil {
-			return fmt.Errorf("写入文件失败: %v", err)
+// JSON数组编码器：每个分片是一个完整的数组，数组的开闭由分片的开始和结束写入，无需回退文件指针
+type jsonArrayEncoder struct {
+	pretty bool
+	count  int64 // 当前分片已写入的对象数
+}
+
+func (e *jsonArrayEncoder) extension() string { return "json" }
+
+func (e *jsonArrayEncoder) begin(w io.Writer, part int) error {
+	e.count = 0
+	_, err := io.WriteString(w, "[")
+	return err
+}
+
+func (e *jsonArrayEncoder) encode(w io.Writer, records []map[string]interface{}) error {
+	indent := ""
+	if e.pretty {
+		indent = "  "
+	}
+	for _, obj := range records {
+		var jsonData []byte
+		var err error
+		if e.pretty {
+			jsonData, err = json.MarshalIndent(obj, indent, indent)
+		} else {
+			jsonData, err = json.Marshal(obj)
 		}
-	} else {
-		file, err = os.OpenFile(filePath, os.O_RDWR, 0644)
 		if err != nil {
-			return fmt.Errorf("打开文件失败: %v", err)
+			return fmt.Errorf("序列化JSON失败: %v", err)
 		}
-		// 移动到文件末尾前2个字符（去掉最后的\n]）
-		stat, err := file.Stat()
-		if err != nil {
-			return fmt.Errorf("获取文件信息失败: %v", err)
+		separator := ",\n" + indent
+		if e.count == 0 {
+			separator = "\n" + indent
 		}
-		_, err = file.Seek(stat.Size()-2, 0)
-		if err != nil {
-			return fmt.Errorf("移动文件指针失败: %v", err)
+		if _, err := io.WriteString(w, separator); err != nil {
+			return fmt.Errorf("写入文件失败: %v", err)
 		}
-		// 写入逗号
-		_, err = file.WriteString(",\n")
-		if err != nil {
+		if _, err := w.Write(jsonData); err != nil {
 			return fmt.Errorf("写入文件失败: %v", err)
 		}
+		e.count++
 	}
-	defer file.Close()
+	return nil
+}
+
+func (e *jsonArrayEncoder) end(w io.Writer) error {
+	_, err := io.WriteString(w, "\n]\n")
+	return err
+}
+
+// 每行一个JSON对象的编码器（JSON Lines），每行都以换行符结尾
+type jsonLineEncoder struct {
+	ext string
+}
+
+func (e *jsonLineEncoder) extension() string { return e.ext }
+
+func (e *jsonLineEncoder) begin(w io.Writer, part int) error { return nil }
 
-	// 写入JSON对象
-	for i, obj := range jsonObjects {
-		jsonData, err := json.MarshalIndent(obj, "  ", "  ")
+func (e *jsonLineEncoder) encode(w io.Writer, records []map[string]interface{}) error {
+	for _, obj := range records {
+		jsonData, err := json.Marshal(obj)
 		if err != nil {
 			return fmt.Errorf("序列化JSON失败: %v", err)
 		}
-
-		// 添加缩进
-		lines := strings.Split(string(jsonData), "\n")
-		for j, line := range lines {
-			if j == 0 {
-				_, err = file.WriteString("  " + line + "\n")
-			} else {
-				_, err = file.WriteString("  " + line + "\n")
-			}
-			if err != nil {
-				return fmt.Errorf("写入文件失败: %v", err)
-			}
-		}
-
-		// 如果不是最后一个对象，添加逗号
-		if i < len(jsonObjects)-1 {
-			_, err = file.WriteString(",\n")
-			if err != nil {
-				return fmt.Errorf("写入文件失败: %v", err)
-			}
+		if _, err := w.Write(append(jsonData, '\n')); err != nil {
+			return fmt.Errorf("写入文件失败: %v", err)
 		}
 	}
-
-	// 写入数组结束符号
-	_, err = file.WriteString("\n]")
-	if err != nil {
-		return fmt.Errorf("写入文件失败: %v", err)
-	}
-
 	return nil
 }
 
+func (e *jsonLineEncoder) end(w io.Writer) error { return nil }
+
 // 连接数据库
 func (s *ExportService) connectDatabase(dataSource *models.DataSource) (*sql.DB, error) {
 	var dsn string
@@ -335,65 +539,57 @@ func (s *ExportService) connectDatabase(dataSource *models.DataSource) (*sql.DB,
 	}
 }
 
-// ExportToCSV 导出为CSV文件
-func (s *ExportService) ExportToCSV(fileName string, headers []string, records []map[string]interface{}, isFirstBatch bool) error {
-
-	// 确保文件名有正确的后缀
-	fileName = ensureFileExtension(fileName, "csv")
-
-	// 自动拼接文件路径
-	filePath := filepath.Join(config.AppConfig.GenerateDir, fileName)
-
-	// 打开文件
-	flag := os.O_APPEND | os.O_WRONLY | os.O_CREATE
-	if isFirstBatch {
-		flag = os.O_TRUNC | os.O_WRONLY | os.O_CREATE
-	}
-
-	file, err := os.OpenFile(filePath, flag, 0644)
-	if err != nil {
-		return fmt.Errorf("打开文件失败: %v", err)
+// 按表头顺序转换一条记录
+func csvRow(headers []string, record map[string]interface{}) []string {
+	row := make([]string, len(headers))
+	for i, header := range headers {
+		val := record[header]
+		// 处理不同类型的值
+		switch v := val.(type) {
+		case nil:
+			row[i] = ""
+		case string:
+			row[i] = v
+		case float64:
+			// 去除多余的小数点0
+			str := fmt.Sprintf("%f", v)
+			row[i] = strings.TrimRight(strings.TrimRight(str, "0"), ".")
+		default:
+			row[i] = fmt.Sprintf("%v", v)
+		}
 	}
-	defer file.Close()
+	return row
+}
 
-	// 写入BOM头，防止中文乱码 (仅首次写入)
-	if isFirstBatch {
-		file.WriteString("\xEF\xBB\xBF")
-	}
+// CSV编码器，每个分片都带BOM头和表头
+type csvEncoder struct {
+	headers []string
+}
 
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+func (e *csvEncoder) extension() string { return "csv" }
 
-	// 写入表头 (仅首次写入)
-	if isFirstBatch {
-		if err := writer.Write(headers); err != nil {
-			return fmt.Errorf("写入表头失败: %v", err)
-		}
+func (e *csvEncoder) begin(w io.Writer, part int) error {
+	// 写入BOM头，防止中文乱码
+	if _, err := io.WriteString(w, "\xEF\xBB\xBF"); err != nil {
+		return fmt.Errorf("写入文件失败: %v", err)
+	}
+	writer := csv.NewWriter(w)
+	if err := writer.Write(e.headers); err != nil {
+		return fmt.Errorf("写入表头失败: %v", err)
 	}
+	writer.Flush()
+	return writer.Error()
+}
 
-	// 写入数据
+func (e *csvEncoder) encode(w io.Writer, records []map[string]interface{}) error {
+	writer := csv.NewWriter(w)
 	for _, record := range records {
-		row := make([]string, len(headers))
-		for i, header := range headers {
-			val := record[header]
-			// 处理不同类型的值
-			switch v := val.(type) {
-			case nil:
-				row[i] = ""
-			case string:
-				row[i] = v
-			case float64:
-				// 去除多余的小数点0
-				str := fmt.Sprintf("%f", v)
-				row[i] = strings.TrimRight(strings.TrimRight(str, "0"), ".")
-			default:
-				row[i] = fmt.Sprintf("%v", v)
-			}
-		}
-		if err := writer.Write(row); err != nil {
+		if err := writer.Write(csvRow(e.headers, record)); err != nil {
 			return fmt.Errorf("写入数据失败: %v", err)
 		}
 	}
-
-	return nil
+	writer.Flush()
+	return writer.Error()
 }
+
+func (e *csvEncoder) end(w io.Writer) error { return nil }
@@ -0,0 +1,477 @@
+package services
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"generateTestData/backend/config"
+	"generateTestData/backend/models"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// 按大小分片时每次交给编码器的最大记录数，分片大小可能因此略超过上限
+const fileSplitChunk = 100
+
+// 文件输出选项（存储在 Task.Configuration 中），适用于逐条编码的文件格式
+type OutputOptions struct {
+	Compression string `json:"compression"` // none（默认）, gzip, zstd
+	SplitRows   int64  `json:"splitRows"`   // 每个分片的最大行数，为0表示不按行数分片
+	SplitBytes  int64  `json:"splitBytes"`  // 每个分片的最大字节数（压缩前），为0表示不按大小分片
+	Manifest    bool   `json:"manifest"`    // 生成清单文件，列出各分片的行数、大小和SHA-256校验和
+}
+
+// 获取压缩方式
+func (o *OutputOptions) compression() (string, error) {
+	compression := ""
+	if o != nil {
+		compression = strings.ToLower(o.Compression)
+	}
+	switch compression {
+	case "", "none":
+		return "", nil
+	case "gzip", "gz":
+		return "gzip", nil
+	case "zstd", "zstandard":
+		return "zstd", nil
+	default:
+		return "", fmt.Errorf("不支持的压缩方式: %s", o.Compression)
+	}
+}
+
+// 是否设置了压缩、分片或清单
+func (o *OutputOptions) enabled() bool {
+	compression, _ := o.compression()
+	return compression != "" || o.SplitRows > 0 || o.SplitBytes > 0 || o.Manifest
+}
+
+func (o *OutputOptions) validate() error {
+	if _, err := o.compression(); err != nil {
+		return err
+	}
+	if o.SplitRows < 0 || o.SplitBytes < 0 {
+		return fmt.Errorf("分片行数和分片大小不能为负数")
+	}
+	return nil
+}
+
+// 逐条编码的文件格式。分片时每个分片都以 begin 开始、以 end 结束，是完整可用的文件
+type recordEncoder interface {
+	extension() string
+	begin(w io.Writer, part int) error
+	encode(w io.Writer, records []map[string]interface{}) error
+	end(w io.Writer) error
+}
+
+// 清单中的分片信息
+type FilePart struct {
+	File   string `json:"file"`   // 相对于生成目录的文件名
+	Rows   int64  `json:"rows"`   // 记录数
+	Bytes  int64  `json:"bytes"`  // 文件大小（压缩后）
+	SHA256 string `json:"sha256"` // 文件的SHA-256校验和
+}
+
+// 清单文件内容
+type fileManifest struct {
+	Format      string     `json:"format"`
+	Compression string     `json:"compression,omitempty"`
+	Rows        int64      `json:"rows"`
+	Parts       []FilePart `json:"parts"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// 统计写入的字节数
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// 正在写入的分片：编码器 -> 计数 -> 缓冲 -> 压缩 -> 计数 + 校验和 -> 文件
+type openFilePart struct {
+	info       FilePart
+	file       *tempFile
+	hash       hash.Hash
+	size       *countingWriter
+	compressor io.WriteCloser
+	buffer     *bufio.Writer
+	counter    *countingWriter
+}
+
+// FileWriter 在整个任务期间保持文件打开，负责压缩、按行数或大小分片以及生成清单。
+// 各分片先写入临时文件，Close 时删除上次运行遗留的多余分片和清单，统一重命名后再写入清单；
+// 出错或 Abort 时只删除本次运行尚未提交的临时文件。
+type FileWriter struct {
+	name        string // 不含后缀的文件名（相对于生成目录）
+	encoder     recordEncoder
+	options     OutputOptions
+	compression string
+
+	part   *openFilePart
+	parts  []FilePart
+	temps  []*tempFile // 已写完、等待提交的分片
+	files  []string
+	rows   int64
+	closed bool
+}
+
+// 按任务的输出类型和配置创建文件输出，tableInfo 用于SQL输出，headers 用于CSV输出
+func (s *ExportService) NewFileWriter(task *models.Task, tableInfo *models.TableInfo, headers []string) (*FileWriter, error) {
+	var options OutputOptions
+	if err := task.GetConfiguration(&options); err != nil {
+		return nil, fmt.Errorf("解析文件输出配置失败: %v", err)
+	}
+	encoder, err := s.newRecordEncoder(task, tableInfo, headers)
+	if err != nil {
+		return nil, err
+	}
+	return newFileWriter(task.OutputPath, encoder, &options)
+}
+
+// 是否为通过 FileWriter 逐条编码的文件输出
+func isFileOutput(outputType models.OutputType) bool {
+	switch outputType {
+	case models.OutputTypeJSON, models.OutputTypeTXT, models.OutputTypeJSONL, models.OutputTypeCSV, models.OutputTypeSQL,
+		models.OutputTypeXML, models.OutputTypeYAML, models.OutputTypeTemplate, models.OutputTypeProtobuf:
+		return true
+	default:
+		return false
+	}
+}
+
+// 按输出类型创建编码器
+func (s *ExportService) newRecordEncoder(task *models.Task, tableInfo *models.TableInfo, headers []string) (recordEncoder, error) {
+	switch task.OutputType {
+	case models.OutputTypeJSON:
+		var options JSONOptions
+		if err := task.GetConfiguration(&options); err != nil {
+			return nil, fmt.Errorf("解析JSON配置失败: %v", err)
+		}
+		return &jsonArrayEncoder{pretty: options.pretty()}, nil
+	case models.OutputTypeTXT:
+		return &jsonLineEncoder{ext: "txt"}, nil
+	case models.OutputTypeJSONL:
+		return &jsonLineEncoder{ext: "jsonl"}, nil
+	case models.OutputTypeCSV:
+		return &csvEncoder{headers: headers}, nil
+	case models.OutputTypeSQL:
+		var options SQLOptions
+		if err := task.GetConfiguration(&options); err != nil {
+			return nil, fmt.Errorf("解析SQL导出配置失败: %v", err)
+		}
+		options.defaultDialect(task.DataSource)
+		dialect, err := options.dialect()
+		if err != nil {
+			return nil, err
+		}
+		return &sqlEncoder{dialect: dialect, tableInfo: tableInfo, options: &options}, nil
+	case models.OutputTypeXML:
+		var options XMLOptions
+		if err := task.GetConfiguration(&options); err != nil {
+			return nil, fmt.Errorf("解析XML配置失败: %v", err)
+		}
+		return newXMLEncoder(&options), nil
+	case models.OutputTypeYAML:
+		return &yamlEncoder{}, nil
+	case models.OutputTypeTemplate:
+		var options TemplateOptions
+		if err := task.GetConfiguration(&options); err != nil {
+			return nil, fmt.Errorf("解析模板配置失败: %v", err)
+		}
+		return newTemplateEncoder(task.OutputPath, &options)
+	case models.OutputTypeProtobuf:
+		var options ProtobufOptions
+		if err := task.GetConfiguration(&options); err != nil {
+			return nil, fmt.Errorf("解析Protobuf配置失败: %v", err)
+		}
+		message, err := s.LoadProtoMessage(&options)
+		if err != nil {
+			return nil, err
+		}
+		return &protobufEncoder{descriptor: message}, nil
+	default:
+		return nil, fmt.Errorf("不支持的文件输出类型: %s", task.OutputType)
+	}
+}
+
+func newFileWriter(fileName string, encoder recordEncoder, options *OutputOptions) (*FileWriter, error) {
+	w := &FileWriter{encoder: encoder}
+	if options != nil {
+		w.options = *options
+	}
+	if err := w.options.validate(); err != nil {
+		return nil, err
+	}
+	w.compression, _ = w.options.compression()
+	if fileName == "" {
+		return nil, fmt.Errorf("必须指定输出路径")
+	}
+
+	// 确保文件名有正确的后缀，分片和压缩后缀在此基础上添加
+	extension := encoder.extension()
+	fileName = ensureFileExtension(fileName, extension)
+	w.name = fileName[:len(fileName)-len(extension)-1]
+	return w, nil
+}
+
+// 创建第一个分片并写入文件头
+func (w *FileWriter) Open() error {
+	if w.part != nil || len(w.parts) > 0 {
+		return nil
+	}
+	return w.openPart()
+}
+
+// 写入一批记录，达到分片上限时在记录边界切换到下一个分片
+func (w *FileWriter) WriteBatch(records []map[string]interface{}) error {
+	for len(records) > 0 {
+		if w.part == nil {
+			if err := w.openPart(); err != nil {
+				return err
+			}
+		}
+
+		n := int64(len(records))
+		if w.options.SplitRows > 0 && n > w.options.SplitRows-w.part.info.Rows {
+			n = w.options.SplitRows - w.part.info.Rows
+		}
+		if w.options.SplitBytes > 0 && n > fileSplitChunk {
+			n = fileSplitChunk
+		}
+		if err := w.encoder.encode(w.part.counter, records[:n]); err != nil {
+			return err
+		}
+		w.part.info.Rows += n
+		w.rows += n
+		records = records[n:]
+
+		if (w.options.SplitRows > 0 && w.part.info.Rows >= w.options.SplitRows) ||
+			(w.options.SplitBytes > 0 && w.part.counter.n >= w.options.SplitBytes) {
+			if err := w.closePart(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// 结束最后一个分片，将所有分片重命名为正式文件并写入清单，可重复调用
+func (w *FileWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	// 没有任何数据时仍生成一个只有文件头尾的文件
+	if w.part == nil && len(w.parts) == 0 {
+		if err := w.openPart(); err != nil {
+			return err
+		}
+	}
+	if w.part != nil {
+		if err := w.closePart(); err != nil {
+			w.abort()
+			return err
+		}
+	}
+	if err := w.removeStaleFiles(); err != nil {
+		w.abort()
+		return err
+	}
+	for len(w.temps) > 0 {
+		if err := w.temps[0].commit(); err != nil {
+			w.abort()
+			return err
+		}
+		w.files = append(w.files, w.parts[len(w.files)].File)
+		w.temps = w.temps[1:]
+	}
+	if w.options.Manifest {
+		if err := w.writeManifest(); err != nil {
+			w.abort()
+			return err
+		}
+	}
+	return nil
+}
+
+// 放弃输出，删除尚未提交的分片临时文件，可重复调用
+func (w *FileWriter) Abort() error {
+	if w.closed && len(w.temps) == 0 && w.part == nil {
+		return nil
+	}
+	w.closed = true
+	w.abort()
+	return nil
+}
+
+func (w *FileWriter) abort() {
+	if w.part != nil {
+		w.part.file.discard()
+		w.part = nil
+	}
+	for _, temp := range w.temps {
+		temp.discard()
+	}
+	w.temps = nil
+}
+
+// 删除上次运行生成、本次不再使用的分片（如上次分片更多时的 orders-0004.csv）和旧清单，
+// 避免新旧文件混在一起；本次的分片随后重命名时会覆盖同名文件
+func (w *FileWriter) removeStaleFiles() error {
+	path := filepath.Join(config.AppConfig.GenerateDir, w.name)
+	dir, base := filepath.Split(path)
+	pattern := regexp.MustCompile(`^` + regexp.QuoteMeta(base) + `-\d{4,}\.` + regexp.QuoteMeta(w.encoder.extension()) + `(\.gz|\.zst)?$`)
+	current := make(map[string]bool, len(w.parts))
+	for _, part := range w.parts {
+		current[filepath.Base(part.File)] = true
+	}
+
+	entries, err := os.ReadDir(filepath.Clean(dir))
+	if err != nil {
+		return fmt.Errorf("读取输出目录失败: %v", err)
+	}
+	stale := []string{path + ".manifest.json"}
+	for _, entry := range entries {
+		if !entry.IsDir() && pattern.MatchString(entry.Name()) && !current[entry.Name()] {
+			stale = append(stale, filepath.Join(dir, entry.Name()))
+		}
+	}
+	for _, file := range stale {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("删除旧文件失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// 已生成的文件（相对于生成目录），包括清单文件
+func (w *FileWriter) Files() []string {
+	return w.files
+}
+
+// 已写入的记录数
+func (w *FileWriter) Rows() int64 {
+	return w.rows
+}
+
+// 分片文件名，如 orders-0001.csv.gz
+func (w *FileWriter) partName(index int) string {
+	name := w.name
+	if w.options.SplitRows > 0 || w.options.SplitBytes > 0 {
+		name = fmt.Sprintf("%s-%04d", name, index)
+	}
+	name += "." + w.encoder.extension()
+	switch w.compression {
+	case "gzip":
+		name += ".gz"
+	case "zstd":
+		name += ".zst"
+	}
+	return name
+}
+
+func (w *FileWriter) openPart() error {
+	index := len(w.parts) + 1
+	name := w.partName(index)
+	file, err := createTempFile(filepath.Join(config.AppConfig.GenerateDir, name))
+	if err != nil {
+		return err
+	}
+
+	part := &openFilePart{info: FilePart{File: name}, file: file, hash: sha256.New()}
+	part.size = &countingWriter{w: io.MultiWriter(file, part.hash)}
+	var sink io.Writer = part.size
+	switch w.compression {
+	case "gzip":
+		part.compressor = gzip.NewWriter(sink)
+	case "zstd":
+		encoder, err := zstd.NewWriter(sink)
+		if err != nil {
+			file.discard()
+			return fmt.Errorf("创建压缩流失败: %v", err)
+		}
+		part.compressor = encoder
+	}
+	if part.compressor != nil {
+		sink = part.compressor
+	}
+	part.buffer = bufio.NewWriterSize(sink, 256*1024)
+	part.counter = &countingWriter{w: part.buffer}
+	w.part = part
+
+	if err := w.encoder.begin(part.counter, index); err != nil {
+		return err
+	}
+	return nil
+}
+
+// 结束当前分片，出错时分片仍保留在 w.part 中，由 Abort 删除
+func (w *FileWriter) closePart() error {
+	part := w.part
+	if err := w.encoder.end(part.counter); err != nil {
+		return err
+	}
+	if err := part.buffer.Flush(); err != nil {
+		return fmt.Errorf("写入文件失败: %v", err)
+	}
+	if part.compressor != nil {
+		if err := part.compressor.Close(); err != nil {
+			return fmt.Errorf("写入压缩流失败: %v", err)
+		}
+	}
+	if err := part.file.Close(); err != nil {
+		return fmt.Errorf("写入文件失败: %v", err)
+	}
+
+	part.info.Bytes = part.size.n
+	part.info.SHA256 = hex.EncodeToString(part.hash.Sum(nil))
+	w.parts = append(w.parts, part.info)
+	w.temps = append(w.temps, part.file)
+	w.part = nil
+	return nil
+}
+
+// 写入清单文件，如 orders.manifest.json
+func (w *FileWriter) writeManifest() error {
+	manifest := fileManifest{
+		Format:      w.encoder.extension(),
+		Compression: w.compression,
+		Rows:        w.rows,
+		Parts:       w.parts,
+		CreatedAt:   time.Now(),
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	name := w.name + ".manifest.json"
+	file, err := createTempFile(filepath.Join(config.AppConfig.GenerateDir, name))
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.discard()
+		return fmt.Errorf("写入清单文件失败: %v", err)
+	}
+	if err := file.commit(); err != nil {
+		return err
+	}
+	w.files = append(w.files, name)
+	return nil
+}
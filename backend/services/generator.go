@@ -5,6 +5,7 @@ import (
 	"generateTestData/backend/models"
 	"math/big"
 	"math/rand"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -14,20 +15,83 @@ import (
 	regen "github.com/zach-klippenstein/goregen"
 )
 
+// 唯一值检查失败后重新生成的最大次数
+const maxUniqueAttempts = 1000
+
 type GeneratorService struct {
-	uniqueValues     map[string]map[interface{}]bool // 用于存储唯一值
-	sequenceCounters map[string]*big.Int             // 序列计数器，支持大整数
-	dbService        *DatabaseService
+	unique           UniqueStore         // 用于存储唯一值
+	sequenceCounters map[string]*big.Int // 序列计数器，支持大整数
+	lookup           LookupProvider
 	lookupCache      map[string][]interface{}
+	rng              *rand.Rand
+	now              func() time.Time
+}
+
+// 生成器选项，未设置的项使用默认实现
+type GeneratorOptions struct {
+	Seed   *int64           // 随机种子，相同种子和规则生成相同的数据；为空时使用随机种子
+	Lookup LookupProvider   // db_lookup 规则的取值来源
+	Unique UniqueStore      // 唯一字段已生成的值，可在多个生成器之间共享
+	Now    func() time.Time // 当前时间，用于默认的日期范围
+}
+
+// db_lookup 规则的取值来源
+type LookupProvider interface {
+	// 返回可供随机选取的值，dataSourceID 为规则中的 dataSourceId（未指定时为0）
+	LookupValues(dataSourceID uint, table, column string, context map[string]interface{}) ([]interface{}, error)
+}
+
+// 唯一字段的取值记录
+type UniqueStore interface {
+	// 记录字段值，值已存在时返回 false
+	Add(field string, value interface{}) bool
+}
+
+// 内存中的唯一值记录
+type MemoryUniqueStore struct {
+	values map[string]map[interface{}]bool
+}
+
+func NewMemoryUniqueStore() *MemoryUniqueStore {
+	return &MemoryUniqueStore{values: make(map[string]map[interface{}]bool)}
+}
+
+func (m *MemoryUniqueStore) Add(field string, value interface{}) bool {
+	if _, exists := m.values[field]; !exists {
+		m.values[field] = make(map[interface{}]bool)
+	}
+	if m.values[field][value] {
+		return false
+	}
+	m.values[field][value] = true
+	return true
 }
 
 func NewGeneratorService(dbService *DatabaseService) *GeneratorService {
-	return &GeneratorService{
-		uniqueValues:     make(map[string]map[interface{}]bool),
+	return NewGenerator(GeneratorOptions{Lookup: &databaseLookup{dbService: dbService}})
+}
+
+// 按选项创建生成器，不依赖平台数据库和配置
+func NewGenerator(options GeneratorOptions) *GeneratorService {
+	seed := time.Now().UnixNano()
+	if options.Seed != nil {
+		seed = *options.Seed
+	}
+	g := &GeneratorService{
+		unique:           options.Unique,
 		sequenceCounters: make(map[string]*big.Int),
-		dbService:        dbService,
+		lookup:           options.Lookup,
 		lookupCache:      make(map[string][]interface{}),
+		rng:              rand.New(rand.NewSource(seed)),
+		now:              options.Now,
+	}
+	if g.unique == nil {
+		g.unique = NewMemoryUniqueStore()
+	}
+	if g.now == nil {
+		g.now = time.Now
 	}
+	return g
 }
 
 // 生成单条数据库记录
@@ -43,7 +107,7 @@ func (g *GeneratorService) GenerateRecord(tableInfo *models.TableInfo, rules map
 
 		value, err := g.generateValue(column.Name, column.Type, rule, uniqueFields, context)
 		if err != nil {
-			return nil, fmt.Errorf("生成字段 %s 的值失败: %v", column.Name, err)
+			return nil, err
 		}
 
 		record[column.Name] = value
@@ -64,8 +128,50 @@ func (g *GeneratorService) GenerateJSON(schema map[string]interface{}, rules map
 	return nil, fmt.Errorf("生成的结果不是有效的JSON对象")
 }
 
-// 生成值
+// 字段生成失败的错误，记录出错的字段、规则和行号，便于定位自定义脚本等规则的问题
+type FieldError struct {
+	Field string
+	Rule  string
+	Row   int64 // 从0开始的行号，上下文中没有行号时为-1
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("生成字段 %s 的值失败: %v", e.Field, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+func newFieldError(fieldName string, rule models.FieldRule, context map[string]interface{}, err error) *FieldError {
+	row, ok := context["rowIndex"].(int64)
+	if !ok {
+		row = -1
+	}
+	return &FieldError{Field: fieldName, Rule: rule.Type, Row: row, Err: err}
+}
+
+// 生成值，唯一字段的值重复时重新生成
 func (g *GeneratorService) generateValue(fieldName, fieldType string, rule models.FieldRule, uniqueFields []string, context map[string]interface{}) (interface{}, error) {
+	unique := g.isUniqueField(fieldName, uniqueFields)
+	for attempt := 0; ; attempt++ {
+		value, err := g.generateRuleValue(fieldName, fieldType, rule, context)
+		if err != nil {
+			return nil, newFieldError(fieldName, rule, context, err)
+		}
+		// 检查唯一性约束
+		if !unique || g.unique.Add(fieldName, value) {
+			return value, nil
+		}
+		if attempt >= maxUniqueAttempts {
+			return nil, newFieldError(fieldName, rule, context, fmt.Errorf("重试 %d 次仍无法生成不重复的值", maxUniqueAttempts))
+		}
+	}
+}
+
+// 按规则生成一个值
+func (g *GeneratorService) generateRuleValue(fieldName, fieldType string, rule models.FieldRule, context map[string]interface{}) (interface{}, error) {
 	var value interface{}
 	var err error
 
@@ -114,16 +220,6 @@ func (g *GeneratorService) generateValue(fieldName, fieldType string, rule model
 	if err != nil {
 		return nil, err
 	}
-
-	// 检查唯一性约束
-	if g.isUniqueField(fieldName, uniqueFields) {
-		if g.isValueExists(fieldName, value) {
-			// 如果值已存在，重新生成
-			return g.generateValue(fieldName, fieldType, rule, uniqueFields, context)
-		}
-		g.addUniqueValue(fieldName, value)
-	}
-
 	return value, nil
 }
 
@@ -204,7 +300,7 @@ func (g *GeneratorService) generateRandom(fieldType string, rule models.FieldRul
 
 	switch strings.ToLower(fieldType) {
 	case "int", "integer", "bigint", "smallint", "tinyint":
-		return rand.Intn(1000000), nil
+		return g.rng.Intn(1000000), nil
 	case "varchar", "text", "char", "string":
 		// 检查字段名是否包含日期相关关键词
 		if strings.Contains(fieldName, "date") || strings.Contains(fieldName, "time") ||
@@ -224,7 +320,7 @@ func (g *GeneratorService) generateRandom(fieldType string, rule models.FieldRul
 		}
 		return g.generateRandomString(length), nil
 	case "decimal", "float", "double", "numeric":
-		return rand.Float64() * 1000, nil
+		return g.rng.Float64() * 1000, nil
 	case "date":
 		// 如果有日期范围参数，使用日期范围生成
 		if _, hasStart := rule.Parameters["start"]; hasStart {
@@ -254,7 +350,7 @@ func (g *GeneratorService) generateRandom(fieldType string, rule models.FieldRul
 		}
 		return result.Format("2006-01-02 15:04:05"), nil
 	case "boolean", "bool":
-		return rand.Intn(2) == 1, nil
+		return g.rng.Intn(2) == 1, nil
 	default:
 		return g.generateRandomString(10), nil
 	}
@@ -297,9 +393,9 @@ func (g *GeneratorService) generateRange(fieldType string, rule models.FieldRule
 	case "int", "integer", "bigint", "smallint", "tinyint":
 		minVal := int(minFloat)
 		maxVal := int(maxFloat)
-		return rand.Intn(maxVal-minVal+1) + minVal, nil
+		return g.rng.Intn(maxVal-minVal+1) + minVal, nil
 	case "decimal", "float", "double", "numeric":
-		return rand.Float64()*(maxFloat-minFloat) + minFloat, nil
+		return g.rng.Float64()*(maxFloat-minFloat) + minFloat, nil
 	default:
 		return nil, fmt.Errorf("字段类型 %s 不支持范围生成", fieldType)
 	}
@@ -330,13 +426,13 @@ func (g *GeneratorService) generateRegex(rule models.FieldRule) (interface{}, er
 	convertedPattern = strings.ReplaceAll(convertedPattern, "\\s", "[ \\t\\n\\r]")
 
 	// 使用 goregen 生成符合正则的随机字符串
-	result, err := regen.Generate(convertedPattern)
+	generator, err := regen.NewGenerator(convertedPattern, &regen.GeneratorArgs{RngSource: g.rng})
 	if err != nil {
 		// 最后回退到随机字符串
 		return g.generateRandomString(10), fmt.Errorf("无法解析正则表达式 %s: %v，使用默认随机字符串", pattern, err)
 	}
 
-	return result, nil
+	return generator.Generate(), nil
 }
 
 // 生成枚举值
@@ -356,7 +452,7 @@ func (g *GeneratorService) generateEnum(rule models.FieldRule) (interface{}, err
 		return nil, fmt.Errorf("枚举值不能为空")
 	}
 
-	return values[rand.Intn(len(values))], nil
+	return values[g.rng.Intn(len(values))], nil
 }
 
 // 生成自定义值
@@ -367,6 +463,7 @@ func (g *GeneratorService) generateCustom(rule models.FieldRule, context map[str
 	}
 
 	vm := goja.New()
+	vm.SetRandSource(g.rng.Float64)
 
 	// 注入上下文变量
 	for key, value := range context {
@@ -375,7 +472,7 @@ func (g *GeneratorService) generateCustom(rule models.FieldRule, context map[str
 
 	// 注入辅助函数
 	vm.Set("randomInt", func(min, max int) int {
-		return rand.Intn(max-min+1) + min
+		return g.rng.Intn(max-min+1) + min
 	})
 
 	// 注入 Faker 对象
@@ -392,20 +489,20 @@ func (g *GeneratorService) generateCustom(rule models.FieldRule, context map[str
 	fakerObj.Set("ChineseName", faker.ChineseName)
 	fakerObj.Set("ChinesePhone", func() string {
 		prefixes := []string{"133", "135", "136", "137", "138", "139", "150", "151", "152", "157", "158", "159", "182", "186", "187", "188", "189", "198", "199"}
-		prefix := prefixes[rand.Intn(len(prefixes))]
-		return fmt.Sprintf("%s%08d", prefix, rand.Intn(100000000))
+		prefix := prefixes[g.rng.Intn(len(prefixes))]
+		return fmt.Sprintf("%s%08d", prefix, g.rng.Intn(100000000))
 	})
 	fakerObj.Set("ChineseIdCard", func() string {
 		// 简单生成18位身份证号：6位地区码 + 8位生日 + 3位顺序码 + 1位校验码
 		// 这里只做简单模拟
 		areaCodes := []string{"110101", "310101", "440101", "330106", "510107"}
-		area := areaCodes[rand.Intn(len(areaCodes))]
+		area := areaCodes[g.rng.Intn(len(areaCodes))]
 
-		year := rand.Intn(50) + 1970 // 1970-2020
-		month := rand.Intn(12) + 1
-		day := rand.Intn(28) + 1
+		year := g.rng.Intn(50) + 1970 // 1970-2020
+		month := g.rng.Intn(12) + 1
+		day := g.rng.Intn(28) + 1
 
-		return fmt.Sprintf("%s%d%02d%02d%04d", area, year, month, day, rand.Intn(10000))
+		return fmt.Sprintf("%s%d%02d%02d%04d", area, year, month, day, g.rng.Intn(10000))
 	})
 
 	vm.Set("faker", fakerObj)
@@ -423,8 +520,16 @@ func (g *GeneratorService) generateCustom(rule models.FieldRule, context map[str
 func (g *GeneratorService) generateJSONValue(path string, schema interface{}, rules map[string]models.FieldRule, uniqueFields []string, context map[string]interface{}) (interface{}, error) {
 	switch v := schema.(type) {
 	case map[string]interface{}:
+		// 按字段名顺序生成，固定随机种子时结果可复现
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
 		result := make(map[string]interface{})
-		for key, value := range v {
+		for _, key := range keys {
+			value := v[key]
 			fieldPath := path
 			if fieldPath != "" {
 				fieldPath += "."
@@ -516,26 +621,9 @@ func (g *GeneratorService) isUniqueField(fieldName string, uniqueFields []string
 	return false
 }
 
-// 检查值是否已存在
-func (g *GeneratorService) isValueExists(fieldName string, value interface{}) bool {
-	if values, exists := g.uniqueValues[fieldName]; exists {
-		// uniqueValues  =>  map[string]map[interface{}]bool
-		return values[value]
-	}
-	return false
-}
-
-// 添加唯一值
-func (g *GeneratorService) addUniqueValue(fieldName string, value interface{}) {
-	if _, exists := g.uniqueValues[fieldName]; !exists {
-		g.uniqueValues[fieldName] = make(map[interface{}]bool)
-	}
-	g.uniqueValues[fieldName][value] = true
-}
-
 // 重置生成器状态
 func (g *GeneratorService) Reset() {
-	g.uniqueValues = make(map[string]map[interface{}]bool)
+	g.unique = NewMemoryUniqueStore()
 	// 注意：不重置序列计数器，保持序列的连续性
 	// g.sequenceCounters = make(map[string]*big.Int)
 }
@@ -548,11 +636,11 @@ func (g *GeneratorService) ResetSequenceCounters() {
 // 生成UUID
 func (g *GeneratorService) generateUUID() string {
 	return fmt.Sprintf("%08x-%04x-%04x-%04x-%012x",
-		rand.Uint32(),
-		rand.Uint32()&0xffff,
-		rand.Uint32()&0xffff,
-		rand.Uint32()&0xffff,
-		rand.Uint64()&0xffffffffffff)
+		g.rng.Uint32(),
+		g.rng.Uint32()&0xffff,
+		g.rng.Uint32()&0xffff,
+		g.rng.Uint32()&0xffff,
+		g.rng.Uint64()&0xffffffffffff)
 }
 
 // 生成随机字符串
@@ -560,7 +648,7 @@ func (g *GeneratorService) generateRandomString(length int) string {
 	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 	result := make([]byte, length)
 	for i := range result {
-		result[i] = charset[rand.Intn(len(charset))]
+		result[i] = charset[g.rng.Intn(len(charset))]
 	}
 	return string(result)
 }
@@ -568,16 +656,16 @@ func (g *GeneratorService) generateRandomString(length int) string {
 // 生成随机日期
 func (g *GeneratorService) generateRandomDate() time.Time {
 	min := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
-	max := time.Now().Unix()
+	max := g.now().Unix()
 	delta := max - min
-	sec := rand.Int63n(delta) + min
+	sec := g.rng.Int63n(delta) + min
 	return time.Unix(sec, 0)
 }
 
 // 数据库反查
 func (g *GeneratorService) generateDBLookup(rule models.FieldRule, context map[string]interface{}) (interface{}, error) {
-	if g.dbService == nil {
-		return nil, fmt.Errorf("database service not initialized")
+	if g.lookup == nil {
+		return nil, fmt.Errorf("db_lookup 规则未配置取值来源")
 	}
 
 	tableName, ok := rule.Parameters["tableName"].(string)
@@ -590,50 +678,33 @@ func (g *GeneratorService) generateDBLookup(rule models.FieldRule, context map[s
 	}
 
 	// 尝试从规则参数获取 dataSourceId
-	var ds *models.DataSource
+	var dsID uint
 	if dsIDParam, ok := rule.Parameters["dataSourceId"]; ok {
-		var dsID uint
 		switch v := dsIDParam.(type) {
 		case float64:
 			dsID = uint(v)
 		case int:
 			dsID = uint(v)
+		case uint:
+			dsID = v
 		case string:
 			// 尝试解析字符串
 			if id, err := strconv.ParseUint(v, 10, 64); err == nil {
 				dsID = uint(id)
 			}
 		}
-
-		if dsID > 0 {
-			var dataSource models.DataSource
-			if err := models.DB.First(&dataSource, dsID).Error; err == nil {
-				ds = &dataSource
-			}
-		}
-	}
-
-	// 如果规则中没有指定或找不到，尝试从 context 获取 DataSource
-	if ds == nil {
-		if val, ok := context["dataSource"]; ok && val != nil {
-			ds, _ = val.(*models.DataSource)
-		}
-	}
-
-	if ds == nil {
-		return nil, fmt.Errorf("dataSource not found in rule parameters or context")
 	}
 
 	// 缓存 Key
-	cacheKey := fmt.Sprintf("%d:%s:%s", ds.ID, tableName, columnName)
+	cacheKey := fmt.Sprintf("%d:%s:%s", dsID, tableName, columnName)
 
 	// 检查缓存
 	if values, ok := g.lookupCache[cacheKey]; ok && len(values) > 0 {
-		return values[rand.Intn(len(values))], nil
+		return values[g.rng.Intn(len(values))], nil
 	}
 
-	// 缓存未命中，从数据库拉取
-	values, err := g.dbService.GetRandomRecords(ds, tableName, columnName, 1000)
+	// 缓存未命中，从取值来源拉取
+	values, err := g.lookup.LookupValues(dsID, tableName, columnName, context)
 	if err != nil {
 		return nil, fmt.Errorf("db lookup failed: %v", err)
 	}
@@ -645,7 +716,38 @@ func (g *GeneratorService) generateDBLookup(rule models.FieldRule, context map[s
 	// 更新缓存
 	g.lookupCache[cacheKey] = values
 
-	return values[rand.Intn(len(values))], nil
+	return values[g.rng.Intn(len(values))], nil
+}
+
+// 从平台数据源随机读取反查值：优先使用规则中的 dataSourceId，其次使用上下文中任务的数据源
+type databaseLookup struct {
+	dbService *DatabaseService
+}
+
+func (l *databaseLookup) LookupValues(dataSourceID uint, table, column string, context map[string]interface{}) ([]interface{}, error) {
+	if l.dbService == nil {
+		return nil, fmt.Errorf("database service not initialized")
+	}
+
+	var ds *models.DataSource
+	if dataSourceID > 0 && models.DB != nil {
+		var dataSource models.DataSource
+		if err := models.DB.First(&dataSource, dataSourceID).Error; err == nil {
+			ds = &dataSource
+		}
+	}
+
+	// 如果规则中没有指定或找不到，尝试从 context 获取 DataSource
+	if ds == nil {
+		if val, ok := context["dataSource"]; ok && val != nil {
+			ds, _ = val.(*models.DataSource)
+		}
+	}
+
+	if ds == nil {
+		return nil, fmt.Errorf("dataSource not found in rule parameters or context")
+	}
+	return l.dbService.GetRandomRecords(ds, table, column, 1000)
 }
 
 // 生成日期范围内的随机日期
@@ -678,7 +780,7 @@ func (g *GeneratorService) generateDateRange(rule models.FieldRule) (interface{}
 			}
 		}
 	} else {
-		endTime = time.Now()
+		endTime = g.now()
 	}
 
 	// 确保开始时间小于结束时间
@@ -692,7 +794,7 @@ func (g *GeneratorService) generateDateRange(rule models.FieldRule) (interface{}
 		return startTime, nil
 	}
 
-	sec := rand.Int63n(delta) + startTime.Unix()
+	sec := g.rng.Int63n(delta) + startTime.Unix()
 	resultTime := time.Unix(sec, 0)
 
 	// 根据格式参数返回相应格式
@@ -760,13 +862,13 @@ func (g *GeneratorService) generateDateSequence(fieldName string, rule models.Fi
 
 // 生成随机手机号
 func (g *GeneratorService) generatePhone() string {
-	return fmt.Sprintf("1%d%08d", rand.Intn(9)+1, rand.Intn(100000000))
+	return fmt.Sprintf("1%d%08d", g.rng.Intn(9)+1, g.rng.Intn(100000000))
 }
 
 // 生成随机邮箱
 func (g *GeneratorService) generateEmail() string {
 	domains := []string{"gmail.com", "yahoo.com", "hotmail.com", "outlook.com", "qq.com"}
 	username := g.generateRandomString(8)
-	domain := domains[rand.Intn(len(domains))]
+	domain := domains[g.rng.Intn(len(domains))]
 	return fmt.Sprintf("%s@%s", username, domain)
 }
@@ -0,0 +1,451 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"generateTestData/backend/config"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// HTTP输出选项（存储在 Task.Configuration 中）
+type HTTPOptions struct {
+	URL            string            `json:"httpUrl"`            // 请求地址，可使用模板语法
+	Method         string            `json:"httpMethod"`         // 请求方法，默认 POST
+	Headers        map[string]string `json:"httpHeaders"`        // 请求头，值可使用模板语法
+	Body           string            `json:"httpBody"`           // 请求体模板，为空时发送记录（或记录数组）的JSON
+	Mode           string            `json:"httpMode"`           // batch（默认，每个请求发送多条记录）, record（每条记录一个请求）
+	BatchSize      int               `json:"httpBatchSize"`      // batch 模式每个请求的记录数，默认100
+	Concurrency    int               `json:"httpConcurrency"`    // 并发请求数，默认1
+	RateLimit      float64           `json:"httpRateLimit"`      // 每秒最多发送的请求数，0 表示不限制
+	TimeoutMs      int               `json:"httpTimeoutMs"`      // 单个请求的超时时间（毫秒），默认30000
+	MaxRetries     *int              `json:"httpMaxRetries"`     // 5xx 和 429 响应及网络错误的最大重试次数，默认3
+	RetryBackoffMs int               `json:"httpRetryBackoffMs"` // 首次重试的等待时间（毫秒），之后每次翻倍，默认500
+	Auth           string            `json:"httpAuth"`           // none（默认）, bearer, basic, hmac
+	Token          string            `json:"httpToken"`          // bearer 认证的令牌
+	Username       string            `json:"httpUsername"`       // basic 认证的用户名
+	Password       string            `json:"httpPassword"`       // basic 认证的密码
+	HMACSecret     string            `json:"httpHmacSecret"`     // hmac 签名的密钥，对请求体计算 HMAC-SHA256
+	HMACHeader     string            `json:"httpHmacHeader"`     // 签名请求头，默认 X-Signature，值为 sha256=<十六进制签名>
+	DeadLetterFile string            `json:"httpDeadLetterFile"` // 发送失败的请求写入此文件（JSON Lines），为空时发送失败即终止任务
+}
+
+// 校验配置
+func (o *HTTPOptions) validate() error {
+	if o.URL == "" {
+		return fmt.Errorf("HTTP输出必须指定请求地址")
+	}
+	switch o.method() {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodGet:
+	default:
+		return fmt.Errorf("不支持的HTTP请求方法: %s", o.Method)
+	}
+	switch strings.ToLower(o.Mode) {
+	case "", "batch", "record":
+	default:
+		return fmt.Errorf("不支持的HTTP发送模式: %s", o.Mode)
+	}
+	if o.BatchSize < 0 || o.Concurrency < 0 || o.RateLimit < 0 || o.TimeoutMs < 0 || o.RetryBackoffMs < 0 ||
+		(o.MaxRetries != nil && *o.MaxRetries < 0) {
+		return fmt.Errorf("HTTP批次、并发、限速和重试配置不能为负数")
+	}
+	switch strings.ToLower(o.Auth) {
+	case "", "none":
+	case "bearer":
+		if o.Token == "" {
+			return fmt.Errorf("bearer 认证必须指定令牌")
+		}
+	case "basic":
+		if o.Username == "" {
+			return fmt.Errorf("basic 认证必须指定用户名")
+		}
+	case "hmac":
+		if o.HMACSecret == "" {
+			return fmt.Errorf("hmac 签名必须指定密钥")
+		}
+	default:
+		return fmt.Errorf("不支持的HTTP认证方式: %s", o.Auth)
+	}
+	_, _, _, err := o.templates()
+	return err
+}
+
+func (o *HTTPOptions) method() string {
+	if o.Method == "" {
+		return http.MethodPost
+	}
+	return strings.ToUpper(o.Method)
+}
+
+// 解析地址、请求头和请求体模板，请求体模板为空时返回 nil
+func (o *HTTPOptions) templates() (url *template.Template, headers map[string]*template.Template, body *template.Template, err error) {
+	if url, err = parseValueTemplate("url", o.URL); err != nil {
+		return nil, nil, nil, fmt.Errorf("解析请求地址模板失败: %v", err)
+	}
+	headers = make(map[string]*template.Template, len(o.Headers))
+	for name, text := range o.Headers {
+		if headers[name], err = parseValueTemplate(name, text); err != nil {
+			return nil, nil, nil, fmt.Errorf("解析请求头 %s 失败: %v", name, err)
+		}
+	}
+	if o.Body != "" {
+		if body, err = parseValueTemplate("body", o.Body); err != nil {
+			return nil, nil, nil, fmt.Errorf("解析请求体模板失败: %v", err)
+		}
+	}
+	return url, headers, body, nil
+}
+
+// HTTPWriter 将记录逐条或按批发送到HTTP接口，整个任务复用同一个HTTP客户端。
+// 模板数据：record 模式为记录本身；batch 模式为 {"records": 记录数组, "count": 记录数}。
+type HTTPWriter struct {
+	options    HTTPOptions
+	url        *template.Template
+	headers    map[string]*template.Template
+	body       func(data interface{}) ([]byte, error)
+	perRecord  bool
+	batchSize  int // 每个请求的记录数，0 表示整批发送
+	maxRetries int
+	backoff    time.Duration
+	client     *http.Client
+	limiter    *rateLimiter
+
+	mu             sync.Mutex
+	deadLetter     *os.File
+	deadLetterName string
+	rejected       int64
+	logger         *runLogger // 记录重试和写入死信文件的请求
+}
+
+// 一个待发送的请求
+type httpPayload struct {
+	data    interface{} // 模板数据
+	records int
+}
+
+// 创建HTTP输出
+func (s *ExportService) NewHTTPWriter(options *HTTPOptions) (*HTTPWriter, error) {
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+	url, headers, body, _ := options.templates()
+	w := &HTTPWriter{
+		options:    *options,
+		url:        url,
+		headers:    headers,
+		perRecord:  strings.ToLower(options.Mode) == "record",
+		batchSize:  options.BatchSize,
+		maxRetries: 3,
+		backoff:    time.Duration(options.RetryBackoffMs) * time.Millisecond,
+		client:     &http.Client{Timeout: time.Duration(options.TimeoutMs) * time.Millisecond},
+	}
+	if w.batchSize == 0 {
+		w.batchSize = 100
+	}
+	if options.MaxRetries != nil {
+		w.maxRetries = *options.MaxRetries
+	}
+	if w.backoff == 0 {
+		w.backoff = 500 * time.Millisecond
+	}
+	if w.client.Timeout == 0 {
+		w.client.Timeout = 30 * time.Second
+	}
+	if options.RateLimit > 0 {
+		w.limiter = newRateLimiter(options.RateLimit)
+	}
+
+	if body != nil {
+		w.body = func(data interface{}) ([]byte, error) {
+			var buffer bytes.Buffer
+			if err := body.Execute(&buffer, data); err != nil {
+				return nil, err
+			}
+			return buffer.Bytes(), nil
+		}
+	} else if w.perRecord {
+		w.body = json.Marshal
+	} else {
+		w.body = func(data interface{}) ([]byte, error) {
+			return json.Marshal(data.(map[string]interface{})["records"])
+		}
+	}
+	return w, nil
+}
+
+// 创建死信文件
+func (w *HTTPWriter) Open() error {
+	if w.options.DeadLetterFile == "" {
+		return nil
+	}
+	w.deadLetterName = ensureFileExtension(w.options.DeadLetterFile, "jsonl")
+	file, err := os.Create(filepath.Join(config.AppConfig.GenerateDir, w.deadLetterName))
+	if err != nil {
+		return fmt.Errorf("创建死信文件失败: %v", err)
+	}
+	w.deadLetter = file
+	return nil
+}
+
+// 发送一批记录，按并发数同时发送，全部完成后返回
+func (w *HTTPWriter) WriteBatch(records []map[string]interface{}) error {
+	var payloads []httpPayload
+	if w.perRecord {
+		for _, record := range records {
+			payloads = append(payloads, httpPayload{data: record, records: 1})
+		}
+	} else {
+		size := w.batchSize
+		if size == 0 {
+			size = len(records)
+		}
+		for start := 0; start < len(records); start += size {
+			end := start + size
+			if end > len(records) {
+				end = len(records)
+			}
+			chunk := records[start:end]
+			payloads = append(payloads, httpPayload{
+				data:    map[string]interface{}{"records": chunk, "count": len(chunk)},
+				records: len(chunk),
+			})
+		}
+	}
+
+	concurrency := w.options.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	queue := make(chan httpPayload)
+	errs := make(chan error, concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for payload := range queue {
+				if err := w.send(payload); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+
+	// 任一请求失败后停止分发
+	var err error
+dispatch:
+	for _, payload := range payloads {
+		select {
+		case queue <- payload:
+		case err = <-errs:
+			break dispatch
+		}
+	}
+	close(queue)
+	wg.Wait()
+	close(errs)
+	if err != nil {
+		return err
+	}
+	return <-errs
+}
+
+// 发送一个请求，5xx、429 和网络错误按指数退避重试，仍失败时写入死信文件
+func (w *HTTPWriter) send(payload httpPayload) error {
+	method := w.options.method()
+	url, err := w.render(w.url, payload.data)
+	if err != nil {
+		return fmt.Errorf("渲染请求地址失败: %v", err)
+	}
+	body, err := w.body(payload.data)
+	if err != nil {
+		return fmt.Errorf("渲染请求体失败: %v", err)
+	}
+	headers := make(http.Header)
+	headers.Set("Content-Type", "application/json")
+	for name, header := range w.headers {
+		value, err := w.render(header, payload.data)
+		if err != nil {
+			return fmt.Errorf("渲染请求头 %s 失败: %v", name, err)
+		}
+		headers.Set(name, value)
+	}
+	w.authorize(headers, body)
+
+	var status int
+	var failure string
+	backoff := w.backoff
+	for attempt := 0; ; attempt++ {
+		if w.limiter != nil {
+			w.limiter.wait()
+		}
+		var retryAfter time.Duration
+		status, retryAfter, failure = w.do(method, url, headers, body)
+		if failure == "" {
+			return nil
+		}
+		retryable := status == 0 || status == http.StatusTooManyRequests || status >= 500
+		if !retryable || attempt >= w.maxRetries {
+			break
+		}
+		wait := backoff
+		if retryAfter > 0 {
+			wait = retryAfter
+		}
+		w.logger.warn(fmt.Sprintf("请求失败，%v 后第 %d 次重试", wait, attempt+1), map[string]interface{}{
+			"method": method,
+			"url":    url,
+			"status": status,
+			"error":  failure,
+		})
+		time.Sleep(wait)
+		backoff *= 2
+	}
+	w.logger.warn("请求失败，已写入死信文件", map[string]interface{}{
+		"method":  method,
+		"url":     url,
+		"status":  status,
+		"error":   failure,
+		"records": payload.records,
+	})
+	return w.reject(method, url, body, payload.records, status, failure)
+}
+
+// 执行一次请求，返回状态码、服务端要求的重试等待时间和失败原因（成功时为空）
+func (w *HTTPWriter) do(method, url string, headers http.Header, body []byte) (int, time.Duration, string) {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, 0, err.Error()
+	}
+	req.Header = headers.Clone()
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, 0, err.Error()
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp.StatusCode, 0, ""
+	}
+	var retryAfter time.Duration
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		retryAfter = time.Duration(seconds) * time.Second
+	}
+	if len(respBody) == 0 {
+		return resp.StatusCode, retryAfter, http.StatusText(resp.StatusCode)
+	}
+	return resp.StatusCode, retryAfter, string(respBody)
+}
+
+// 添加认证信息
+func (w *HTTPWriter) authorize(headers http.Header, body []byte) {
+	switch strings.ToLower(w.options.Auth) {
+	case "bearer":
+		token := w.options.Token
+		// 自动添加 Bearer 前缀（如果用户未提供）
+		if !strings.HasPrefix(strings.ToLower(token), "bearer ") {
+			token = "Bearer " + token
+		}
+		headers.Set("Authorization", token)
+	case "basic":
+		credentials := base64.StdEncoding.EncodeToString([]byte(w.options.Username + ":" + w.options.Password))
+		headers.Set("Authorization", "Basic "+credentials)
+	case "hmac":
+		mac := hmac.New(sha256.New, []byte(w.options.HMACSecret))
+		mac.Write(body)
+		header := w.options.HMACHeader
+		if header == "" {
+			header = "X-Signature"
+		}
+		headers.Set(header, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+}
+
+func (w *HTTPWriter) render(t *template.Template, data interface{}) (string, error) {
+	var buffer bytes.Buffer
+	if err := t.Execute(&buffer, data); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+// 记录发送失败的请求；未配置死信文件时返回错误终止任务
+func (w *HTTPWriter) reject(method, url string, body []byte, records, status int, failure string) error {
+	if w.deadLetter == nil {
+		if status == 0 {
+			return fmt.Errorf("HTTP请求失败: %s", failure)
+		}
+		return fmt.Errorf("HTTP请求失败 (%d): %s", status, failure)
+	}
+
+	line, err := json.Marshal(map[string]interface{}{
+		"time":    time.Now().Format(time.RFC3339),
+		"method":  method,
+		"url":     url,
+		"status":  status,
+		"error":   failure,
+		"records": records,
+		"body":    string(body),
+	})
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := w.deadLetter.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("写入死信文件失败: %v", err)
+	}
+	w.rejected += int64(records)
+	return nil
+}
+
+// 关闭死信文件并释放连接
+func (w *HTTPWriter) Close() error {
+	w.client.CloseIdleConnections()
+	if w.deadLetter == nil {
+		return nil
+	}
+	file := w.deadLetter
+	w.deadLetter = nil
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("写入死信文件失败: %v", err)
+	}
+	return nil
+}
+
+// 已发送的请求无法撤回，中止时保留死信文件便于排查
+func (w *HTTPWriter) Abort() error {
+	return w.Close()
+}
+
+// 写入死信文件的记录数
+func (w *HTTPWriter) Rejected() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.rejected
+}
+
+func (w *HTTPWriter) setLogger(logger *runLogger) {
+	w.logger = logger
+}
+
+// 生成的死信文件（相对于生成目录）
+func (w *HTTPWriter) Files() []string {
+	if w.deadLetterName == "" {
+		return nil
+	}
+	return []string{w.deadLetterName}
+}
@@ -0,0 +1,338 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/hamba/avro/v2"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// Kafka输出选项（存储在 Task.Configuration 中）
+type KafkaOptions struct {
+	Brokers        []string          `json:"kafkaBrokers"`        // Broker地址，如 localhost:9092
+	Topic          string            `json:"kafkaTopic"`          // 目标主题
+	ClientID       string            `json:"kafkaClientId"`       // 客户端ID，默认 generateTestData
+	KeyField       string            `json:"kafkaKeyField"`       // 作为消息键的字段，为空或字段值为空时不设置键
+	Partitioner    string            `json:"kafkaPartitioner"`    // hash（默认，按键哈希，与Java客户端一致）, round_robin, sticky, manual
+	PartitionField string            `json:"kafkaPartitionField"` // manual 分区时取分区号的字段
+	Headers        map[string]string `json:"kafkaHeaders"`        // 消息头，值可使用模板语法引用记录字段，如 {{.id}}
+	Format         string            `json:"kafkaFormat"`         // 消息体格式：json（默认）, avro（不带容器的二进制，结构同Avro输出）, template（使用行模板）
+	Acks           string            `json:"kafkaAcks"`           // all（默认）, leader, none
+	Compression    string            `json:"kafkaCompression"`    // none（默认）, gzip, snappy, lz4, zstd
+	LingerMs       int               `json:"kafkaLingerMs"`       // 批次等待时间（毫秒），0 表示不等待
+	BatchBytes     int32             `json:"kafkaBatchBytes"`     // 每个批次的最大字节数，默认 1MB
+	RateLimit      float64           `json:"kafkaRateLimit"`      // 每秒最多发送的消息数，0 表示不限制
+}
+
+// 校验配置
+func (o *KafkaOptions) validate() error {
+	if len(o.Brokers) == 0 {
+		return fmt.Errorf("Kafka输出必须指定Broker地址")
+	}
+	if o.Topic == "" {
+		return fmt.Errorf("Kafka输出必须指定主题")
+	}
+	if _, err := o.partitioner(); err != nil {
+		return err
+	}
+	if _, err := o.acks(); err != nil {
+		return err
+	}
+	if _, err := o.compression(); err != nil {
+		return err
+	}
+	switch o.format() {
+	case "json", "avro", "template":
+	default:
+		return fmt.Errorf("不支持的Kafka消息格式: %s", o.Format)
+	}
+	if o.LingerMs < 0 || o.BatchBytes < 0 || o.RateLimit < 0 {
+		return fmt.Errorf("Kafka批次和限速配置不能为负数")
+	}
+	for name, text := range o.Headers {
+		if _, err := parseHeaderTemplate(name, text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 消息体格式
+func (o *KafkaOptions) format() string {
+	if o.Format == "" {
+		return "json"
+	}
+	return strings.ToLower(o.Format)
+}
+
+// 获取分区器
+func (o *KafkaOptions) partitioner() (kgo.Partitioner, error) {
+	switch strings.ToLower(o.Partitioner) {
+	case "", "hash":
+		return kgo.StickyKeyPartitioner(nil), nil
+	case "round_robin":
+		return kgo.RoundRobinPartitioner(), nil
+	case "sticky":
+		return kgo.StickyPartitioner(), nil
+	case "manual":
+		if o.PartitionField == "" {
+			return nil, fmt.Errorf("manual 分区必须指定分区字段")
+		}
+		return kgo.ManualPartitioner(), nil
+	default:
+		return nil, fmt.Errorf("不支持的Kafka分区方式: %s", o.Partitioner)
+	}
+}
+
+// 获取确认级别
+func (o *KafkaOptions) acks() (kgo.Acks, error) {
+	switch strings.ToLower(o.Acks) {
+	case "", "all":
+		return kgo.AllISRAcks(), nil
+	case "leader":
+		return kgo.LeaderAck(), nil
+	case "none":
+		return kgo.NoAck(), nil
+	default:
+		return kgo.Acks{}, fmt.Errorf("不支持的Kafka确认级别: %s", o.Acks)
+	}
+}
+
+// 获取压缩方式
+func (o *KafkaOptions) compression() (kgo.CompressionCodec, error) {
+	switch strings.ToLower(o.Compression) {
+	case "", "none":
+		return kgo.NoCompression(), nil
+	case "gzip":
+		return kgo.GzipCompression(), nil
+	case "snappy":
+		return kgo.SnappyCompression(), nil
+	case "lz4":
+		return kgo.Lz4Compression(), nil
+	case "zstd":
+		return kgo.ZstdCompression(), nil
+	default:
+		return kgo.CompressionCodec{}, fmt.Errorf("不支持的Kafka压缩方式: %s", o.Compression)
+	}
+}
+
+// 解析消息头模板
+func parseHeaderTemplate(name, text string) (*template.Template, error) {
+	t, err := parseValueTemplate(name, text)
+	if err != nil {
+		return nil, fmt.Errorf("解析消息头 %s 失败: %v", name, err)
+	}
+	return t, nil
+}
+
+// KafkaWriter 在整个任务期间复用同一个生产者，消息异步批量发送，Close 时等待全部确认
+type KafkaWriter struct {
+	options   KafkaOptions
+	serialize func(record map[string]interface{}) ([]byte, error)
+	headers   map[string]*template.Template
+	client    *kgo.Client
+	limiter   *rateLimiter
+
+	mu         sync.Mutex
+	err        error           // 第一个发送失败的错误
+	partitions map[int32]int64 // 每个分区已确认的消息数
+}
+
+// 创建Kafka输出；schema 用于 avro 格式，templateOptions 用于 template 格式
+func (s *ExportService) NewKafkaWriter(options *KafkaOptions, schema avro.Schema, templateOptions *TemplateOptions) (*KafkaWriter, error) {
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+	w := &KafkaWriter{
+		options:    *options,
+		headers:    make(map[string]*template.Template, len(options.Headers)),
+		partitions: make(map[int32]int64),
+	}
+	for name, text := range options.Headers {
+		w.headers[name], _ = parseHeaderTemplate(name, text)
+	}
+	if options.RateLimit > 0 {
+		w.limiter = newRateLimiter(options.RateLimit)
+	}
+
+	switch options.format() {
+	case "json":
+		w.serialize = func(record map[string]interface{}) ([]byte, error) {
+			return json.Marshal(record)
+		}
+	case "avro":
+		if schema == nil {
+			return nil, fmt.Errorf("avro 格式必须提供Avro结构")
+		}
+		w.serialize = func(record map[string]interface{}) ([]byte, error) {
+			value, err := avroValue(record, schema)
+			if err != nil {
+				return nil, err
+			}
+			return avro.Marshal(schema, value)
+		}
+	case "template":
+		var rows int64
+		templates, err := templateOptions.parse(func() int64 { return rows + 1 })
+		if err != nil {
+			return nil, err
+		}
+		w.serialize = func(record map[string]interface{}) ([]byte, error) {
+			var buffer bytes.Buffer
+			if err := templates.row.Execute(&buffer, record); err != nil {
+				return nil, fmt.Errorf("渲染第%d条记录失败: %v", rows+1, err)
+			}
+			rows++
+			return buffer.Bytes(), nil
+		}
+	}
+	return w, nil
+}
+
+// 创建生产者并检查Broker是否可用
+func (w *KafkaWriter) Open() error {
+	partitioner, _ := w.options.partitioner()
+	acks, _ := w.options.acks()
+	compression, _ := w.options.compression()
+	clientID := w.options.ClientID
+	if clientID == "" {
+		clientID = "generateTestData"
+	}
+
+	opts := []kgo.Opt{
+		kgo.SeedBrokers(w.options.Brokers...),
+		kgo.ClientID(clientID),
+		kgo.DefaultProduceTopic(w.options.Topic),
+		kgo.RecordPartitioner(partitioner),
+		kgo.RequiredAcks(acks),
+		kgo.ProducerBatchCompression(compression),
+		kgo.ProducerLinger(time.Duration(w.options.LingerMs) * time.Millisecond),
+	}
+	// 幂等写入要求 acks=all
+	if strings.ToLower(w.options.Acks) == "leader" || strings.ToLower(w.options.Acks) == "none" {
+		opts = append(opts, kgo.DisableIdempotentWrite())
+	}
+	if w.options.BatchBytes > 0 {
+		opts = append(opts, kgo.ProducerBatchMaxBytes(w.options.BatchBytes))
+	}
+
+	client, err := kgo.NewClient(opts...)
+	if err != nil {
+		return fmt.Errorf("创建Kafka客户端失败: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx); err != nil {
+		client.Close()
+		return fmt.Errorf("连接Kafka失败: %v", err)
+	}
+	w.client = client
+	return nil
+}
+
+// 发送一批记录；消息异步发送，之前的批次发送失败时返回错误
+func (w *KafkaWriter) WriteBatch(records []map[string]interface{}) error {
+	for _, record := range records {
+		message, err := w.message(record)
+		if err != nil {
+			return err
+		}
+		if w.limiter != nil {
+			w.limiter.wait()
+		}
+		w.client.Produce(context.Background(), message, w.produced)
+	}
+	return w.firstError()
+}
+
+// 将记录转换为Kafka消息
+func (w *KafkaWriter) message(record map[string]interface{}) (*kgo.Record, error) {
+	value, err := w.serialize(record)
+	if err != nil {
+		return nil, fmt.Errorf("序列化消息失败: %v", err)
+	}
+	message := &kgo.Record{Value: value}
+
+	if w.options.KeyField != "" {
+		if key := record[w.options.KeyField]; key != nil {
+			message.Key = []byte(toText(key))
+		}
+	}
+	if strings.ToLower(w.options.Partitioner) == "manual" {
+		partition, err := toInt64(record[w.options.PartitionField])
+		if err != nil {
+			return nil, fmt.Errorf("分区字段 %s 的值无效: %v", w.options.PartitionField, err)
+		}
+		message.Partition = int32(partition)
+	}
+	for name, header := range w.headers {
+		var buffer bytes.Buffer
+		if err := header.Execute(&buffer, record); err != nil {
+			return nil, fmt.Errorf("渲染消息头 %s 失败: %v", name, err)
+		}
+		message.Headers = append(message.Headers, kgo.RecordHeader{Key: name, Value: buffer.Bytes()})
+	}
+	return message, nil
+}
+
+// 发送结果回调
+func (w *KafkaWriter) produced(record *kgo.Record, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err != nil {
+		if w.err == nil {
+			w.err = fmt.Errorf("发送Kafka消息失败: %v", err)
+		}
+		return
+	}
+	w.partitions[record.Partition]++
+}
+
+func (w *KafkaWriter) firstError() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// 等待所有消息确认后关闭生产者
+func (w *KafkaWriter) Close() error {
+	if w.client == nil {
+		return nil
+	}
+	client := w.client
+	w.client = nil
+	defer client.Close()
+	if err := client.Flush(context.Background()); err != nil {
+		return fmt.Errorf("发送Kafka消息失败: %v", err)
+	}
+	return w.firstError()
+}
+
+// 已发送的消息无法撤回，中止时丢弃尚未发送的消息并关闭生产者
+func (w *KafkaWriter) Abort() error {
+	if w.client == nil {
+		return nil
+	}
+	w.client.PurgeTopicsFromClient(w.options.Topic)
+	w.client.Close()
+	w.client = nil
+	return nil
+}
+
+// 每个分区已确认的消息数
+func (w *KafkaWriter) PartitionCounts() map[int32]int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	counts := make(map[int32]int64, len(w.partitions))
+	for partition, count := range w.partitions {
+		counts[partition] = count
+	}
+	return counts
+}
@@ -0,0 +1,265 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"generateTestData/backend/models"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 脱敏任务配置（存储在 Task.Configuration 中）
+type MaskConfig struct {
+	Salt               string `json:"salt"`               // 脱敏盐值，相同盐值下相同输入得到相同输出
+	Filter             string `json:"filter"`             // 源表过滤条件（SQL WHERE 子句，不含 WHERE 关键字）
+	TargetDataSourceID uint   `json:"targetDataSourceId"` // 输出到数据库时的目标数据源
+	TargetTable        string `json:"targetTable"`        // 目标表名，默认与源表相同
+	BatchSize          int    `json:"batchSize"`          // 每批写出的行数，默认1000
+	InsertOptions             // 输出到数据库时的写入选项
+	SQLOptions                // 输出SQL文件时的导出选项
+}
+
+// 可解析的日期格式（用于日期偏移）
+var maskDateLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02",
+	"2006/01/02",
+	"20060102",
+}
+
+// 假数据替换使用的词库
+var (
+	maskFirstNames   = []string{"James", "Mary", "John", "Patricia", "Robert", "Jennifer", "Michael", "Linda", "William", "Elizabeth", "David", "Susan", "Richard", "Jessica", "Joseph", "Sarah"}
+	maskLastNames    = []string{"Smith", "Johnson", "Williams", "Brown", "Jones", "Garcia", "Miller", "Davis", "Wilson", "Anderson", "Taylor", "Thomas", "Moore", "Martin", "Jackson", "White"}
+	maskChineseSurs  = []string{"王", "李", "张", "刘", "陈", "杨", "赵", "黄", "周", "吴", "徐", "孙", "胡", "朱", "高", "林"}
+	maskChineseGiven = []string{"伟", "芳", "娜", "敏", "静", "丽", "强", "磊", "军", "洋", "勇", "艳", "杰", "娟", "涛", "明", "超", "秀英", "华", "平"}
+	maskMailDomains  = []string{"example.com", "example.org", "example.net", "test.com"}
+	maskPhonePrefix  = []string{"133", "135", "136", "137", "138", "139", "150", "151", "152", "157", "158", "159", "182", "186", "187", "188", "189", "198", "199"}
+	maskAreaCodes    = []string{"110101", "310101", "440101", "330106", "510107"}
+)
+
+// MaskingService 按字段规则对数据脱敏。所有规则均为确定性的：
+// 同一盐值下相同的输入值总是得到相同的输出，因此跨表的关联字段脱敏后仍可关联。
+type MaskingService struct {
+	salt []byte
+}
+
+func NewMaskingService(salt string) *MaskingService {
+	return &MaskingService{salt: []byte(salt)}
+}
+
+// 对一条记录按规则脱敏，未配置规则的字段保持原值
+func (m *MaskingService) MaskRecord(record map[string]interface{}, rules map[string]models.FieldRule) (map[string]interface{}, error) {
+	masked := make(map[string]interface{}, len(record))
+	for column, value := range record {
+		rule, exists := rules[column]
+		if !exists {
+			masked[column] = value
+			continue
+		}
+
+		maskedValue, err := m.MaskValue(value, rule, record)
+		if err != nil {
+			return nil, fmt.Errorf("脱敏字段 %s 失败: %v", column, err)
+		}
+		masked[column] = maskedValue
+	}
+	return masked, nil
+}
+
+// 按规则对单个值脱敏，record 为所在的原始记录（日期偏移按其他字段取偏移量时使用）
+func (m *MaskingService) MaskValue(value interface{}, rule models.FieldRule, record map[string]interface{}) (interface{}, error) {
+	if rule.Type == "null" {
+		return nil, nil
+	}
+	if value == nil {
+		return nil, nil
+	}
+
+	switch rule.Type {
+	case "", "keep":
+		return value, nil
+	case "hash":
+		return m.hash(value, rule), nil
+	case "tokenize":
+		return m.tokenize(value)
+	case "faker":
+		return m.fake(value, rule)
+	case "partial":
+		return m.partial(value, rule), nil
+	case "date_shift":
+		return m.shiftDate(value, rule, record)
+	default:
+		return nil, fmt.Errorf("不支持的脱敏规则: %s", rule.Type)
+	}
+}
+
+// 加盐哈希：HMAC-SHA256 十六进制，可通过 length 截断、prefix 添加前缀
+func (m *MaskingService) hash(value interface{}, rule models.FieldRule) string {
+	digest := hex.EncodeToString(m.mac("hash", maskString(value)))
+	if length, ok := rule.Parameters["length"].(float64); ok && int(length) > 0 && int(length) < len(digest) {
+		digest = digest[:int(length)]
+	}
+	if prefix, ok := rule.Parameters["prefix"].(string); ok {
+		digest = prefix + digest
+	}
+	return digest
+}
+
+// 保留格式的令牌化：数字替换为数字、字母替换为同大小写字母，其余字符保持不变。
+// 每个字符的偏移量由盐值和它之前的原始字符决定，因此相同长度和格式下是一一映射，不会产生冲突。
+func (m *MaskingService) tokenize(value interface{}) (interface{}, error) {
+	original := maskString(value)
+	runes := []rune(original)
+	result := make([]rune, len(runes))
+	firstDigit := true
+	for i, r := range runes {
+		offset := int(m.mac("tokenize", string(runes[:i]))[0])
+		switch {
+		case r >= '0' && r <= '9':
+			if firstDigit {
+				// 首位数字：0保持为0，非零保持非零，避免数值变短且保持一一映射
+				if r != '0' {
+					result[i] = '1' + rune((int(r-'1')+offset)%9)
+				} else {
+					result[i] = r
+				}
+			} else {
+				result[i] = '0' + rune((int(r-'0')+offset)%10)
+			}
+			firstDigit = false
+		case r >= 'a' && r <= 'z':
+			result[i] = 'a' + rune((int(r-'a')+offset)%26)
+		case r >= 'A' && r <= 'Z':
+			result[i] = 'A' + rune((int(r-'A')+offset)%26)
+		default:
+			result[i] = r
+		}
+	}
+	token := string(result)
+
+	// 保持数值类型，便于写回数值列；令牌超出类型范围（如19位的大数）时返回字符串
+	switch value.(type) {
+	case int, int8, int16, int32, int64:
+		if n, err := strconv.ParseInt(token, 10, 64); err == nil {
+			return n, nil
+		}
+	case uint, uint8, uint16, uint32, uint64:
+		if n, err := strconv.ParseUint(token, 10, 64); err == nil {
+			return n, nil
+		}
+	}
+	return token, nil
+}
+
+// 假数据替换：kind 可选 name, chinese_name, email, phone, chinese_phone, id_card, uuid
+func (m *MaskingService) fake(value interface{}, rule models.FieldRule) (interface{}, error) {
+	kind, _ := rule.Parameters["kind"].(string)
+	seed := int64(binary.BigEndian.Uint64(m.mac("faker:"+kind, maskString(value))))
+	rng := rand.New(rand.NewSource(seed))
+
+	switch kind {
+	case "name":
+		return maskFirstNames[rng.Intn(len(maskFirstNames))] + " " + maskLastNames[rng.Intn(len(maskLastNames))], nil
+	case "chinese_name":
+		return maskChineseSurs[rng.Intn(len(maskChineseSurs))] + maskChineseGiven[rng.Intn(len(maskChineseGiven))], nil
+	case "email":
+		user := strings.ToLower(maskFirstNames[rng.Intn(len(maskFirstNames))])
+		return fmt.Sprintf("%s%d@%s", user, rng.Intn(100000), maskMailDomains[rng.Intn(len(maskMailDomains))]), nil
+	case "phone":
+		return fmt.Sprintf("%03d-%03d-%04d", rng.Intn(800)+200, rng.Intn(1000), rng.Intn(10000)), nil
+	case "chinese_phone":
+		return fmt.Sprintf("%s%08d", maskPhonePrefix[rng.Intn(len(maskPhonePrefix))], rng.Intn(100000000)), nil
+	case "id_card":
+		birth := time.Date(1960, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, rng.Intn(365*45))
+		return fmt.Sprintf("%s%s%04d", maskAreaCodes[rng.Intn(len(maskAreaCodes))], birth.Format("20060102"), rng.Intn(10000)), nil
+	case "uuid":
+		return fmt.Sprintf("%08x-%04x-%04x-%04x-%012x",
+			rng.Uint32(), rng.Uint32()&0xffff, rng.Uint32()&0xffff, rng.Uint32()&0xffff, rng.Uint64()&0xffffffffffff), nil
+	default:
+		return nil, fmt.Errorf("不支持的假数据类型: %s", kind)
+	}
+}
+
+// 部分遮盖：保留前 keepPrefix 位和后 keepSuffix 位，如 138****1234；负数按0处理
+func (m *MaskingService) partial(value interface{}, rule models.FieldRule) string {
+	keepPrefix, keepSuffix := 3, 4
+	if v, ok := rule.Parameters["keepPrefix"].(float64); ok {
+		keepPrefix = int(math.Max(v, 0))
+	}
+	if v, ok := rule.Parameters["keepSuffix"].(float64); ok {
+		keepSuffix = int(math.Max(v, 0))
+	}
+	maskChar := "*"
+	if v, ok := rule.Parameters["maskChar"].(string); ok && v != "" {
+		maskChar = v
+	}
+
+	runes := []rune(maskString(value))
+	if keepPrefix+keepSuffix >= len(runes) {
+		return strings.Repeat(maskChar, len(runes))
+	}
+	return string(runes[:keepPrefix]) + strings.Repeat(maskChar, len(runes)-keepPrefix-keepSuffix) + string(runes[len(runes)-keepSuffix:])
+}
+
+// 日期偏移：在 ±maxDays 天内偏移，偏移量由 keyColumn 字段（默认为值本身）决定，
+// 使同一实体的所有日期偏移相同天数，保留日期间的间隔
+func (m *MaskingService) shiftDate(value interface{}, rule models.FieldRule, record map[string]interface{}) (interface{}, error) {
+	maxDays := 30
+	if v, ok := rule.Parameters["maxDays"].(float64); ok && v > 0 {
+		maxDays = int(v)
+	}
+
+	key := maskString(value)
+	if keyColumn, ok := rule.Parameters["keyColumn"].(string); ok && keyColumn != "" {
+		key = maskString(record[keyColumn])
+	}
+	span := uint64(maxDays*2 + 1)
+	days := int(binary.BigEndian.Uint64(m.mac("date_shift", key))%span) - maxDays
+
+	switch v := value.(type) {
+	case time.Time:
+		return v.AddDate(0, 0, days), nil
+	case string:
+		for _, layout := range maskDateLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t.AddDate(0, 0, days).Format(layout), nil
+			}
+		}
+		return nil, fmt.Errorf("无法解析日期: %s", v)
+	default:
+		return nil, fmt.Errorf("日期偏移不支持类型 %T", value)
+	}
+}
+
+// 计算带用途前缀的 HMAC，不同规则之间互不影响
+func (m *MaskingService) mac(purpose, value string) []byte {
+	h := hmac.New(sha256.New, m.salt)
+	h.Write([]byte(purpose))
+	h.Write([]byte{0})
+	h.Write([]byte(value))
+	return h.Sum(nil)
+}
+
+// 将值规范化为字符串，保证不同表中类型略有差异的相同值得到相同结果
+func maskString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
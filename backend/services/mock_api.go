@@ -0,0 +1,596 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"generateTestData/backend/models"
+	"math/rand"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 内置Mock接口输出选项（存储在 Task.Configuration 中）
+type MockAPIOptions struct {
+	Name        string  `json:"mockName"`        // 数据集名称，访问路径为 /mock/<名称>
+	IDField     string  `json:"mockIdField"`     // 作为记录ID的字段，默认 id，记录中没有该字段时自动编号
+	Storage     string  `json:"mockStorage"`     // sqlite（默认，保存在平台数据库中）, memory（仅保存在内存中，重启后清空）
+	Append      bool    `json:"mockAppend"`      // 追加到已有数据集，默认替换
+	LatencyMs   int     `json:"mockLatencyMs"`   // 每个请求的固定延迟（毫秒）
+	JitterMs    int     `json:"mockJitterMs"`    // 额外的随机延迟上限（毫秒）
+	ErrorRate   float64 `json:"mockErrorRate"`   // 随机返回错误的比例（0-1）
+	ErrorStatus int     `json:"mockErrorStatus"` // 注入错误时的状态码，默认500
+}
+
+var mockNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// 校验配置
+func (o *MockAPIOptions) validate() error {
+	if !mockNamePattern.MatchString(o.Name) {
+		return fmt.Errorf("Mock数据集名称只能包含字母、数字、下划线和连字符")
+	}
+	switch o.storage() {
+	case "sqlite", "memory":
+	default:
+		return fmt.Errorf("不支持的Mock存储方式: %s", o.Storage)
+	}
+	if o.LatencyMs < 0 || o.JitterMs < 0 {
+		return fmt.Errorf("Mock延迟不能为负数")
+	}
+	if o.ErrorRate < 0 || o.ErrorRate > 1 {
+		return fmt.Errorf("Mock错误比例必须在0到1之间")
+	}
+	if o.ErrorStatus != 0 && (o.ErrorStatus < 400 || o.ErrorStatus > 599) {
+		return fmt.Errorf("Mock错误状态码必须在400到599之间")
+	}
+	return nil
+}
+
+func (o *MockAPIOptions) storage() string {
+	if o.Storage == "" {
+		return "sqlite"
+	}
+	return strings.ToLower(o.Storage)
+}
+
+var (
+	ErrMockNotFound = errors.New("数据不存在")
+	ErrMockConflict = errors.New("记录ID已存在")
+)
+
+// 已加载的数据集，内存和 sqlite 存储都在内存中保留一份用于查询
+type mockCollection struct {
+	mu      sync.RWMutex
+	meta    models.MockCollection
+	ids     []string // 按插入顺序
+	records map[string]map[string]interface{}
+	nextID  int64 // 下一个自动编号
+}
+
+func newMockCollection(meta models.MockCollection) *mockCollection {
+	return &mockCollection{meta: meta, records: make(map[string]map[string]interface{}), nextID: 1}
+}
+
+// 写入记录，ID已存在时覆盖；记录中没有ID字段时自动编号，返回记录ID
+func (c *mockCollection) put(record map[string]interface{}) string {
+	value, ok := record[c.meta.IDField]
+	if !ok || value == nil {
+		value = json.Number(strconv.FormatInt(c.nextID, 10))
+		record[c.meta.IDField] = value
+	}
+	id := mockText(value)
+	if n, err := strconv.ParseInt(id, 10, 64); err == nil && n >= c.nextID {
+		c.nextID = n + 1
+	}
+	if _, exists := c.records[id]; !exists {
+		c.ids = append(c.ids, id)
+	}
+	c.records[id] = record
+	return id
+}
+
+func (c *mockCollection) remove(id string) {
+	delete(c.records, id)
+	for i, existing := range c.ids {
+		if existing == id {
+			c.ids = append(c.ids[:i], c.ids[i+1:]...)
+			break
+		}
+	}
+}
+
+// 已加载的数据集，进程内共享，按名称索引
+var mockRegistry = struct {
+	sync.Mutex
+	collections map[string]*mockCollection
+}{collections: make(map[string]*mockCollection)}
+
+type MockService struct{}
+
+func NewMockService() *MockService {
+	return &MockService{}
+}
+
+// 获取数据集，首次访问时从数据库加载
+func (s *MockService) collection(name string) (*mockCollection, error) {
+	mockRegistry.Lock()
+	defer mockRegistry.Unlock()
+	if c, ok := mockRegistry.collections[name]; ok {
+		return c, nil
+	}
+
+	var meta models.MockCollection
+	if err := models.DB.Where("name = ?", name).First(&meta).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMockNotFound
+		}
+		return nil, err
+	}
+	c := newMockCollection(meta)
+	if meta.Storage == "sqlite" {
+		var rows []models.MockRecord
+		if err := models.DB.Where("collection = ?", name).Order("id").Find(&rows).Error; err != nil {
+			return nil, fmt.Errorf("加载Mock数据失败: %v", err)
+		}
+		for _, row := range rows {
+			record, err := decodeMockRecord([]byte(row.Data))
+			if err != nil {
+				return nil, fmt.Errorf("加载Mock数据失败: %v", err)
+			}
+			c.put(record)
+		}
+	}
+	mockRegistry.collections[name] = c
+	return c, nil
+}
+
+// 已发布的数据集
+func (s *MockService) Collections() ([]models.MockCollection, error) {
+	var collections []models.MockCollection
+	err := models.DB.Order("name").Find(&collections).Error
+	return collections, err
+}
+
+// 删除数据集
+func (s *MockService) DeleteCollection(name string) error {
+	mockRegistry.Lock()
+	defer mockRegistry.Unlock()
+	result := models.DB.Where("name = ?", name).Delete(&models.MockCollection{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if err := models.DB.Where("collection = ?", name).Delete(&models.MockRecord{}).Error; err != nil {
+		return err
+	}
+	if _, ok := mockRegistry.collections[name]; !ok && result.RowsAffected == 0 {
+		return ErrMockNotFound
+	}
+	delete(mockRegistry.collections, name)
+	return nil
+}
+
+// 按数据集配置模拟延迟和错误，返回需要注入的错误状态码，不注入时为0
+func (s *MockService) Simulate(name string) (int, error) {
+	c, err := s.collection(name)
+	if err != nil {
+		return 0, err
+	}
+	c.mu.RLock()
+	meta := c.meta
+	c.mu.RUnlock()
+
+	delay := time.Duration(meta.LatencyMs) * time.Millisecond
+	if meta.JitterMs > 0 {
+		delay += time.Duration(rand.Intn(meta.JitterMs+1)) * time.Millisecond
+	}
+	time.Sleep(delay)
+	if meta.ErrorRate > 0 && rand.Float64() < meta.ErrorRate {
+		if meta.ErrorStatus == 0 {
+			return 500, nil
+		}
+		return meta.ErrorStatus, nil
+	}
+	return 0, nil
+}
+
+// 查询记录，返回当前页和过滤后的总数。
+// 查询参数：字段=值（可重复，匹配任一值）、字段_ne、字段_gt、字段_gte、字段_lt、字段_lte、字段_like，
+// 嵌套字段用 . 分隔；_sort、_order 指定排序（逗号分隔多个字段）；_page、_limit 指定分页，不指定时返回全部。
+func (s *MockService) List(name string, query url.Values) ([]map[string]interface{}, int, error) {
+	c, err := s.collection(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	c.mu.RLock()
+	records := make([]map[string]interface{}, 0, len(c.ids))
+	for _, id := range c.ids {
+		if record := c.records[id]; mockMatches(record, query) {
+			records = append(records, record)
+		}
+	}
+	c.mu.RUnlock()
+
+	if sortFields := query.Get("_sort"); sortFields != "" {
+		fields := strings.Split(sortFields, ",")
+		orders := strings.Split(query.Get("_order"), ",")
+		sort.SliceStable(records, func(i, j int) bool {
+			for k, field := range fields {
+				cmp := mockCompare(mockField(records[i], field), mockField(records[j], field))
+				if cmp == 0 {
+					continue
+				}
+				if k < len(orders) && strings.EqualFold(orders[k], "desc") {
+					return cmp > 0
+				}
+				return cmp < 0
+			}
+			return false
+		})
+	}
+
+	total := len(records)
+	if query.Has("_page") || query.Has("_limit") {
+		page, _ := strconv.Atoi(query.Get("_page"))
+		limit, _ := strconv.Atoi(query.Get("_limit"))
+		if page < 1 {
+			page = 1
+		}
+		if limit < 1 {
+			limit = 20
+		}
+		start := (page - 1) * limit
+		if start > total {
+			start = total
+		}
+		end := start + limit
+		if end > total {
+			end = total
+		}
+		records = records[start:end]
+	}
+	return records, total, nil
+}
+
+// 按ID获取记录
+func (s *MockService) Get(name, id string) (map[string]interface{}, error) {
+	c, err := s.collection(name)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	record, ok := c.records[id]
+	if !ok {
+		return nil, ErrMockNotFound
+	}
+	return record, nil
+}
+
+// 新增记录，没有ID时自动编号
+func (s *MockService) Create(name string, data []byte) (map[string]interface{}, error) {
+	record, err := decodeMockRecord(data)
+	if err != nil {
+		return nil, err
+	}
+	c, err := s.collection(name)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if value, ok := record[c.meta.IDField]; ok && value != nil {
+		if _, exists := c.records[mockText(value)]; exists {
+			return nil, ErrMockConflict
+		}
+	}
+	id := c.put(record)
+	if err := c.save(id, record); err != nil {
+		c.remove(id)
+		return nil, err
+	}
+	return record, nil
+}
+
+// 更新记录：merge 为 false 时整体替换（PUT），为 true 时合并字段（PATCH）；记录ID不可修改
+func (s *MockService) Update(name, id string, data []byte, merge bool) (map[string]interface{}, error) {
+	changes, err := decodeMockRecord(data)
+	if err != nil {
+		return nil, err
+	}
+	c, err := s.collection(name)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	existing, ok := c.records[id]
+	if !ok {
+		return nil, ErrMockNotFound
+	}
+
+	record := changes
+	if merge {
+		record = make(map[string]interface{}, len(existing)+len(changes))
+		for key, value := range existing {
+			record[key] = value
+		}
+		for key, value := range changes {
+			record[key] = value
+		}
+	}
+	record[c.meta.IDField] = existing[c.meta.IDField]
+	if err := c.save(id, record); err != nil {
+		return nil, err
+	}
+	c.records[id] = record
+	return record, nil
+}
+
+// 删除记录
+func (s *MockService) Delete(name, id string) error {
+	c, err := s.collection(name)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.records[id]; !ok {
+		return ErrMockNotFound
+	}
+	if c.meta.Storage == "sqlite" {
+		if err := models.DB.Where("collection = ? AND record_id = ?", c.meta.Name, id).Delete(&models.MockRecord{}).Error; err != nil {
+			return err
+		}
+	}
+	c.remove(id)
+	return nil
+}
+
+// sqlite 存储时写入数据库
+func (c *mockCollection) save(id string, record map[string]interface{}) error {
+	if c.meta.Storage != "sqlite" {
+		return nil
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	row := models.MockRecord{Collection: c.meta.Name, RecordID: id}
+	return models.DB.Where(row).Assign(models.MockRecord{Data: string(data)}).FirstOrCreate(&row).Error
+}
+
+// MockAPIWriter 将生成的数据发布为内置Mock接口的数据集，Close 时整体替换（或追加到）已有数据集
+type MockAPIWriter struct {
+	options MockAPIOptions
+	taskID  uint
+	staging *mockCollection
+}
+
+// 创建Mock接口输出
+func (s *ExportService) NewMockAPIWriter(options *MockAPIOptions, taskID uint) (*MockAPIWriter, error) {
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+	return &MockAPIWriter{options: *options, taskID: taskID}, nil
+}
+
+// 创建待发布的数据集，追加时先复制已有记录
+func (w *MockAPIWriter) Open() error {
+	idField := w.options.IDField
+	if idField == "" {
+		idField = "id"
+	}
+	w.staging = newMockCollection(models.MockCollection{
+		Name:        w.options.Name,
+		TaskID:      w.taskID,
+		IDField:     idField,
+		Storage:     w.options.storage(),
+		LatencyMs:   w.options.LatencyMs,
+		JitterMs:    w.options.JitterMs,
+		ErrorRate:   w.options.ErrorRate,
+		ErrorStatus: w.options.ErrorStatus,
+	})
+	if !w.options.Append {
+		return nil
+	}
+
+	existing, err := NewMockService().collection(w.options.Name)
+	if errors.Is(err, ErrMockNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	existing.mu.RLock()
+	defer existing.mu.RUnlock()
+	for _, id := range existing.ids {
+		w.staging.put(existing.records[id])
+	}
+	return nil
+}
+
+// 加入一批记录，记录按JSON规范化，与接口读写的数据一致
+func (w *MockAPIWriter) WriteBatch(records []map[string]interface{}) error {
+	for _, record := range records {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("序列化记录失败: %v", err)
+		}
+		normalized, err := decodeMockRecord(data)
+		if err != nil {
+			return err
+		}
+		w.staging.put(normalized)
+	}
+	return nil
+}
+
+// 保存数据集并替换已加载的版本
+func (w *MockAPIWriter) Close() error {
+	if w.staging == nil {
+		return nil
+	}
+	staging := w.staging
+	w.staging = nil
+	meta := &staging.meta
+	meta.Count = int64(len(staging.ids))
+
+	mockRegistry.Lock()
+	defer mockRegistry.Unlock()
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		var existing models.MockCollection
+		if err := tx.Where("name = ?", meta.Name).First(&existing).Error; err == nil {
+			meta.ID = existing.ID
+			meta.CreatedAt = existing.CreatedAt
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err := tx.Save(meta).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("collection = ?", meta.Name).Delete(&models.MockRecord{}).Error; err != nil {
+			return err
+		}
+		if meta.Storage != "sqlite" {
+			return nil
+		}
+
+		rows := make([]models.MockRecord, 0, len(staging.ids))
+		for _, id := range staging.ids {
+			data, err := json.Marshal(staging.records[id])
+			if err != nil {
+				return err
+			}
+			rows = append(rows, models.MockRecord{Collection: meta.Name, RecordID: id, Data: string(data)})
+		}
+		return tx.CreateInBatches(rows, 500).Error
+	})
+	if err != nil {
+		return fmt.Errorf("保存Mock数据集失败: %v", err)
+	}
+	mockRegistry.collections[meta.Name] = staging
+	return nil
+}
+
+// 放弃发布，已有数据集保持不变
+func (w *MockAPIWriter) Abort() error {
+	w.staging = nil
+	return nil
+}
+
+// 解析JSON对象，数字保留原始文本，避免大整数ID失真
+func decodeMockRecord(data []byte) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var record map[string]interface{}
+	if err := decoder.Decode(&record); err != nil {
+		return nil, fmt.Errorf("解析记录失败: %v", err)
+	}
+	if record == nil {
+		return nil, fmt.Errorf("记录必须是JSON对象")
+	}
+	return record, nil
+}
+
+// 按 . 分隔的路径取字段值
+func mockField(record map[string]interface{}, path string) interface{} {
+	var value interface{} = record
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+	return value
+}
+
+// 字段值的文本形式，空值为空字符串
+func mockText(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return toText(value)
+}
+
+// 比较两个值：都是数字时按数值比较，否则按文本比较；空值最小
+func mockCompare(a, b interface{}) int {
+	if a == nil || b == nil {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return -1
+		default:
+			return 1
+		}
+	}
+	x, errX := strconv.ParseFloat(mockText(a), 64)
+	y, errY := strconv.ParseFloat(mockText(b), 64)
+	if errX == nil && errY == nil {
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(mockText(a), mockText(b))
+}
+
+// 判断记录是否满足全部过滤条件，以 _ 开头的参数为查询控制参数
+var mockOperators = []string{"_ne", "_gte", "_gt", "_lte", "_lt", "_like"}
+
+func mockMatches(record map[string]interface{}, query url.Values) bool {
+	for key, values := range query {
+		if strings.HasPrefix(key, "_") {
+			continue
+		}
+		field, operator := key, ""
+		for _, suffix := range mockOperators {
+			if strings.HasSuffix(key, suffix) {
+				field, operator = strings.TrimSuffix(key, suffix), suffix
+				break
+			}
+		}
+		value := mockField(record, field)
+
+		matched := false
+		for _, expected := range values {
+			var ok bool
+			switch operator {
+			case "":
+				ok = value != nil && mockText(value) == expected
+			case "_ne":
+				ok = mockText(value) != expected
+			case "_gt":
+				ok = value != nil && mockCompare(value, expected) > 0
+			case "_gte":
+				ok = value != nil && mockCompare(value, expected) >= 0
+			case "_lt":
+				ok = value != nil && mockCompare(value, expected) < 0
+			case "_lte":
+				ok = value != nil && mockCompare(value, expected) <= 0
+			case "_like":
+				ok = strings.Contains(strings.ToLower(mockText(value)), strings.ToLower(expected))
+			}
+			if ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,264 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"generateTestData/backend/config"
+	"math"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress"
+	"github.com/parquet-go/parquet-go/compress/gzip"
+	"github.com/parquet-go/parquet-go/compress/snappy"
+	"github.com/parquet-go/parquet-go/compress/uncompressed"
+	"github.com/parquet-go/parquet-go/compress/zstd"
+)
+
+// Parquet导出选项（存储在 Task.Configuration 中）
+type ParquetOptions struct {
+	Compression  string `json:"parquetCompression"`  // snappy（默认）, zstd, gzip, none
+	RowGroupSize int64  `json:"parquetRowGroupSize"` // 每个行组的最大行数，默认100000
+}
+
+// 获取压缩编码
+func (o *ParquetOptions) codec() (compress.Codec, error) {
+	compression := ""
+	if o != nil {
+		compression = strings.ToLower(o.Compression)
+	}
+	switch compression {
+	case "", "snappy":
+		return &snappy.Codec{}, nil
+	case "zstd":
+		return &zstd.Codec{}, nil
+	case "gzip":
+		return &gzip.Codec{}, nil
+	case "none", "uncompressed":
+		return &uncompressed.Codec{}, nil
+	default:
+		return nil, fmt.Errorf("不支持的Parquet压缩方式: %s", o.Compression)
+	}
+}
+
+// 构造 parquet 节点
+func parquetNode(f *schemaField) parquet.Node {
+	var node parquet.Node
+	switch f.kind {
+	case fieldInt32:
+		node = parquet.Int(32)
+	case fieldInt64:
+		node = parquet.Int(64)
+	case fieldDouble:
+		node = parquet.Leaf(parquet.DoubleType)
+	case fieldBoolean:
+		node = parquet.Leaf(parquet.BooleanType)
+	case fieldBytes:
+		node = parquet.Leaf(parquet.ByteArrayType)
+	case fieldJSON:
+		node = parquet.JSON()
+	case fieldDate:
+		node = parquet.Date()
+	case fieldTimestamp:
+		node = parquet.Timestamp(parquet.Microsecond)
+	case fieldGroup:
+		group := parquet.Group{}
+		for name, child := range f.fields {
+			group[name] = parquetNode(child)
+		}
+		node = group
+	case fieldList:
+		node = parquet.List(parquetNode(f.elem))
+	default:
+		node = parquet.String()
+	}
+	if f.optional {
+		return parquet.Optional(node)
+	}
+	return node
+}
+
+// ParquetWriter 在整个任务期间保持文件打开，按批追加数据，
+// 行组写满后自动刷新到磁盘，内存占用与行组大小相关而与总行数无关。
+type ParquetWriter struct {
+	name    string // 相对于生成目录的文件名
+	path    string
+	options []parquet.WriterOption
+	file    *tempFile
+	writer  *parquet.GenericWriter[map[string]any]
+	schema  *RecordSchema
+}
+
+// 创建Parquet文件
+func (s *ExportService) NewParquetWriter(fileName string, schema *RecordSchema, options *ParquetOptions) (*ParquetWriter, error) {
+	codec, err := options.codec()
+	if err != nil {
+		return nil, err
+	}
+	rowGroupSize := int64(100000)
+	if options != nil && options.RowGroupSize > 0 {
+		rowGroupSize = options.RowGroupSize
+	}
+
+	if fileName == "" {
+		return nil, fmt.Errorf("必须指定输出路径")
+	}
+
+	// 确保文件名有正确的后缀
+	fileName = ensureFileExtension(fileName, "parquet")
+
+	return &ParquetWriter{
+		name: fileName,
+		path: filepath.Join(config.AppConfig.GenerateDir, fileName),
+		options: []parquet.WriterOption{
+			parquet.NewSchema("record", parquetNode(schema.root)),
+			parquet.Compression(codec),
+			parquet.MaxRowsPerRowGroup(rowGroupSize),
+		},
+		schema: schema,
+	}, nil
+}
+
+// 创建文件
+func (w *ParquetWriter) Open() error {
+	file, err := createTempFile(w.path)
+	if err != nil {
+		return err
+	}
+	w.file = file
+	w.writer = parquet.NewGenericWriter[map[string]any](file, w.options...)
+	return nil
+}
+
+// 写入一批记录，值按列类型转换
+func (w *ParquetWriter) WriteBatch(records []map[string]interface{}) error {
+	rows := make([]map[string]any, len(records))
+	for i, record := range records {
+		row, err := convertParquetGroup(record, w.schema.root)
+		if err != nil {
+			return err
+		}
+		rows[i] = row
+	}
+	if _, err := w.writer.Write(rows); err != nil {
+		return fmt.Errorf("写入Parquet失败: %v", err)
+	}
+	return nil
+}
+
+// 写入文件尾并保存文件，可重复调用
+func (w *ParquetWriter) Close() error {
+	if w.file == nil {
+		return nil
+	}
+	file := w.file
+	w.file = nil
+
+	if err := w.writer.Close(); err != nil {
+		file.discard()
+		return fmt.Errorf("写入Parquet文件尾失败: %v", err)
+	}
+	return file.commit()
+}
+
+// 放弃输出，删除临时文件
+func (w *ParquetWriter) Abort() error {
+	if w.file != nil {
+		w.file.discard()
+		w.file = nil
+	}
+	return nil
+}
+
+// 生成的文件（相对于生成目录）
+func (w *ParquetWriter) Files() []string {
+	return []string{w.name}
+}
+
+// 按结构转换一条记录，结构中未定义的字段被忽略
+func convertParquetGroup(record map[string]interface{}, field *schemaField) (map[string]any, error) {
+	row := make(map[string]any, len(field.fields))
+	for name, child := range field.fields {
+		value, err := convertParquetValue(record[name], child)
+		if err != nil {
+			return nil, fmt.Errorf("字段 %s: %v", name, err)
+		}
+		row[name] = value
+	}
+	return row, nil
+}
+
+// 将值转换为字段类型对应的Go类型
+func convertParquetValue(value interface{}, field *schemaField) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	switch field.kind {
+	case fieldGroup:
+		record, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("期望对象，实际为 %T", value)
+		}
+		return convertParquetGroup(record, field)
+	case fieldList:
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("期望数组，实际为 %T", value)
+		}
+		list := make([]any, len(items))
+		for i, item := range items {
+			converted, err := convertParquetValue(item, field.elem)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = converted
+		}
+		return list, nil
+	case fieldInt32:
+		n, err := toInt64(value)
+		if err != nil {
+			return nil, err
+		}
+		if n < math.MinInt32 || n > math.MaxInt32 {
+			return nil, fmt.Errorf("整数 %d 超出INT32范围", n)
+		}
+		return int32(n), nil
+	case fieldInt64:
+		return toInt64(value)
+	case fieldDouble:
+		return toFloat64(value)
+	case fieldBoolean:
+		return toBool(value)
+	case fieldDate:
+		t, err := toTime(value)
+		if err != nil {
+			return nil, err
+		}
+		// DATE 存储为自1970-01-01起的天数
+		days := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Unix() / 86400
+		return int32(days), nil
+	case fieldTimestamp:
+		return toTime(value)
+	case fieldBytes:
+		switch v := value.(type) {
+		case []byte:
+			return v, nil
+		default:
+			return []byte(toText(v)), nil
+		}
+	case fieldJSON:
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		return string(data), nil
+	default:
+		return toText(value), nil
+	}
+}
@@ -0,0 +1,250 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"generateTestData/backend/config"
+	"io"
+	"math"
+	"path/filepath"
+	"strconv"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Protobuf导出选项（存储在 Task.Configuration 中）
+type ProtobufOptions struct {
+	ProtoFile   string `json:"protoFile"`    // 上传目录中的 .proto 文件名
+	MessageType string `json:"protoMessage"` // 消息类型，可为全名（如 demo.User）或短名，文件中只有一个消息时可省略
+}
+
+// 编译上传的 .proto 文件并查找消息类型，import 按上传目录解析
+func (s *ExportService) LoadProtoMessage(options *ProtobufOptions) (protoreflect.MessageDescriptor, error) {
+	if options == nil || options.ProtoFile == "" {
+		return nil, fmt.Errorf("必须指定 .proto 文件")
+	}
+
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			ImportPaths: []string{config.AppConfig.UploadDir},
+		}),
+	}
+	files, err := compiler.Compile(context.Background(), filepath.Base(options.ProtoFile))
+	if err != nil {
+		return nil, fmt.Errorf("编译 .proto 文件失败: %v", err)
+	}
+
+	messages := files[0].Messages()
+	if options.MessageType == "" {
+		if messages.Len() != 1 {
+			return nil, fmt.Errorf("%s 中有 %d 个消息类型，必须指定消息类型", options.ProtoFile, messages.Len())
+		}
+		return messages.Get(0), nil
+	}
+	if descriptor, err := files.AsResolver().FindDescriptorByName(protoreflect.FullName(options.MessageType)); err == nil {
+		if message, ok := descriptor.(protoreflect.MessageDescriptor); ok {
+			return message, nil
+		}
+	}
+	if message := messages.ByName(protoreflect.Name(options.MessageType)); message != nil {
+		return message, nil
+	}
+	return nil, fmt.Errorf("%s 中不存在消息类型 %s", options.ProtoFile, options.MessageType)
+}
+
+// 创建以长度前缀（varint）分隔的Protobuf消息文件
+func (s *ExportService) NewProtobufWriter(fileName string, descriptor protoreflect.MessageDescriptor) (*FileWriter, error) {
+	return newFileWriter(fileName, &protobufEncoder{descriptor: descriptor}, nil)
+}
+
+// 长度前缀分隔的Protobuf编码器
+type protobufEncoder struct {
+	descriptor protoreflect.MessageDescriptor
+}
+
+func (e *protobufEncoder) extension() string { return "pb" }
+
+func (e *protobufEncoder) begin(w io.Writer, part int) error { return nil }
+
+func (e *protobufEncoder) encode(w io.Writer, records []map[string]interface{}) error {
+	for _, record := range records {
+		message := dynamicpb.NewMessage(e.descriptor)
+		if err := setProtoFields(message, record); err != nil {
+			return err
+		}
+		if _, err := protodelim.MarshalTo(w, message); err != nil {
+			return fmt.Errorf("写入Protobuf失败: %v", err)
+		}
+	}
+	return nil
+}
+
+func (e *protobufEncoder) end(w io.Writer) error { return nil }
+
+// 按字段名（或JSON名）把记录写入消息，记录中的字段必须在消息中存在
+func setProtoFields(message protoreflect.Message, record map[string]interface{}) error {
+	fields := message.Descriptor().Fields()
+	for name, value := range record {
+		field := fields.ByName(protoreflect.Name(name))
+		if field == nil {
+			field = fields.ByJSONName(name)
+		}
+		if field == nil {
+			return fmt.Errorf("消息 %s 中不存在字段 %s", message.Descriptor().FullName(), name)
+		}
+		if value == nil {
+			continue
+		}
+		if err := setProtoField(message, field, value); err != nil {
+			return fmt.Errorf("字段 %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// 设置单个字段，支持 repeated 和 map 字段
+func setProtoField(message protoreflect.Message, field protoreflect.FieldDescriptor, value interface{}) error {
+	switch {
+	case field.IsList():
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("期望数组，实际为 %T", value)
+		}
+		list := message.Mutable(field).List()
+		for _, item := range items {
+			if item == nil {
+				continue
+			}
+			var element protoreflect.Value
+			var err error
+			if field.Message() != nil {
+				element = list.NewElement()
+				err = setProtoMessage(element.Message(), item)
+			} else {
+				element, err = protoScalar(field, item)
+			}
+			if err != nil {
+				return err
+			}
+			list.Append(element)
+		}
+	case field.IsMap():
+		entries, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("期望对象，实际为 %T", value)
+		}
+		entryMap := message.Mutable(field).Map()
+		for key, entry := range entries {
+			mapKey, err := protoScalar(field.MapKey(), key)
+			if err != nil {
+				return err
+			}
+			if entry == nil {
+				continue
+			}
+			var mapValue protoreflect.Value
+			if field.MapValue().Message() != nil {
+				mapValue = entryMap.NewValue()
+				err = setProtoMessage(mapValue.Message(), entry)
+			} else {
+				mapValue, err = protoScalar(field.MapValue(), entry)
+			}
+			if err != nil {
+				return err
+			}
+			entryMap.Set(mapKey.MapKey(), mapValue)
+		}
+	case field.Message() != nil:
+		return setProtoMessage(message.Mutable(field).Message(), value)
+	default:
+		scalar, err := protoScalar(field, value)
+		if err != nil {
+			return err
+		}
+		message.Set(field, scalar)
+	}
+	return nil
+}
+
+// 填充嵌套消息，google.protobuf.Timestamp 可直接使用时间值
+func setProtoMessage(message protoreflect.Message, value interface{}) error {
+	if message.Descriptor().FullName() == "google.protobuf.Timestamp" {
+		if _, isRecord := value.(map[string]interface{}); !isRecord {
+			t, err := toTime(value)
+			if err != nil {
+				return err
+			}
+			fields := message.Descriptor().Fields()
+			message.Set(fields.ByName("seconds"), protoreflect.ValueOfInt64(t.Unix()))
+			message.Set(fields.ByName("nanos"), protoreflect.ValueOfInt32(int32(t.Nanosecond())))
+			return nil
+		}
+	}
+
+	record, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("期望对象，实际为 %T", value)
+	}
+	return setProtoFields(message, record)
+}
+
+// 转换标量值
+func protoScalar(field protoreflect.FieldDescriptor, value interface{}) (protoreflect.Value, error) {
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		b, err := toBool(value)
+		return protoreflect.ValueOfBool(b), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		n, err := toInt64(value)
+		if err == nil && (n < math.MinInt32 || n > math.MaxInt32) {
+			err = fmt.Errorf("整数 %d 超出int32范围", n)
+		}
+		return protoreflect.ValueOfInt32(int32(n)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		n, err := toInt64(value)
+		return protoreflect.ValueOfInt64(n), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		n, err := toInt64(value)
+		if err == nil && (n < 0 || n > math.MaxUint32) {
+			err = fmt.Errorf("整数 %d 超出uint32范围", n)
+		}
+		return protoreflect.ValueOfUint32(uint32(n)), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		n, err := toInt64(value)
+		if err == nil && n < 0 {
+			err = fmt.Errorf("整数 %d 不能为负数", n)
+		}
+		return protoreflect.ValueOfUint64(uint64(n)), err
+	case protoreflect.FloatKind:
+		f, err := toFloat64(value)
+		return protoreflect.ValueOfFloat32(float32(f)), err
+	case protoreflect.DoubleKind:
+		f, err := toFloat64(value)
+		return protoreflect.ValueOfFloat64(f), err
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(toText(value)), nil
+	case protoreflect.BytesKind:
+		if data, ok := value.([]byte); ok {
+			return protoreflect.ValueOfBytes(data), nil
+		}
+		return protoreflect.ValueOfBytes([]byte(toText(value))), nil
+	case protoreflect.EnumKind:
+		// 枚举可使用名称或编号
+		values := field.Enum().Values()
+		name := toText(value)
+		if enumValue := values.ByName(protoreflect.Name(name)); enumValue != nil {
+			return protoreflect.ValueOfEnum(enumValue.Number()), nil
+		}
+		if n, err := strconv.ParseInt(name, 10, 32); err == nil {
+			if enumValue := values.ByNumber(protoreflect.EnumNumber(n)); enumValue != nil {
+				return protoreflect.ValueOfEnum(enumValue.Number()), nil
+			}
+		}
+		return protoreflect.Value{}, fmt.Errorf("枚举 %s 中不存在值 %s", field.Enum().FullName(), name)
+	default:
+		return protoreflect.Value{}, fmt.Errorf("不支持的字段类型: %s", field.Kind())
+	}
+}
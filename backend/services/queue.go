@@ -0,0 +1,300 @@
+package services
+
+import (
+	"errors"
+	"generateTestData/backend/config"
+	"generateTestData/backend/models"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 执行队列：排队中的执行记录（status=queued）保存在 task_runs 表中，服务重启后继续执行。
+// 按优先级和排队顺序分派，同时执行的任务数和同一数据源上同时执行的任务数受配置限制。
+var taskQueue = struct {
+	sync.Mutex
+	started     bool
+	running     int                    // 正在执行的任务数
+	dataSources map[uint]int           // 各数据源上正在执行的任务数
+	waiters     map[uint]chan struct{} // 等待执行结束的执行记录
+	wake        chan struct{}
+}{
+	dataSources: make(map[uint]int),
+	waiters:     make(map[uint]chan struct{}),
+	wake:        make(chan struct{}, 1),
+}
+
+// 服务启动时恢复执行队列：上次退出时正在执行的记录标记为失败，排队中的记录继续执行
+func StartTaskQueue() error {
+	message := "服务重启，执行中断"
+	now := time.Now()
+	err := models.DB.Model(&models.TaskRun{}).Where("status = ?", models.TaskStatusRunning).
+		Updates(map[string]interface{}{"status": models.TaskStatusFailed, "error_msg": message, "finished_at": &now}).Error
+	if err != nil {
+		return err
+	}
+	err = models.DB.Model(&models.Task{}).Where("status = ?", models.TaskStatusRunning).
+		Updates(map[string]interface{}{"status": models.TaskStatusFailed, "error_msg": message}).Error
+	if err != nil {
+		return err
+	}
+	wakeQueue()
+	return nil
+}
+
+// 获取排队中和执行中的记录，按执行顺序排列
+func (s *TaskService) GetQueue() ([]models.TaskRun, error) {
+	var runs []models.TaskRun
+	err := models.DB.Omit("snapshot").
+		Where("status IN ?", []models.TaskStatus{models.TaskStatusRunning, models.TaskStatusQueued}).
+		Order("CASE WHEN status = 'running' THEN 0 ELSE 1 END, priority DESC, id").
+		Find(&runs).Error
+	return runs, err
+}
+
+// 将任务加入执行队列。任务状态在同一事务中从非排队、非执行状态改为排队中，
+// 并发请求中只有一个能成功，同一任务不会同时执行两次。
+func (s *TaskService) enqueue(task *models.Task, trigger models.RunTrigger, seed int64, rerunOf *uint) (*models.TaskRun, error) {
+	run := &models.TaskRun{
+		TaskID:   task.ID,
+		Trigger:  trigger,
+		Status:   models.TaskStatusQueued,
+		Priority: task.Priority,
+		RerunOf:  rerunOf,
+		Seed:     seed,
+		QueuedAt: time.Now(),
+	}
+	if err := run.SetSnapshot(task); err != nil {
+		return nil, err
+	}
+
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Task{}).
+			Where("id = ? AND status NOT IN ?", task.ID, []models.TaskStatus{models.TaskStatusQueued, models.TaskStatusRunning}).
+			Updates(map[string]interface{}{"status": models.TaskStatusQueued, "progress": 0, "error_msg": ""})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTaskRunning
+		}
+		return tx.Create(run).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	publishTaskEvent(TaskEvent{Type: TaskEventStatus, TaskID: task.ID, RunID: run.ID, Status: models.TaskStatusQueued})
+	wakeQueue()
+	return run, nil
+}
+
+// 等待执行结束，返回执行的错误
+func waitRun(runID uint) error {
+	done := make(chan struct{})
+	taskQueue.Lock()
+	taskQueue.waiters[runID] = done
+	taskQueue.Unlock()
+
+	// 先注册再检查，避免错过在注册前结束的执行
+	var run models.TaskRun
+	if err := models.DB.First(&run, runID).Error; err != nil {
+		taskQueue.Lock()
+		delete(taskQueue.waiters, runID)
+		taskQueue.Unlock()
+		return err
+	}
+	if run.FinishedAt == nil {
+		<-done
+		if err := models.DB.First(&run, runID).Error; err != nil {
+			return err
+		}
+	} else {
+		taskQueue.Lock()
+		delete(taskQueue.waiters, runID)
+		taskQueue.Unlock()
+	}
+
+	if run.Status == models.TaskStatusFailed {
+		return errors.New(run.ErrorMsg)
+	}
+	return nil
+}
+
+// 通知分派协程检查队列，首次调用时启动分派协程
+func wakeQueue() {
+	taskQueue.Lock()
+	if !taskQueue.started {
+		taskQueue.started = true
+		go func() {
+			for range taskQueue.wake {
+				dispatchQueue()
+			}
+		}()
+	}
+	taskQueue.Unlock()
+
+	select {
+	case taskQueue.wake <- struct{}{}:
+	default:
+	}
+}
+
+// 同时执行的任务数和每个数据源默认的并发上限
+func queueLimits() (workers, perDataSource int) {
+	workers, perDataSource = 4, 2
+	if config.AppConfig != nil {
+		if config.AppConfig.WorkerPoolSize > 0 {
+			workers = config.AppConfig.WorkerPoolSize
+		}
+		if config.AppConfig.DataSourceConcurrency > 0 {
+			perDataSource = config.AppConfig.DataSourceConcurrency
+		}
+	}
+	return workers, perDataSource
+}
+
+// 按优先级分派排队中的执行，数据源已达上限的执行留在队列中，不阻塞其他数据源的执行。
+// 只有分派协程调用，读取队列和认领记录时不持有锁，锁只保护计数
+func dispatchQueue() {
+	workers, perDataSource := queueLimits()
+	taskQueue.Lock()
+	full := taskQueue.running >= workers
+	taskQueue.Unlock()
+	if full {
+		return
+	}
+
+	var runs []models.TaskRun
+	if err := models.DB.Where("status = ?", models.TaskStatusQueued).Order("priority DESC, id").Find(&runs).Error; err != nil {
+		log.Printf("读取执行队列失败: %v", err)
+		return
+	}
+
+	for i := range runs {
+		run := &runs[i]
+		task, err := snapshotTask(run)
+		if err != nil {
+			abortQueued(run, err)
+			continue
+		}
+		dataSources := runDataSources(task)
+
+		// 先占用名额再认领，认领失败时归还
+		taskQueue.Lock()
+		if taskQueue.running >= workers {
+			taskQueue.Unlock()
+			return
+		}
+		available := true
+		for _, dataSource := range dataSources {
+			limit := perDataSource
+			if dataSource.MaxConcurrency > 0 {
+				limit = dataSource.MaxConcurrency
+			}
+			if taskQueue.dataSources[dataSource.ID] >= limit {
+				available = false
+				break
+			}
+		}
+		if !available {
+			taskQueue.Unlock()
+			continue
+		}
+		dataSourceIDs := make([]uint, len(dataSources))
+		for j, dataSource := range dataSources {
+			dataSourceIDs[j] = dataSource.ID
+		}
+		acquireSlots(dataSourceIDs)
+		taskQueue.Unlock()
+
+		// 认领：只执行仍在排队中的记录
+		now := time.Now()
+		result := models.DB.Model(&models.TaskRun{}).Where("id = ? AND status = ?", run.ID, models.TaskStatusQueued).
+			Updates(map[string]interface{}{"status": models.TaskStatusRunning, "started_at": &now})
+		if result.Error != nil || result.RowsAffected == 0 {
+			taskQueue.Lock()
+			releaseSlots(dataSourceIDs)
+			taskQueue.Unlock()
+			continue
+		}
+		run.Status = models.TaskStatusRunning
+		run.StartedAt = &now
+		go executeQueued(task, run, dataSourceIDs)
+	}
+}
+
+// 执行用到的数据源：任务的数据源，以及子集和脱敏任务输出到数据库时的目标数据源
+func runDataSources(task *models.Task) []models.DataSource {
+	var dataSources []models.DataSource
+	if task.DataSource != nil {
+		dataSources = append(dataSources, *task.DataSource)
+	}
+	if task.OutputType != models.OutputTypeDatabase || (task.Type != models.TaskTypeSubset && task.Type != models.TaskTypeMask) {
+		return dataSources
+	}
+	var config struct {
+		TargetDataSourceID uint `json:"targetDataSourceId"`
+	}
+	if err := task.GetConfiguration(&config); err != nil || config.TargetDataSourceID == 0 {
+		return dataSources
+	}
+	if task.DataSource != nil && task.DataSource.ID == config.TargetDataSourceID {
+		return dataSources
+	}
+	// 目标数据源不存在时由执行报错
+	var target models.DataSource
+	if err := models.DB.First(&target, config.TargetDataSourceID).Error; err == nil {
+		dataSources = append(dataSources, target)
+	}
+	return dataSources
+}
+
+// 占用执行名额，调用方已持有锁
+func acquireSlots(dataSourceIDs []uint) {
+	taskQueue.running++
+	for _, id := range dataSourceIDs {
+		taskQueue.dataSources[id]++
+	}
+}
+
+// 归还执行名额，调用方已持有锁
+func releaseSlots(dataSourceIDs []uint) {
+	taskQueue.running--
+	for _, id := range dataSourceIDs {
+		taskQueue.dataSources[id]--
+	}
+}
+
+// 执行一条认领的记录，结束后释放名额并继续分派
+func executeQueued(task *models.Task, run *models.TaskRun, dataSourceIDs []uint) {
+	NewTaskService().executeTask(task, run)
+
+	taskQueue.Lock()
+	releaseSlots(dataSourceIDs)
+	releaseWaiter(run.ID)
+	taskQueue.Unlock()
+	wakeQueue()
+}
+
+// 无法执行的排队记录（如配置快照损坏、数据源已删除）直接标记为失败
+func abortQueued(run *models.TaskRun, err error) {
+	now := time.Now()
+	models.DB.Model(run).Updates(map[string]interface{}{"status": models.TaskStatusFailed, "error_msg": err.Error(), "finished_at": &now})
+	models.DB.Model(&models.Task{}).Where("id = ?", run.TaskID).
+		Updates(map[string]interface{}{"status": models.TaskStatusFailed, "error_msg": err.Error()})
+	publishTaskEvent(TaskEvent{Type: TaskEventResult, TaskID: run.TaskID, RunID: run.ID, Status: models.TaskStatusFailed, Message: err.Error()})
+	taskQueue.Lock()
+	releaseWaiter(run.ID)
+	taskQueue.Unlock()
+}
+
+// 通知等待该执行结束的调用方，调用方已持有锁
+func releaseWaiter(runID uint) {
+	if done, ok := taskQueue.waiters[runID]; ok {
+		close(done)
+		delete(taskQueue.waiters, runID)
+	}
+}
@@ -0,0 +1,264 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"generateTestData/backend/models"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 记录字段类型
+const (
+	fieldInt32     = "int32"
+	fieldInt64     = "int64"
+	fieldDouble    = "double"
+	fieldBoolean   = "boolean"
+	fieldString    = "string"
+	fieldBytes     = "bytes"
+	fieldJSON      = "json"
+	fieldDate      = "date"
+	fieldTimestamp = "timestamp"
+	fieldGroup     = "group"
+	fieldList      = "list"
+)
+
+// 记录字段定义，用于构造带类型的输出格式（Parquet、Avro 等）的结构
+type schemaField struct {
+	kind     string
+	fields   map[string]*schemaField // group 的子字段
+	order    []string                // group 子字段的顺序
+	elem     *schemaField            // list 的元素
+	optional bool
+}
+
+// RecordSchema 描述输出记录的结构，由表结构或JSON结构推断
+type RecordSchema struct {
+	root *schemaField
+}
+
+// 根据表结构构造记录结构，所有列均可为空
+func RecordSchemaFromTable(tableInfo *models.TableInfo) *RecordSchema {
+	root := &schemaField{kind: fieldGroup, fields: make(map[string]*schemaField)}
+	for _, col := range tableInfo.Columns {
+		root.fields[col.Name] = &schemaField{kind: columnKind(col.Type), optional: true}
+		root.order = append(root.order, col.Name)
+	}
+	return &RecordSchema{root: root}
+}
+
+// 根据JSON结构构造记录结构：对象映射为嵌套结构，数组映射为列表。
+// 叶子字段按示例值推断类型，配置了序列规则的字段使用整数类型。
+func RecordSchemaFromJSON(schema map[string]interface{}, rules map[string]models.FieldRule) (*RecordSchema, error) {
+	root, err := jsonSchemaField("", schema, rules)
+	if err != nil {
+		return nil, err
+	}
+	if len(root.fields) == 0 {
+		return nil, fmt.Errorf("JSON结构不能为空对象")
+	}
+	root.optional = false
+	return &RecordSchema{root: root}, nil
+}
+
+// 推断JSON结构中单个字段的类型
+func jsonSchemaField(path string, schema interface{}, rules map[string]models.FieldRule) (*schemaField, error) {
+	switch v := schema.(type) {
+	case map[string]interface{}:
+		field := &schemaField{kind: fieldGroup, fields: make(map[string]*schemaField), optional: true}
+		for key, value := range v {
+			fieldPath := key
+			if path != "" {
+				fieldPath = path + "." + key
+			}
+			child, err := jsonSchemaField(fieldPath, value, rules)
+			if err != nil {
+				return nil, err
+			}
+			field.fields[key] = child
+			field.order = append(field.order, key)
+		}
+		sort.Strings(field.order)
+		if len(field.fields) == 0 {
+			// Parquet 不允许空的嵌套结构，空对象按JSON字符串输出
+			return &schemaField{kind: fieldJSON, optional: true}, nil
+		}
+		return field, nil
+	case []interface{}:
+		if len(v) == 0 {
+			return &schemaField{kind: fieldList, elem: &schemaField{kind: fieldString, optional: true}, optional: true}, nil
+		}
+		elem, err := jsonSchemaField(path+"[]", v[0], rules)
+		if err != nil {
+			return nil, err
+		}
+		return &schemaField{kind: fieldList, elem: elem, optional: true}, nil
+	}
+
+	if rule, exists := rules[path]; exists && (rule.Type == "sequence" || rule.Type == "increment") {
+		return &schemaField{kind: fieldInt64, optional: true}, nil
+	}
+	switch schema.(type) {
+	case float64, int:
+		return &schemaField{kind: fieldDouble, optional: true}, nil
+	case bool:
+		return &schemaField{kind: fieldBoolean, optional: true}, nil
+	default:
+		return &schemaField{kind: fieldString, optional: true}, nil
+	}
+}
+
+// 将数据库列类型映射为字段类型
+func columnKind(columnType string) string {
+	baseType := strings.ToLower(strings.TrimSpace(columnType))
+	if baseType == "tinyint(1)" {
+		// MySQL 约定 tinyint(1) 表示布尔值
+		return fieldBoolean
+	}
+	if idx := strings.IndexAny(baseType, "( "); idx > 0 && !strings.HasPrefix(baseType, "double precision") && !strings.HasPrefix(baseType, "timestamp") {
+		baseType = baseType[:idx]
+	}
+
+	switch baseType {
+	case "int", "integer", "mediumint", "smallint", "tinyint", "int4", "int2":
+		return fieldInt32
+	case "bigint", "int8", "serial", "bigserial":
+		return fieldInt64
+	case "decimal", "numeric", "float", "double", "real", "double precision":
+		return fieldDouble
+	case "bool", "boolean", "bit":
+		return fieldBoolean
+	case "date":
+		return fieldDate
+	case "datetime", "timestamp", "timestamp without time zone", "timestamp with time zone":
+		return fieldTimestamp
+	case "blob", "bytea", "binary", "varbinary", "longblob":
+		return fieldBytes
+	case "json", "jsonb":
+		return fieldJSON
+	default:
+		return fieldString
+	}
+}
+
+// 转换为整数
+func toInt64(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), nil
+	case int8:
+		return int64(v), nil
+	case int16:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case uint:
+		return int64(v), nil
+	case uint8:
+		return int64(v), nil
+	case uint16:
+		return int64(v), nil
+	case uint32:
+		return int64(v), nil
+	case uint64:
+		return int64(v), nil
+	case float32:
+		return int64(v), nil
+	case float64:
+		return int64(v), nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case []byte:
+		return strconv.ParseInt(string(v), 10, 64)
+	case string:
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return n, nil
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, fmt.Errorf("无法将 %q 转换为整数", v)
+		}
+		return int64(f), nil
+	default:
+		return 0, fmt.Errorf("无法将 %T 转换为整数", value)
+	}
+}
+
+// 转换为布尔值
+func toBool(value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		return strconv.ParseBool(v)
+	default:
+		n, err := toInt64(value)
+		return n != 0, err
+	}
+}
+
+// 转换为浮点数
+func toFloat64(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	case []byte:
+		return strconv.ParseFloat(string(v), 64)
+	default:
+		n, err := toInt64(value)
+		if err != nil {
+			return 0, fmt.Errorf("无法将 %T 转换为浮点数", value)
+		}
+		return float64(n), nil
+	}
+}
+
+// 转换为时间
+func toTime(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		for _, layout := range maskDateLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("无法解析日期: %s", v)
+	case []byte:
+		return toTime(string(v))
+	default:
+		return time.Time{}, fmt.Errorf("无法将 %T 转换为时间", value)
+	}
+}
+
+// 转换为字符串，对象和数组按JSON输出
+func toText(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format("2006-01-02 15:04:05")
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	default:
+		return fmt.Sprint(v)
+	}
+}
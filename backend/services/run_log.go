@@ -0,0 +1,206 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"generateTestData/backend/config"
+	"generateTestData/backend/models"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 每次执行最多记录的被拒绝行数，超出后只记录总数
+const maxRejectedRowLogs = 100
+
+// 缓存的日志条数达到该值时写入数据库
+const runLogFlushSize = 50
+
+// 一次执行的日志：缓存日志条目并批量写入 task_run_logs 表。
+// 方法可在多个协程中调用，nil 表示不记录日志（如命令行执行）。
+type runLogger struct {
+	mu       sync.Mutex
+	taskID   uint
+	runID    uint
+	entries  []models.TaskRunLog
+	rejected int // 已记录的被拒绝行数
+}
+
+func newRunLogger(task *models.Task, run *models.TaskRun) *runLogger {
+	return &runLogger{taskID: task.ID, runID: run.ID}
+}
+
+// 记录一条日志，data 序列化为JSON保存
+func (l *runLogger) log(level models.LogLevel, field, message string, data interface{}) {
+	if l == nil {
+		return
+	}
+	entry := models.TaskRunLog{
+		TaskID:    l.taskID,
+		RunID:     l.runID,
+		Level:     level,
+		Field:     field,
+		Message:   message,
+		CreatedAt: time.Now(),
+	}
+	if data != nil {
+		if encoded, err := json.Marshal(data); err == nil {
+			entry.Data = string(encoded)
+		} else {
+			entry.Data = fmt.Sprintf("%q", fmt.Sprint(data))
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, entry)
+	if len(l.entries) >= runLogFlushSize {
+		l.flushLocked()
+	}
+}
+
+func (l *runLogger) debug(message string, data interface{}) {
+	l.log(models.LogLevelDebug, "", message, data)
+}
+
+func (l *runLogger) info(message string, data interface{}) {
+	l.log(models.LogLevelInfo, "", message, data)
+}
+
+func (l *runLogger) warn(message string, data interface{}) {
+	l.log(models.LogLevelWarn, "", message, data)
+}
+
+// 记录导致执行失败的错误，字段生成失败时记录字段名、规则和行号
+func (l *runLogger) error(err error) {
+	var fieldErr *FieldError
+	if errors.As(err, &fieldErr) {
+		l.log(models.LogLevelError, fieldErr.Field, err.Error(), map[string]interface{}{
+			"rule":  fieldErr.Rule,
+			"row":   fieldErr.Row,
+			"cause": fieldErr.Err.Error(),
+		})
+		return
+	}
+	l.log(models.LogLevelError, "", err.Error(), nil)
+}
+
+// 记录被拒绝的行及其值，超出上限后只在结束时记录总数
+func (l *runLogger) rejectedRow(record map[string]interface{}, reason string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	l.rejected++
+	skip := l.rejected > maxRejectedRowLogs
+	l.mu.Unlock()
+	if skip {
+		return
+	}
+	l.log(models.LogLevelWarn, "", "记录被拒绝: "+reason, record)
+}
+
+// 将缓存的日志写入数据库
+func (l *runLogger) flush() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.flushLocked()
+}
+
+func (l *runLogger) flushLocked() {
+	if len(l.entries) == 0 {
+		return
+	}
+	if err := models.DB.CreateInBatches(l.entries, runLogFlushSize).Error; err != nil {
+		log.Printf("写入执行日志失败: %v", err)
+	}
+	l.entries = nil
+}
+
+// 执行开始时记录的配置摘要
+func runConfigSummary(task *models.Task, run *models.TaskRun) map[string]interface{} {
+	summary := map[string]interface{}{
+		"task":       task.Name,
+		"type":       task.Type,
+		"outputType": task.OutputType,
+		"count":      task.Count,
+		"trigger":    run.Trigger,
+		"seed":       run.Seed,
+		"priority":   run.Priority,
+	}
+	if task.OutputPath != "" {
+		summary["outputPath"] = task.OutputPath
+	}
+	if task.TableName != "" {
+		summary["table"] = task.TableName
+	}
+	if task.DataSource != nil {
+		summary["dataSource"] = fmt.Sprintf("%s (%s)", task.DataSource.Name, task.DataSource.Type)
+	}
+	if run.RerunOf != nil {
+		summary["rerunOf"] = *run.RerunOf
+	}
+	if rules, err := task.GetFieldRules(); err == nil && len(rules) > 0 {
+		fields := make(map[string]string, len(rules))
+		for name, rule := range rules {
+			fields[name] = rule.Type
+		}
+		summary["fieldRules"] = fields
+	}
+	return summary
+}
+
+// 执行日志的过滤条件
+type RunLogFilter struct {
+	Level   models.LogLevel // 最低级别，为空时返回全部
+	Field   string
+	Keyword string // 匹配消息和附加信息
+}
+
+// 获取一次执行的日志，按记录顺序排列
+func (s *TaskService) GetRunLogs(taskID, runID uint, filter *RunLogFilter) ([]models.TaskRunLog, error) {
+	if _, err := s.GetTaskRun(taskID, runID); err != nil {
+		return nil, err
+	}
+
+	query := models.DB.Where("task_id = ? AND run_id = ?", taskID, runID)
+	if filter.Level != "" {
+		severity := filter.Level.Severity()
+		if severity < 0 {
+			return nil, fmt.Errorf("无效的日志级别: %s", filter.Level)
+		}
+		var levels []models.LogLevel
+		for _, level := range []models.LogLevel{models.LogLevelDebug, models.LogLevelInfo, models.LogLevelWarn, models.LogLevelError} {
+			if level.Severity() >= severity {
+				levels = append(levels, level)
+			}
+		}
+		query = query.Where("level IN ?", levels)
+	}
+	if filter.Field != "" {
+		query = query.Where("field = ?", filter.Field)
+	}
+	if keyword := strings.TrimSpace(filter.Keyword); keyword != "" {
+		like := "%" + keyword + "%"
+		query = query.Where("message LIKE ? OR data LIKE ?", like, like)
+	}
+
+	var logs []models.TaskRunLog
+	err := query.Order("id").Find(&logs).Error
+	return logs, err
+}
+
+// 按保留天数清理过期的执行日志，返回删除的条数
+func PruneRunLogs() (int64, error) {
+	if config.AppConfig == nil || config.AppConfig.LogRetentionDays <= 0 {
+		return 0, nil
+	}
+	cutoff := time.Now().AddDate(0, 0, -config.AppConfig.LogRetentionDays)
+	result := models.DB.Where("created_at < ?", cutoff).Delete(&models.TaskRunLog{})
+	return result.RowsAffected, result.Error
+}
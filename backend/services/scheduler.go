@@ -0,0 +1,353 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"generateTestData/backend/models"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+)
+
+// catch-up 策略为 all 时最多补跑的次数
+const maxCatchUpRuns = 10
+
+// wait 策略下等待任务（如手动执行）结束的轮询间隔
+const schedulePollInterval = 2 * time.Second
+
+// 清理过期执行日志的时间：每天零点
+const logRetentionSpec = "@daily"
+
+// 5段cron表达式，支持 @daily、@every 5m 等描述符和 CRON_TZ= 前缀
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// SchedulerService 按任务的定时调度触发执行
+type SchedulerService struct {
+	taskService *TaskService
+	cron        *cron.Cron
+
+	mu      sync.Mutex
+	entries map[uint]cron.EntryID   // 调度ID → cron条目
+	states  map[uint]*scheduleState // 调度ID → 执行状态
+	stopped bool
+	wg      sync.WaitGroup
+}
+
+// 一个调度的执行状态
+type scheduleState struct {
+	running bool
+	pending int // 还需执行的次数
+}
+
+func NewSchedulerService() *SchedulerService {
+	return &SchedulerService{
+		taskService: NewTaskService(),
+		cron:        cron.New(cron.WithParser(cronParser)),
+		entries:     make(map[uint]cron.EntryID),
+		states:      make(map[uint]*scheduleState),
+	}
+}
+
+// 加载已启用的调度，按补跑策略处理停机期间错过的执行，然后开始调度
+func (s *SchedulerService) Start() error {
+	var schedules []models.TaskSchedule
+	if err := models.DB.Where("enabled = ?", true).Find(&schedules).Error; err != nil {
+		return fmt.Errorf("加载定时调度失败: %v", err)
+	}
+
+	now := time.Now()
+	for i := range schedules {
+		schedule := &schedules[i]
+		parsed, err := parseSchedule(schedule)
+		if err != nil {
+			log.Printf("定时调度 %d 无效: %v", schedule.ID, err)
+			continue
+		}
+
+		if missed := missedRuns(parsed, schedule.NextRunAt, now); missed > 0 {
+			switch schedule.CatchUpPolicy {
+			case models.CatchUpOnce:
+				s.trigger(schedule, 1)
+			case models.CatchUpAll:
+				s.trigger(schedule, missed)
+			}
+		}
+
+		next := parsed.Next(now)
+		models.DB.Model(schedule).UpdateColumn("next_run_at", &next)
+		s.register(schedule, parsed)
+	}
+
+	if _, err := s.cron.AddFunc(logRetentionSpec, pruneRunLogs); err != nil {
+		return fmt.Errorf("注册执行日志清理失败: %v", err)
+	}
+	s.cron.Start()
+	return nil
+}
+
+// 停止调度，等待正在执行的任务结束
+func (s *SchedulerService) Stop() {
+	<-s.cron.Stop().Done()
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// 获取调度列表，taskID 为0时返回全部
+func (s *SchedulerService) List(taskID uint) ([]models.TaskSchedule, error) {
+	var schedules []models.TaskSchedule
+	query := models.DB.Order("id")
+	if taskID != 0 {
+		query = query.Where("task_id = ?", taskID)
+	}
+	err := query.Find(&schedules).Error
+	return schedules, err
+}
+
+// 创建调度
+func (s *SchedulerService) Create(schedule *models.TaskSchedule) error {
+	parsed, err := s.validate(schedule)
+	if err != nil {
+		return err
+	}
+	next := parsed.Next(time.Now())
+	schedule.NextRunAt = &next
+	if err := models.DB.Create(schedule).Error; err != nil {
+		return err
+	}
+	s.register(schedule, parsed)
+	return nil
+}
+
+// 更新调度的表达式、时区、启用状态和策略
+func (s *SchedulerService) Update(schedule *models.TaskSchedule) error {
+	var existing models.TaskSchedule
+	if err := models.DB.First(&existing, schedule.ID).Error; err != nil {
+		return fmt.Errorf("定时调度不存在")
+	}
+	parsed, err := s.validate(schedule)
+	if err != nil {
+		return err
+	}
+
+	existing.TaskID = schedule.TaskID
+	existing.Cron = schedule.Cron
+	existing.TimeZone = schedule.TimeZone
+	existing.Enabled = schedule.Enabled
+	existing.OverlapPolicy = schedule.OverlapPolicy
+	existing.CatchUpPolicy = schedule.CatchUpPolicy
+	next := parsed.Next(time.Now())
+	existing.NextRunAt = &next
+	if err := models.DB.Save(&existing).Error; err != nil {
+		return err
+	}
+	*schedule = existing
+	s.register(schedule, parsed)
+	return nil
+}
+
+// 删除调度，正在执行的任务不受影响
+func (s *SchedulerService) Delete(id uint) error {
+	s.unregister(id)
+	return models.DB.Delete(&models.TaskSchedule{}, id).Error
+}
+
+// 验证调度配置并填充默认策略
+func (s *SchedulerService) validate(schedule *models.TaskSchedule) (cron.Schedule, error) {
+	var task models.Task
+	if err := models.DB.Select("id").First(&task, schedule.TaskID).Error; err != nil {
+		return nil, fmt.Errorf("任务不存在")
+	}
+
+	if schedule.OverlapPolicy == "" {
+		schedule.OverlapPolicy = models.OverlapSkip
+	}
+	if schedule.OverlapPolicy != models.OverlapSkip && schedule.OverlapPolicy != models.OverlapWait {
+		return nil, fmt.Errorf("不支持的重叠策略: %s", schedule.OverlapPolicy)
+	}
+	if schedule.CatchUpPolicy == "" {
+		schedule.CatchUpPolicy = models.CatchUpNone
+	}
+	switch schedule.CatchUpPolicy {
+	case models.CatchUpNone, models.CatchUpOnce, models.CatchUpAll:
+	default:
+		return nil, fmt.Errorf("不支持的补跑策略: %s", schedule.CatchUpPolicy)
+	}
+	return parseSchedule(schedule)
+}
+
+// 解析cron表达式，时区通过 CRON_TZ= 前缀指定
+func parseSchedule(schedule *models.TaskSchedule) (cron.Schedule, error) {
+	spec := strings.TrimSpace(schedule.Cron)
+	if spec == "" {
+		return nil, fmt.Errorf("cron表达式不能为空")
+	}
+	if schedule.TimeZone != "" {
+		if _, err := time.LoadLocation(schedule.TimeZone); err != nil {
+			return nil, fmt.Errorf("无效的时区: %s", schedule.TimeZone)
+		}
+		spec = "CRON_TZ=" + schedule.TimeZone + " " + spec
+	}
+	parsed, err := cronParser.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("无效的cron表达式: %v", err)
+	}
+	return parsed, nil
+}
+
+// 计划在 next 执行、到 now 为止错过的次数，最多 maxCatchUpRuns
+func missedRuns(parsed cron.Schedule, next *time.Time, now time.Time) int {
+	if next == nil {
+		return 0
+	}
+	missed := 0
+	for t := *next; !t.After(now) && missed < maxCatchUpRuns; t = parsed.Next(t) {
+		missed++
+	}
+	return missed
+}
+
+// 注册或替换调度的cron条目，未启用的调度只移除
+func (s *SchedulerService) register(schedule *models.TaskSchedule, parsed cron.Schedule) {
+	s.unregister(schedule.ID)
+	if !schedule.Enabled {
+		return
+	}
+	id := schedule.ID
+	entryID := s.cron.Schedule(parsed, cron.FuncJob(func() { s.fire(id) }))
+	s.mu.Lock()
+	s.entries[id] = entryID
+	s.mu.Unlock()
+}
+
+func (s *SchedulerService) unregister(id uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entryID, ok := s.entries[id]; ok {
+		s.cron.Remove(entryID)
+		delete(s.entries, id)
+	}
+}
+
+// cron触发：记录下次执行时间并执行任务
+func (s *SchedulerService) fire(id uint) {
+	var schedule models.TaskSchedule
+	if err := models.DB.First(&schedule, id).Error; err != nil || !schedule.Enabled {
+		s.unregister(id)
+		return
+	}
+	// 任务已被删除时一并删除遗留的调度
+	var task models.Task
+	if err := models.DB.Select("id").First(&task, schedule.TaskID).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		s.unregister(id)
+		models.DB.Delete(&schedule)
+		return
+	}
+	if parsed, err := parseSchedule(&schedule); err == nil {
+		next := parsed.Next(time.Now())
+		models.DB.Model(&schedule).UpdateColumn("next_run_at", &next)
+	}
+	s.trigger(&schedule, 1)
+}
+
+// 按重叠策略安排执行 runs 次
+func (s *SchedulerService) trigger(schedule *models.TaskSchedule, runs int) {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return
+	}
+	state, ok := s.states[schedule.ID]
+	if !ok {
+		state = &scheduleState{}
+		s.states[schedule.ID] = state
+	}
+	if state.running {
+		if schedule.OverlapPolicy == models.OverlapWait {
+			// 执行结束后再执行一次，期间的多次触发合并
+			if state.pending == 0 {
+				state.pending = 1
+			}
+			s.mu.Unlock()
+			return
+		}
+		s.mu.Unlock()
+		s.record(schedule.ID, false, "上次执行尚未结束，已跳过")
+		return
+	}
+	state.running = true
+	state.pending = runs
+	s.wg.Add(1)
+	s.mu.Unlock()
+
+	go s.run(schedule)
+}
+
+// 依次执行待执行的次数
+func (s *SchedulerService) run(schedule *models.TaskSchedule) {
+	defer s.wg.Done()
+	for {
+		s.mu.Lock()
+		state := s.states[schedule.ID]
+		if state.pending == 0 || s.stopped {
+			state.running = false
+			state.pending = 0
+			s.mu.Unlock()
+			return
+		}
+		state.pending--
+		s.mu.Unlock()
+
+		now := time.Now()
+		models.DB.Model(&models.TaskSchedule{}).Where("id = ?", schedule.ID).UpdateColumn("last_run_at", &now)
+		err := s.taskService.ExecuteTaskSync(schedule.TaskID, models.RunTriggerSchedule)
+		for errors.Is(err, ErrTaskRunning) && schedule.OverlapPolicy == models.OverlapWait && !s.isStopped() {
+			time.Sleep(schedulePollInterval)
+			err = s.taskService.ExecuteTaskSync(schedule.TaskID, models.RunTriggerSchedule)
+		}
+
+		switch {
+		case errors.Is(err, ErrTaskRunning):
+			s.record(schedule.ID, false, "任务正在执行中，已跳过")
+		case err != nil:
+			s.record(schedule.ID, true, err.Error())
+		default:
+			s.record(schedule.ID, true, "")
+		}
+	}
+}
+
+func (s *SchedulerService) isStopped() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stopped
+}
+
+// 按保留天数清理执行日志
+func pruneRunLogs() {
+	pruned, err := PruneRunLogs()
+	if err != nil {
+		log.Printf("清理执行日志失败: %v", err)
+		return
+	}
+	if pruned > 0 {
+		log.Printf("已清理 %d 条过期的执行日志", pruned)
+	}
+}
+
+// 记录执行结果，executed 为 false 表示本次被跳过
+func (s *SchedulerService) record(id uint, executed bool, message string) {
+	updates := map[string]interface{}{"last_error": message}
+	if executed {
+		updates["run_count"] = gorm.Expr("run_count + 1")
+	}
+	models.DB.Model(&models.TaskSchedule{}).Where("id = ?", id).UpdateColumns(updates)
+	if message != "" {
+		log.Printf("定时调度 %d: %s", id, message)
+	}
+}
@@ -0,0 +1,442 @@
+package services
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"generateTestData/backend/models"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SQL方言
+const (
+	DialectMySQL      = "mysql"
+	DialectPostgreSQL = "postgresql"
+	DialectSQLite     = "sqlite"
+	DialectSQLServer  = "sqlserver"
+	DialectOracle     = "oracle"
+)
+
+// SQL文件导出选项（存储在 Task.Configuration 中）
+type SQLOptions struct {
+	Dialect            string `json:"sqlDialect"`            // mysql, postgresql, sqlite, sqlserver, oracle，默认取数据源类型
+	CreateTable        bool   `json:"sqlCreateTable"`        // 输出 CREATE TABLE 语句
+	Transaction        bool   `json:"sqlTransaction"`        // 使用 BEGIN/COMMIT 包裹
+	DisableForeignKeys bool   `json:"sqlDisableForeignKeys"` // 导入期间关闭外键检查
+}
+
+// 未指定方言时使用数据源的数据库类型
+func (o *SQLOptions) defaultDialect(dataSource *models.DataSource) {
+	if o.Dialect == "" && dataSource != nil {
+		o.Dialect = dataSource.Type
+	}
+}
+
+// 获取方言，未指定时使用MySQL
+func (o *SQLOptions) dialect() (sqlDialect, error) {
+	if o == nil || o.Dialect == "" {
+		return DialectMySQL, nil
+	}
+	switch d := sqlDialect(strings.ToLower(o.Dialect)); d {
+	case DialectMySQL, DialectPostgreSQL, DialectSQLite, DialectSQLServer, DialectOracle:
+		return d, nil
+	default:
+		return "", fmt.Errorf("不支持的SQL方言: %s", o.Dialect)
+	}
+}
+
+// sqlDialect 负责按目标数据库生成标识符、字面量和语句
+type sqlDialect string
+
+// 引用标识符
+func (d sqlDialect) quoteIdent(name string) string {
+	switch d {
+	case DialectMySQL:
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	case DialectSQLServer:
+		return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+	default:
+		return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+	}
+}
+
+// 引用表名，支持 schema.table 形式
+func (d sqlDialect) quoteTable(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = d.quoteIdent(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+// 将值格式化为字面量，columnType 为列的数据库类型（可为空）
+func (d sqlDialect) literal(value interface{}, columnType string) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if d == DialectPostgreSQL || d == DialectMySQL {
+			if v {
+				return "TRUE"
+			}
+			return "FALSE"
+		}
+		if v {
+			return "1"
+		}
+		return "0"
+	case string:
+		return d.stringLiteral(v, columnType)
+	case []byte:
+		return d.binaryLiteral(v)
+	case time.Time:
+		return d.timeLiteral(v)
+	case float64:
+		return d.floatLiteral(v)
+	case float32:
+		return d.floatLiteral(float64(v))
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprintf("%d", v)
+	case json.Number:
+		return v.String()
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "NULL"
+		}
+		return d.stringLiteral(string(data), columnType)
+	default:
+		return d.stringLiteral(fmt.Sprintf("%v", v), columnType)
+	}
+}
+
+// 字符串字面量
+func (d sqlDialect) stringLiteral(s, columnType string) string {
+	var quoted string
+	switch d {
+	case DialectMySQL:
+		// MySQL 默认将反斜杠视为转义符
+		replacer := strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\x00", `\0`, "\n", `\n`, "\r", `\r`, "\x1a", `\Z`)
+		quoted = "'" + replacer.Replace(s) + "'"
+	case DialectSQLServer:
+		quoted = "N'" + strings.ReplaceAll(s, "'", "''") + "'"
+	default:
+		quoted = "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	}
+
+	if d == DialectPostgreSQL {
+		switch strings.ToLower(columnType) {
+		case "json":
+			return quoted + "::json"
+		case "jsonb":
+			return quoted + "::jsonb"
+		}
+	}
+	return quoted
+}
+
+// 二进制字面量
+func (d sqlDialect) binaryLiteral(b []byte) string {
+	encoded := hex.EncodeToString(b)
+	switch d {
+	case DialectPostgreSQL:
+		return `'\x` + encoded + "'::bytea"
+	case DialectSQLServer:
+		return "0x" + encoded
+	case DialectOracle:
+		return "HEXTORAW('" + encoded + "')"
+	default:
+		return "X'" + encoded + "'"
+	}
+}
+
+// 时间字面量
+func (d sqlDialect) timeLiteral(t time.Time) string {
+	formatted := t.Format("2006-01-02 15:04:05.999999")
+	switch d {
+	case DialectPostgreSQL:
+		return "TIMESTAMP '" + formatted + "'"
+	case DialectOracle:
+		return "TO_TIMESTAMP('" + t.Format("2006-01-02 15:04:05.000000") + "', 'YYYY-MM-DD HH24:MI:SS.FF6')"
+	default:
+		return "'" + formatted + "'"
+	}
+}
+
+// 浮点数字面量，NaN 和无穷大无法表示时写为 NULL
+func (d sqlDialect) floatLiteral(f float64) string {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "NULL"
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// 构造批量INSERT语句，Oracle 使用 INSERT ALL
+func (d sqlDialect) insertStatement(tableName string, columns []string, columnTypes map[string]string, records []map[string]interface{}) string {
+	quotedColumns := make([]string, len(columns))
+	for i, column := range columns {
+		quotedColumns[i] = d.quoteIdent(column)
+	}
+	columnList := strings.Join(quotedColumns, ", ")
+	table := d.quoteTable(tableName)
+
+	rows := make([]string, len(records))
+	for i, record := range records {
+		values := make([]string, len(columns))
+		for j, column := range columns {
+			values[j] = d.literal(record[column], columnTypes[column])
+		}
+		rows[i] = "(" + strings.Join(values, ", ") + ")"
+	}
+
+	if d == DialectOracle {
+		var builder strings.Builder
+		builder.WriteString("INSERT ALL\n")
+		for _, row := range rows {
+			fmt.Fprintf(&builder, "  INTO %s (%s) VALUES %s\n", table, columnList, row)
+		}
+		builder.WriteString("SELECT 1 FROM DUAL;")
+		return builder.String()
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES %s;", table, columnList, strings.Join(rows, ", "))
+}
+
+// 构造 UPDATE 语句
+func (d sqlDialect) updateStatement(tableName string, values, keys map[string]interface{}) string {
+	var sets, conditions []string
+	for _, col := range sortedKeys(values) {
+		sets = append(sets, fmt.Sprintf("%s = %s", d.quoteIdent(col), d.literal(values[col], "")))
+	}
+	for _, col := range sortedKeys(keys) {
+		conditions = append(conditions, fmt.Sprintf("%s = %s", d.quoteIdent(col), d.literal(keys[col], "")))
+	}
+	return fmt.Sprintf("UPDATE %s SET %s WHERE %s;", d.quoteTable(tableName), strings.Join(sets, ", "), strings.Join(conditions, " AND "))
+}
+
+// 构造 CREATE TABLE 语句
+func (d sqlDialect) createTable(tableInfo *models.TableInfo) string {
+	var lines, primaryKeys []string
+	for _, col := range tableInfo.Columns {
+		line := fmt.Sprintf("  %s %s", d.quoteIdent(col.Name), d.columnType(col))
+		if !col.Nullable || col.IsPrimaryKey {
+			line += " NOT NULL"
+		}
+		lines = append(lines, line)
+		if col.IsPrimaryKey {
+			primaryKeys = append(primaryKeys, d.quoteIdent(col.Name))
+		}
+	}
+	if len(primaryKeys) > 0 {
+		lines = append(lines, fmt.Sprintf("  PRIMARY KEY (%s)", strings.Join(primaryKeys, ", ")))
+	}
+
+	ifNotExists := ""
+	if d == DialectMySQL || d == DialectPostgreSQL || d == DialectSQLite {
+		ifNotExists = "IF NOT EXISTS "
+	}
+	return fmt.Sprintf("CREATE TABLE %s%s (\n%s\n);", ifNotExists, d.quoteTable(tableInfo.TableName), strings.Join(lines, ",\n"))
+}
+
+// 将源列类型映射为目标方言的列类型
+func (d sqlDialect) columnType(col models.ColumnInfo) string {
+	length := col.MaxLength
+	if length <= 0 {
+		length = 255
+	}
+
+	// 去掉长度等修饰，如 VARCHAR(50)、int unsigned
+	baseType := strings.ToLower(strings.TrimSpace(col.Type))
+	if idx := strings.IndexAny(baseType, "( "); idx > 0 && !strings.HasPrefix(baseType, "double precision") && !strings.HasPrefix(baseType, "timestamp") {
+		baseType = baseType[:idx]
+	}
+
+	switch baseType {
+	case "int", "integer", "mediumint", "smallint", "tinyint", "int4", "int2":
+		switch d {
+		case DialectOracle:
+			return "NUMBER(10)"
+		case DialectSQLite:
+			return "INTEGER"
+		default:
+			return "INT"
+		}
+	case "bigint", "int8", "serial", "bigserial":
+		if d == DialectOracle {
+			return "NUMBER(19)"
+		}
+		return "BIGINT"
+	case "decimal", "numeric":
+		if d == DialectOracle {
+			return "NUMBER"
+		}
+		return "DECIMAL(18,4)"
+	case "float", "double", "real", "double precision":
+		switch d {
+		case DialectPostgreSQL:
+			return "DOUBLE PRECISION"
+		case DialectSQLServer:
+			return "FLOAT"
+		case DialectOracle:
+			return "BINARY_DOUBLE"
+		case DialectSQLite:
+			return "REAL"
+		default:
+			return "DOUBLE"
+		}
+	case "bool", "boolean", "bit":
+		switch d {
+		case DialectMySQL:
+			return "TINYINT(1)"
+		case DialectSQLServer:
+			return "BIT"
+		case DialectOracle:
+			return "NUMBER(1)"
+		default:
+			return "BOOLEAN"
+		}
+	case "date":
+		return "DATE"
+	case "datetime", "timestamp", "timestamp without time zone", "timestamp with time zone":
+		switch d {
+		case DialectMySQL:
+			return "DATETIME"
+		case DialectSQLServer:
+			return "DATETIME2"
+		default:
+			return "TIMESTAMP"
+		}
+	case "text", "longtext", "mediumtext", "clob":
+		switch d {
+		case DialectSQLServer:
+			return "NVARCHAR(MAX)"
+		case DialectOracle:
+			return "CLOB"
+		default:
+			return "TEXT"
+		}
+	case "json", "jsonb":
+		switch d {
+		case DialectMySQL:
+			return "JSON"
+		case DialectPostgreSQL:
+			return "JSONB"
+		case DialectSQLServer:
+			return "NVARCHAR(MAX)"
+		case DialectOracle:
+			return "CLOB"
+		default:
+			return "TEXT"
+		}
+	case "blob", "bytea", "binary", "varbinary", "longblob":
+		switch d {
+		case DialectPostgreSQL:
+			return "BYTEA"
+		case DialectSQLServer:
+			return "VARBINARY(MAX)"
+		default:
+			return "BLOB"
+		}
+	default:
+		switch d {
+		case DialectSQLServer:
+			return fmt.Sprintf("NVARCHAR(%d)", length)
+		case DialectOracle:
+			return fmt.Sprintf("VARCHAR2(%d)", length)
+		default:
+			return fmt.Sprintf("VARCHAR(%d)", length)
+		}
+	}
+}
+
+// 文件头部语句
+func (d sqlDialect) header(opts *SQLOptions) []string {
+	var lines []string
+	if opts == nil {
+		return lines
+	}
+	if opts.DisableForeignKeys {
+		switch d {
+		case DialectMySQL:
+			lines = append(lines, "SET FOREIGN_KEY_CHECKS=0;")
+		case DialectPostgreSQL:
+			lines = append(lines, "SET session_replication_role = replica;")
+		case DialectSQLite:
+			lines = append(lines, "PRAGMA foreign_keys = OFF;")
+		}
+	}
+	if opts.Transaction {
+		switch d {
+		case DialectMySQL:
+			lines = append(lines, "START TRANSACTION;")
+		case DialectPostgreSQL:
+			lines = append(lines, "BEGIN;")
+		case DialectSQLite, DialectSQLServer:
+			lines = append(lines, "BEGIN TRANSACTION;")
+		}
+	}
+	return lines
+}
+
+// 文件尾部语句
+func (d sqlDialect) footer(opts *SQLOptions) []string {
+	var lines []string
+	if opts == nil {
+		return lines
+	}
+	if opts.Transaction {
+		lines = append(lines, "COMMIT;")
+	}
+	if opts.DisableForeignKeys {
+		switch d {
+		case DialectMySQL:
+			lines = append(lines, "SET FOREIGN_KEY_CHECKS=1;")
+		case DialectPostgreSQL:
+			lines = append(lines, "SET session_replication_role = DEFAULT;")
+		case DialectSQLite:
+			lines = append(lines, "PRAGMA foreign_keys = ON;")
+		}
+	}
+	return lines
+}
+
+// 表级别的外键开关（SQL Server 需要逐表关闭约束检查）
+func (d sqlDialect) disableTableConstraints(tableName string) string {
+	if d == DialectSQLServer {
+		return fmt.Sprintf("ALTER TABLE %s NOCHECK CONSTRAINT ALL;", d.quoteTable(tableName))
+	}
+	return ""
+}
+
+// 表级别的外键恢复
+func (d sqlDialect) enableTableConstraints(tableName string) string {
+	if d == DialectSQLServer {
+		return fmt.Sprintf("ALTER TABLE %s WITH CHECK CHECK CONSTRAINT ALL;", d.quoteTable(tableName))
+	}
+	return ""
+}
+
+// 列顺序：优先按表结构，缺失时按名称排序
+func sqlColumns(tableInfo *models.TableInfo, records []map[string]interface{}) ([]string, map[string]string) {
+	columnTypes := make(map[string]string)
+	if tableInfo != nil && len(tableInfo.Columns) > 0 {
+		columns := make([]string, len(tableInfo.Columns))
+		for i, col := range tableInfo.Columns {
+			columns[i] = col.Name
+			columnTypes[col.Name] = col.Type
+		}
+		return columns, columnTypes
+	}
+
+	columns := make([]string, 0)
+	if len(records) > 0 {
+		for column := range records[0] {
+			columns = append(columns, column)
+		}
+	}
+	sort.Strings(columns)
+	return columns, columnTypes
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"generateTestData/backend/models"
+	"io"
+	"sort"
+	"strings"
+)
+
+// 流式生成每批的记录数，内存占用与总数量无关
+const streamBatchSize = 1000
+
+// 流式生成请求
+type StreamRequest struct {
+	Task       models.Task `json:"task"`       // 任务定义，无需保存
+	TemplateID uint        `json:"templateId"` // 使用规则模板的类型、结构和字段规则，覆盖 task 中的对应字段
+	Count      int64       `json:"count"`      // 生成数量，为0时使用 task.count
+	Format     string      `json:"format"`     // jsonl（默认）, csv, sql
+}
+
+// 流式输出的格式
+var streamFormats = map[string]struct {
+	outputType  models.OutputType
+	contentType string
+}{
+	"jsonl": {models.OutputTypeJSONL, "application/x-ndjson"},
+	"csv":   {models.OutputTypeCSV, "text/csv; charset=utf-8"},
+	"sql":   {models.OutputTypeSQL, "application/sql; charset=utf-8"},
+}
+
+// GenerationStream 一次流式生成：数据分批生成并编码后直接写给调用方，不落盘
+type GenerationStream struct {
+	task        *models.Task
+	contentType string
+	tableInfo   *models.TableInfo
+	encoder     recordEncoder
+	generate    func(generator *GeneratorService, rowIndex int64) (map[string]interface{}, error)
+	dbService   *DatabaseService
+}
+
+// 按请求准备流式生成，配置错误在开始输出之前返回
+func (s *TaskService) NewGenerationStream(req *StreamRequest) (*GenerationStream, error) {
+	task := req.Task
+	if req.TemplateID != 0 {
+		var template models.TaskTemplate
+		if err := models.DB.First(&template, req.TemplateID).Error; err != nil {
+			return nil, fmt.Errorf("规则模板不存在")
+		}
+		task.Type = template.Type
+		task.JSONSchema = template.JSONSchema
+		task.FieldRules = template.FieldRules
+	}
+	if req.Count > 0 {
+		task.Count = req.Count
+	}
+	if task.Count <= 0 {
+		return nil, fmt.Errorf("生成数量必须大于0")
+	}
+
+	formatName := strings.ToLower(req.Format)
+	if formatName == "" {
+		formatName = "jsonl"
+	}
+	format, ok := streamFormats[formatName]
+	if !ok {
+		return nil, fmt.Errorf("不支持的流式输出格式: %s", req.Format)
+	}
+	task.OutputType = format.outputType
+
+	if task.DataSourceID != nil {
+		var dataSource models.DataSource
+		if err := models.DB.First(&dataSource, *task.DataSourceID).Error; err != nil {
+			return nil, fmt.Errorf("获取数据源失败: %v", err)
+		}
+		task.DataSource = &dataSource
+	}
+
+	rules, err := task.GetFieldRules()
+	if err != nil {
+		return nil, fmt.Errorf("解析字段规则失败: %v", err)
+	}
+	unique, err := task.GetUniqueFields()
+	if err != nil {
+		return nil, fmt.Errorf("解析唯一字段失败: %v", err)
+	}
+	stream := &GenerationStream{
+		task:        &task,
+		contentType: format.contentType,
+		dbService:   s.dbService,
+	}
+
+	var headers []string
+	switch task.Type {
+	case models.TaskTypeDatabase:
+		if task.DataSource == nil || task.TableName == "" {
+			return nil, fmt.Errorf("数据库任务必须指定数据源和表名")
+		}
+		stream.tableInfo, err = s.dbService.GetTableStructure(task.DataSource, task.TableName)
+		if err != nil {
+			return nil, fmt.Errorf("获取表结构失败: %v", err)
+		}
+	case models.TaskTypeCSV:
+		var columns []models.ColumnInfo
+		if err := json.Unmarshal([]byte(task.JSONSchema), &columns); err != nil {
+			return nil, fmt.Errorf("解析CSV列结构失败: %v", err)
+		}
+		stream.tableInfo = &models.TableInfo{TableName: "csv_export", Columns: columns}
+	case models.TaskTypeJSON:
+		var schema map[string]interface{}
+		if err := json.Unmarshal([]byte(task.JSONSchema), &schema); err != nil {
+			return nil, fmt.Errorf("解析JSON结构失败: %v", err)
+		}
+		if task.OutputType == models.OutputTypeSQL {
+			return nil, fmt.Errorf("JSON任务不支持 sql 格式")
+		}
+		// CSV以顶层字段作为列，嵌套对象和数组以JSON文本输出
+		for key := range schema {
+			headers = append(headers, key)
+		}
+		sort.Strings(headers)
+		stream.generate = func(generator *GeneratorService, rowIndex int64) (map[string]interface{}, error) {
+			return generator.GenerateJSON(schema, rules, unique, stream.context(rowIndex))
+		}
+	default:
+		return nil, fmt.Errorf("不支持流式生成的任务类型: %s", task.Type)
+	}
+
+	if stream.tableInfo != nil {
+		for _, col := range stream.tableInfo.Columns {
+			headers = append(headers, col.Name)
+		}
+		stream.generate = func(generator *GeneratorService, rowIndex int64) (map[string]interface{}, error) {
+			return generator.GenerateRecord(stream.tableInfo, rules, unique, stream.context(rowIndex))
+		}
+	}
+
+	stream.encoder, err = s.exportService.newRecordEncoder(&task, stream.tableInfo, headers)
+	if err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
+
+// 生成记录的上下文
+func (g *GenerationStream) context(rowIndex int64) map[string]interface{} {
+	return map[string]interface{}{
+		"rowIndex":   rowIndex,
+		"dataSource": g.task.DataSource,
+	}
+}
+
+// 响应的 Content-Type
+func (g *GenerationStream) ContentType() string {
+	return g.contentType
+}
+
+// 生成并写出全部数据，每批写完后调用 flush 推送给客户端；ctx 取消（如客户端断开）时停止生成。
+// 返回已写出的记录数。
+func (g *GenerationStream) Run(ctx context.Context, w io.Writer, flush func()) (int64, error) {
+	buffer := bufio.NewWriter(w)
+	send := func() error {
+		if err := buffer.Flush(); err != nil {
+			return fmt.Errorf("输出数据失败: %v", err)
+		}
+		if flush != nil {
+			flush()
+		}
+		return nil
+	}
+
+	if err := g.encoder.begin(buffer, 1); err != nil {
+		return 0, err
+	}
+
+	// 为每次生成创建独立的生成器实例，避免并发冲突
+	generator := NewGeneratorService(g.dbService)
+	records := make([]map[string]interface{}, 0, streamBatchSize)
+	var generated int64
+	for generated < g.task.Count {
+		if err := ctx.Err(); err != nil {
+			return generated, err
+		}
+
+		records = records[:0]
+		for i := int64(0); i < streamBatchSize && generated+i < g.task.Count; i++ {
+			record, err := g.generate(generator, generated+i)
+			if err != nil {
+				return generated, fmt.Errorf("生成记录失败: %v", err)
+			}
+			records = append(records, record)
+		}
+		if err := g.encoder.encode(buffer, records); err != nil {
+			return generated, err
+		}
+		if err := send(); err != nil {
+			return generated, err
+		}
+		generated += int64(len(records))
+	}
+
+	if err := g.encoder.end(buffer); err != nil {
+		return generated, err
+	}
+	return generated, send()
+}
@@ -0,0 +1,693 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"generateTestData/backend/models"
+	"math"
+	"sort"
+	"strings"
+)
+
+// 子集抽取每次 IN 查询携带的键数量
+const subsetQueryChunkSize = 500
+
+// 子集抽取配置（存储在 Task.Configuration 中）
+type SubsetConfig struct {
+	Filter             string  `json:"filter"`             // 根表过滤条件（SQL WHERE 子句，不含 WHERE 关键字）
+	SamplePercent      float64 `json:"samplePercent"`      // 根表抽样百分比，(0, 100]，为0表示不抽样
+	Limit              int64   `json:"limit"`              // 根表最多抽取的行数，为0表示不限制
+	TargetDataSourceID uint    `json:"targetDataSourceId"` // 输出到数据库时的目标数据源
+	InsertOptions              // 输出到数据库时的写入选项
+	SQLOptions                 // 输出SQL文件时的导出选项
+	XLSXOptions                // 输出Excel文件时的导出选项
+}
+
+type SubsetService struct {
+	dbService     *DatabaseService
+	exportService *ExportService
+	logger        *runLogger // 记录被拒绝的行，为空时不记录
+}
+
+func NewSubsetService(dbService *DatabaseService, exportService *ExportService) *SubsetService {
+	return &SubsetService{
+		dbService:     dbService,
+		exportService: exportService,
+	}
+}
+
+// 子集中的一张表
+type subsetTable struct {
+	info       *models.TableInfo
+	keyColumns []string                 // 行标识列：主键列，无主键时为全部列
+	rows       []map[string]interface{} // 按收集顺序保存的行
+	keys       map[string]int           // 行标识 -> rows 下标
+	expanded   map[string]bool          // 行标识 -> 是否已向下（依赖方向）展开
+}
+
+// 待处理的新增行
+type subsetWork struct {
+	table string
+	rows  []map[string]interface{}
+	down  bool // 是否继续收集依赖这些行的子表记录
+}
+
+// 延迟回填的外键列（用于打破外键环）
+type deferredUpdate struct {
+	table  string
+	keys   map[string]interface{}
+	values map[string]interface{}
+}
+
+// 子集抽取过程状态
+type subsetRun struct {
+	config      *SubsetConfig
+	source      *models.DataSource
+	db          *sql.DB
+	foreignKeys []models.ForeignKey
+	tables      map[string]*subsetTable
+	queue       []subsetWork
+	rejected    int64  // 按错误策略被跳过的行数
+	writer      Writer // 输出，数据库、SQL文件和Excel均按表依次写入
+}
+
+// 抽取子集并写入目标，返回每张表的行数和被拒绝的行数
+func (s *SubsetService) Extract(task *models.Task, config *SubsetConfig, target *models.DataSource, progress func(float64)) (map[string]int64, int64, error) {
+	source := task.DataSource
+	if source == nil {
+		return nil, 0, fmt.Errorf("数据源不能为空")
+	}
+
+	foreignKeys, err := s.dbService.GetForeignKeys(source)
+	if err != nil {
+		return nil, 0, fmt.Errorf("获取外键信息失败: %v", err)
+	}
+
+	db, err := s.dbService.openConnection(source)
+	if err != nil {
+		return nil, 0, fmt.Errorf("连接数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	run := &subsetRun{
+		config:      config,
+		source:      source,
+		db:          db,
+		foreignKeys: foreignKeys,
+		tables:      make(map[string]*subsetTable),
+	}
+
+	// 1. 抽取根表记录
+	rootRows, err := s.selectRootRows(run, task.TableName, config)
+	if err != nil {
+		return nil, 0, fmt.Errorf("抽取根表记录失败: %v", err)
+	}
+	if _, err := s.addRows(run, task.TableName, rootRows, true); err != nil {
+		return nil, 0, err
+	}
+	if progress != nil {
+		progress(10)
+	}
+
+	// 2. 沿外键收集被引用和依赖的记录
+	for len(run.queue) > 0 {
+		work := run.queue[0]
+		run.queue = run.queue[1:]
+		if err := s.expand(run, work); err != nil {
+			return nil, 0, err
+		}
+	}
+	if progress != nil {
+		progress(50)
+	}
+
+	// 3. 按依赖顺序写出
+	order, blocked := s.dependencyOrder(run)
+	// Excel文件没有约束，无需回填
+	if len(blocked) > 0 && task.OutputType != models.OutputTypeXLSX {
+		return nil, 0, cycleError(blocked)
+	}
+	if err := s.write(run, task, order, target, progress); err != nil {
+		return nil, 0, err
+	}
+
+	counts := make(map[string]int64, len(run.tables))
+	for name, table := range run.tables {
+		counts[name] = int64(len(table.rows))
+	}
+	return counts, run.rejected, nil
+}
+
+// 抽取根表记录（过滤条件 + 抽样）
+func (s *SubsetService) selectRootRows(run *subsetRun, tableName string, config *SubsetConfig) ([]map[string]interface{}, error) {
+	where := ""
+	if strings.TrimSpace(config.Filter) != "" {
+		where = " WHERE " + config.Filter
+	}
+
+	limit := config.Limit
+	orderBy := ""
+	if config.SamplePercent > 0 && config.SamplePercent < 100 {
+		var total int64
+		countSQL := fmt.Sprintf("SELECT COUNT(*) FROM %s%s", tableName, where)
+		if err := run.db.QueryRow(countSQL).Scan(&total); err != nil {
+			return nil, err
+		}
+		sampled := int64(math.Ceil(float64(total) * config.SamplePercent / 100))
+		if limit == 0 || sampled < limit {
+			limit = sampled
+		}
+		orderBy = " ORDER BY " + randomFunction(run.source.Type)
+	}
+
+	query := fmt.Sprintf("SELECT * FROM %s%s%s", tableName, where, orderBy)
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+
+	rows, err := run.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanRecords(rows)
+}
+
+// 获取子集表，首次访问时加载表结构
+func (s *SubsetService) getTable(run *subsetRun, name string) (*subsetTable, error) {
+	if table, ok := run.tables[name]; ok {
+		return table, nil
+	}
+
+	info, err := s.dbService.GetTableStructure(run.source, name)
+	if err != nil {
+		return nil, fmt.Errorf("获取表 %s 结构失败: %v", name, err)
+	}
+
+	var keyColumns []string
+	for _, col := range info.Columns {
+		if col.IsPrimaryKey {
+			keyColumns = append(keyColumns, col.Name)
+		}
+	}
+	if len(keyColumns) == 0 {
+		for _, col := range info.Columns {
+			keyColumns = append(keyColumns, col.Name)
+		}
+	}
+
+	table := &subsetTable{
+		info:       info,
+		keyColumns: keyColumns,
+		keys:       make(map[string]int),
+		expanded:   make(map[string]bool),
+	}
+	run.tables[name] = table
+	return table, nil
+}
+
+// 加入新收集到的行，返回其中新增（或需要补充向下展开）的行
+func (s *SubsetService) addRows(run *subsetRun, tableName string, rows []map[string]interface{}, down bool) ([]map[string]interface{}, error) {
+	table, err := s.getTable(run, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	var added []map[string]interface{}
+	for _, row := range rows {
+		key := rowKey(row, table.keyColumns)
+		if _, exists := table.keys[key]; exists {
+			// 已收集过的行：仅当此前只向上收集而现在需要向下展开时重新处理
+			if down && !table.expanded[key] {
+				table.expanded[key] = true
+				added = append(added, row)
+			}
+			continue
+		}
+		table.keys[key] = len(table.rows)
+		table.rows = append(table.rows, row)
+		table.expanded[key] = down
+		added = append(added, row)
+	}
+
+	if len(added) > 0 {
+		run.queue = append(run.queue, subsetWork{table: tableName, rows: added, down: down})
+	}
+	return added, nil
+}
+
+// 沿外键展开一批新增行
+func (s *SubsetService) expand(run *subsetRun, work subsetWork) error {
+	for _, fk := range run.foreignKeys {
+		// 向上：收集这些行引用的父表记录，保证引用完整性
+		if fk.Table == work.table {
+			rows, err := s.fetchByColumns(run, fk.RefTable, fk.RefColumns, work.rows, fk.Columns)
+			if err != nil {
+				return fmt.Errorf("收集 %s 引用的 %s 记录失败: %v", fk.Table, fk.RefTable, err)
+			}
+			// 被引用的父表记录只继续向上收集，避免把整个库拉进子集
+			if _, err := s.addRows(run, fk.RefTable, rows, false); err != nil {
+				return err
+			}
+		}
+
+		// 向下：收集依赖这些行的子表记录
+		if work.down && fk.RefTable == work.table {
+			rows, err := s.fetchByColumns(run, fk.Table, fk.Columns, work.rows, fk.RefColumns)
+			if err != nil {
+				return fmt.Errorf("收集依赖 %s 的 %s 记录失败: %v", fk.RefTable, fk.Table, err)
+			}
+			if _, err := s.addRows(run, fk.Table, rows, true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// 查询 tableName 中 columns 的取值等于 rows 中 valueColumns 取值的记录
+func (s *SubsetService) fetchByColumns(run *subsetRun, tableName string, columns []string, rows []map[string]interface{}, valueColumns []string) ([]map[string]interface{}, error) {
+	// 收集去重后的键值，忽略含 NULL 的键
+	var tuples [][]interface{}
+	seen := make(map[string]bool)
+	for _, row := range rows {
+		tuple := make([]interface{}, len(valueColumns))
+		hasNull := false
+		for i, col := range valueColumns {
+			tuple[i] = row[col]
+			if tuple[i] == nil {
+				hasNull = true
+				break
+			}
+		}
+		if hasNull {
+			continue
+		}
+		key := rowKey(row, valueColumns)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		tuples = append(tuples, tuple)
+	}
+
+	var result []map[string]interface{}
+	for start := 0; start < len(tuples); start += subsetQueryChunkSize {
+		end := start + subsetQueryChunkSize
+		if end > len(tuples) {
+			end = len(tuples)
+		}
+
+		where, args := buildTupleCondition(run.source.Type, columns, tuples[start:end])
+		query := fmt.Sprintf("SELECT * FROM %s WHERE %s", tableName, where)
+		dbRows, err := run.db.Query(query, args...)
+		if err != nil {
+			return nil, err
+		}
+		records, err := scanRecords(dbRows)
+		dbRows.Close()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, records...)
+	}
+	return result, nil
+}
+
+// 计算写出顺序：被引用的表在前，外键环从外键列均可延迟回填的表处打破。
+// 环上没有可延迟的表时返回环上不可为空的外键
+func (s *SubsetService) dependencyOrder(run *subsetRun) ([]string, []models.ForeignKey) {
+	names := make([]string, 0, len(run.tables))
+	for name := range run.tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// parents[t] 为 t 引用的（子集内的）其他表
+	parents := make(map[string]map[string]bool)
+	for _, fk := range run.foreignKeys {
+		if fk.Table == fk.RefTable || run.tables[fk.Table] == nil || run.tables[fk.RefTable] == nil {
+			continue
+		}
+		if parents[fk.Table] == nil {
+			parents[fk.Table] = make(map[string]bool)
+		}
+		parents[fk.Table][fk.RefTable] = true
+	}
+
+	var order []string
+	placed := make(map[string]bool)
+	for len(order) < len(names) {
+		progressed := false
+		for _, name := range names {
+			if placed[name] {
+				continue
+			}
+			ready := true
+			for parent := range parents[name] {
+				if !placed[parent] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				order = append(order, name)
+				placed[name] = true
+				progressed = true
+			}
+		}
+		if !progressed {
+			// 存在外键环：放入第一个引用未放置表的外键都可为空的表，这些外键稍后回填
+			cycle := s.cycleTables(run, names, placed)
+			var blocked []models.ForeignKey
+			breaker := ""
+			for _, name := range names {
+				if !cycle[name] {
+					continue
+				}
+				deferrable := true
+				for _, fk := range run.foreignKeys {
+					if fk.Table != name || fk.RefTable == name || !cycle[fk.RefTable] {
+						continue
+					}
+					if !s.canDefer(run.tables[name], fk.Columns) {
+						deferrable = false
+						blocked = append(blocked, fk)
+					}
+				}
+				if deferrable {
+					breaker = name
+					break
+				}
+			}
+			if breaker == "" {
+				return order, blocked
+			}
+			order = append(order, breaker)
+			placed[breaker] = true
+		}
+	}
+	return order, nil
+}
+
+// 找出外键环上的表：从未放置的表中反复去掉不被其他未放置表引用的表
+func (s *SubsetService) cycleTables(run *subsetRun, names []string, placed map[string]bool) map[string]bool {
+	cycle := make(map[string]bool)
+	for _, name := range names {
+		if !placed[name] {
+			cycle[name] = true
+		}
+	}
+	for {
+		referenced := make(map[string]bool)
+		for _, fk := range run.foreignKeys {
+			if fk.Table != fk.RefTable && cycle[fk.Table] && cycle[fk.RefTable] {
+				referenced[fk.RefTable] = true
+			}
+		}
+		removed := false
+		for name := range cycle {
+			if !referenced[name] {
+				delete(cycle, name)
+				removed = true
+			}
+		}
+		if !removed {
+			return cycle
+		}
+	}
+}
+
+// 外键环上的外键都不可为空时无法先写入 NULL 再回填
+func cycleError(blocked []models.ForeignKey) error {
+	parts := make([]string, 0, len(blocked))
+	for _, fk := range blocked {
+		parts = append(parts, fmt.Sprintf("%s(%s) -> %s", fk.Table, strings.Join(fk.Columns, ", "), fk.RefTable))
+	}
+	return fmt.Errorf("外键存在环且环上的外键列不可为空（或表没有主键），无法确定写入顺序: %s", strings.Join(parts, "; "))
+}
+
+// 按依赖顺序写出子集
+func (s *SubsetService) write(run *subsetRun, task *models.Task, order []string, target *models.DataSource, progress func(float64)) error {
+	position := make(map[string]int, len(order))
+	for i, name := range order {
+		position[name] = i
+	}
+
+	// 引用的表排在自身之后（含自引用）的外键需要先写 NULL，全部插入后再回填
+	deferredColumns := make(map[string][]string)
+	for _, fk := range run.foreignKeys {
+		table := run.tables[fk.Table]
+		if table == nil || run.tables[fk.RefTable] == nil || position[fk.RefTable] < position[fk.Table] {
+			continue
+		}
+		// Excel文件没有约束，按原值写出即可
+		if task.OutputType == models.OutputTypeXLSX {
+			continue
+		}
+		if !s.canDefer(table, fk.Columns) {
+			continue
+		}
+		deferredColumns[fk.Table] = append(deferredColumns[fk.Table], fk.Columns...)
+	}
+
+	switch task.OutputType {
+	case models.OutputTypeDatabase:
+		writer := s.exportService.NewDatabaseWriter(target, "", &run.config.InsertOptions)
+		writer.setLogger(s.logger)
+		run.writer = writer
+	case models.OutputTypeSQL:
+		run.config.SQLOptions.defaultDialect(task.DataSource)
+		writer, err := s.exportService.NewSQLScriptWriter(task.OutputPath, &run.config.SQLOptions)
+		if err != nil {
+			return fmt.Errorf("创建SQL文件失败: %v", err)
+		}
+		run.writer = writer
+	case models.OutputTypeXLSX:
+		writer, err := s.exportService.NewXLSXWriter(task.OutputPath, &run.config.XLSXOptions)
+		if err != nil {
+			return fmt.Errorf("创建Excel文件失败: %v", err)
+		}
+		run.writer = writer
+	default:
+		return fmt.Errorf("不支持的输出类型: %s", task.OutputType)
+	}
+	if err := run.writer.Open(); err != nil {
+		run.writer.Abort()
+		return err
+	}
+	// 未完成时删除未写完的文件
+	committed := false
+	defer func() {
+		if !committed {
+			run.writer.Abort()
+		}
+	}()
+
+	// 输出到数据库时按写入模式准备目标表，子表先于父表清理
+	if database, ok := run.writer.(*DatabaseWriter); ok {
+		for i := len(order) - 1; i >= 0; i-- {
+			if err := database.PrepareTable(order[i]); err != nil {
+				return fmt.Errorf("准备表 %s 失败: %v", order[i], err)
+			}
+		}
+	}
+
+	var updates []deferredUpdate
+	for i, name := range order {
+		table := run.tables[name]
+		records := table.rows
+		if columns := deferredColumns[name]; len(columns) > 0 {
+			records = make([]map[string]interface{}, len(table.rows))
+			for j, row := range table.rows {
+				record := make(map[string]interface{}, len(row))
+				for k, v := range row {
+					record[k] = v
+				}
+				update := deferredUpdate{
+					table:  name,
+					keys:   make(map[string]interface{}),
+					values: make(map[string]interface{}),
+				}
+				for _, col := range columns {
+					if row[col] != nil {
+						update.values[col] = row[col]
+						record[col] = nil
+					}
+				}
+				if len(update.values) > 0 {
+					for _, col := range table.keyColumns {
+						update.keys[col] = row[col]
+					}
+					updates = append(updates, update)
+				}
+				records[j] = record
+			}
+		}
+
+		if err := s.writeRecords(run, name, records); err != nil {
+			return fmt.Errorf("写入表 %s 失败: %v", name, err)
+		}
+
+		if progress != nil {
+			progress(50 + math.Round(float64(i+1)/float64(len(order))*45))
+		}
+	}
+
+	if len(updates) > 0 {
+		if err := s.writeUpdates(run, updates); err != nil {
+			return fmt.Errorf("回填外键失败: %v", err)
+		}
+	}
+	if err := run.writer.Close(); err != nil {
+		return err
+	}
+	committed = true
+	if database, ok := run.writer.(*DatabaseWriter); ok {
+		run.rejected = database.Rejected()
+	}
+	return nil
+}
+
+// 外键列均可为空且表有主键时才能延迟回填
+func (s *SubsetService) canDefer(table *subsetTable, columns []string) bool {
+	nullable := make(map[string]bool)
+	hasPrimaryKey := false
+	for _, col := range table.info.Columns {
+		nullable[col.Name] = col.Nullable && !col.IsPrimaryKey
+		if col.IsPrimaryKey {
+			hasPrimaryKey = true
+		}
+	}
+	if !hasPrimaryKey {
+		return false
+	}
+	for _, col := range columns {
+		if !nullable[col] {
+			return false
+		}
+	}
+	return true
+}
+
+// 写出一张表的记录
+func (s *SubsetService) writeRecords(run *subsetRun, tableName string, records []map[string]interface{}) error {
+	switch writer := run.writer.(type) {
+	case *DatabaseWriter:
+		writer.StartTable(tableName)
+	case *SQLScriptWriter:
+		if err := writer.StartTable(run.tables[tableName].info); err != nil {
+			return err
+		}
+	case *XLSXWriter:
+		columns := run.tables[tableName].info.Columns
+		headers := make([]string, len(columns))
+		for i, col := range columns {
+			headers[i] = col.Name
+		}
+		if err := writer.StartSheet(tableName, headers); err != nil {
+			return err
+		}
+	}
+
+	batchSize := 1000
+	for start := 0; start < len(records); start += batchSize {
+		end := start + batchSize
+		if end > len(records) {
+			end = len(records)
+		}
+		if err := run.writer.WriteBatch(records[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 写出外键回填语句
+func (s *SubsetService) writeUpdates(run *subsetRun, updates []deferredUpdate) error {
+	switch writer := run.writer.(type) {
+	case *DatabaseWriter:
+		for _, update := range updates {
+			query, args := buildUpdateStatement(writer.dbType, update)
+			if _, err := writer.exec(query, args...); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *SQLScriptWriter:
+		statements := make([]string, len(updates))
+		for i, update := range updates {
+			statements[i] = writer.dialect.updateStatement(update.table, update.values, update.keys)
+		}
+		return writer.WriteStatements(statements)
+	default:
+		return fmt.Errorf("不支持回填外键的输出")
+	}
+}
+
+// 构造带占位符的回填语句
+func buildUpdateStatement(dbType string, update deferredUpdate) (string, []interface{}) {
+	var sets, conditions []string
+	var args []interface{}
+	for _, col := range sortedKeys(update.values) {
+		args = append(args, update.values[col])
+		sets = append(sets, fmt.Sprintf("%s = %s", col, placeholder(dbType, len(args))))
+	}
+	for _, col := range sortedKeys(update.keys) {
+		args = append(args, update.keys[col])
+		conditions = append(conditions, fmt.Sprintf("%s = %s", col, placeholder(dbType, len(args))))
+	}
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s", update.table, strings.Join(sets, ", "), strings.Join(conditions, " AND "))
+	return query, args
+}
+
+// 构造多列键匹配条件：单列使用 IN，复合键使用 (a = ? AND b = ?) OR ...
+func buildTupleCondition(dbType string, columns []string, tuples [][]interface{}) (string, []interface{}) {
+	var args []interface{}
+	if len(columns) == 1 {
+		holders := make([]string, len(tuples))
+		for i, tuple := range tuples {
+			args = append(args, tuple[0])
+			holders[i] = placeholder(dbType, len(args))
+		}
+		return fmt.Sprintf("%s IN (%s)", columns[0], strings.Join(holders, ", ")), args
+	}
+
+	conditions := make([]string, len(tuples))
+	for i, tuple := range tuples {
+		parts := make([]string, len(columns))
+		for j, col := range columns {
+			args = append(args, tuple[j])
+			parts[j] = fmt.Sprintf("%s = %s", col, placeholder(dbType, len(args)))
+		}
+		conditions[i] = "(" + strings.Join(parts, " AND ") + ")"
+	}
+	return strings.Join(conditions, " OR "), args
+}
+
+// 行标识：指定列取值拼接
+func rowKey(row map[string]interface{}, columns []string) string {
+	parts := make([]string, len(columns))
+	for i, col := range columns {
+		parts[i] = fmt.Sprint(row[col])
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// 返回排序后的键列表
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// 获取数据库的随机排序函数
+func randomFunction(dbType string) string {
+	if strings.ToLower(dbType) == "mysql" {
+		return "RAND()"
+	}
+	return "RANDOM()"
+}
@@ -1,19 +1,28 @@
 package services
 
 import (
-	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"generateTestData/backend/models"
-	"io"
 	"math"
-	"net/http"
+	"sort"
+	"strings"
 	"time"
+
+	"gorm.io/gorm"
 )
 
+// 任务已在排队或执行中
+var ErrTaskRunning = errors.New("任务正在排队或执行中")
+
 type TaskService struct {
 	dbService     *DatabaseService
 	exportService *ExportService
+	progress      func(progress float64) // 设置后进度交给回调，不写任务表
+	seed          *int64                 // 生成器的随机种子，为空时使用随机种子
+	reporter      *runReporter           // 执行队列中的执行推送阶段和进度事件
+	logger        *runLogger             // 执行队列中的执行写入执行日志
 }
 
 func NewTaskService() *TaskService {
@@ -56,80 +65,152 @@ func (s *TaskService) GetTask(id uint) (*models.Task, error) {
 	return &task, err
 }
 
-// 删除任务
+// 删除任务及其执行记录和日志
 func (s *TaskService) DeleteTask(id uint) error {
-	return models.DB.Delete(&models.Task{}, id).Error
+	return models.DB.Transaction(func(tx *gorm.DB) error {
+		return deleteTask(tx, id)
+	})
 }
 
-// 执行任务
-func (s *TaskService) ExecuteTask(taskID uint) error {
+// 删除任务及其执行记录、执行日志和定时调度。已注册的cron条目在下次触发时发现调度不存在后自行移除
+func deleteTask(tx *gorm.DB, id uint) error {
+	if err := tx.Where("task_id = ?", id).Delete(&models.TaskRunLog{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("task_id = ?", id).Delete(&models.TaskRun{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("task_id = ?", id).Delete(&models.TaskSchedule{}).Error; err != nil {
+		return err
+	}
+	return tx.Delete(&models.Task{}, id).Error
+}
+
+// 将任务加入执行队列，返回本次的执行记录
+func (s *TaskService) ExecuteTask(taskID uint, trigger models.RunTrigger) (*models.TaskRun, error) {
 	// 获取任务
 	task, err := s.GetTask(taskID)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return s.enqueue(task, trigger, time.Now().UnixNano(), nil)
+}
 
-	// 检查任务状态
-	if task.Status == models.TaskStatusRunning {
-		return fmt.Errorf("任务正在执行中")
+// 将任务加入执行队列并等待执行结束，返回任务执行的错误，供定时调度等后台调用
+func (s *TaskService) ExecuteTaskSync(taskID uint, trigger models.RunTrigger) error {
+	run, err := s.ExecuteTask(taskID, trigger)
+	if err != nil {
+		return err
 	}
-
-	// 启动异步执行
-	go s.executeTaskAsync(task)
-
-	return nil
+	return waitRun(run.ID)
 }
 
-// 异步执行任务
-func (s *TaskService) executeTaskAsync(task *models.Task) {
+// 执行任务并更新任务状态和执行记录
+func (s *TaskService) executeTask(task *models.Task, run *models.TaskRun) (err error) {
 	// 更新任务状态为运行中
 	s.updateTaskStatus(task.ID, models.TaskStatusRunning, 0, "")
+	publishTaskEvent(TaskEvent{Type: TaskEventStatus, TaskID: task.ID, RunID: run.ID, Status: models.TaskStatusRunning})
 
-	start := time.Now()
+	logger := newRunLogger(task, run)
+	logger.info("开始执行", runConfigSummary(task, run))
+
+	var result *models.TaskResult
 	defer func() {
 		if r := recover(); r != nil {
-			errorMsg := fmt.Sprintf("任务执行异常: %v", r)
-			s.updateTaskStatus(task.ID, models.TaskStatusFailed, 0, errorMsg)
+			err = fmt.Errorf("任务执行异常: %v", r)
+			s.updateTaskStatus(task.ID, models.TaskStatusFailed, 0, err.Error())
+		}
+		if err != nil {
+			logger.error(err)
+		} else {
+			logRunResult(logger, result)
 		}
+		logger.flush()
+		s.finishRun(run, result, err)
 	}()
 
-	var err error
-	switch task.Type {
-	case models.TaskTypeDatabase:
-		err = s.executeDatabaseTask(task)
-	case models.TaskTypeJSON:
-		err = s.executeJSONTask(task)
-	case models.TaskTypeCSV:
-		err = s.executeCSVTask(task)
-	default:
-		err = fmt.Errorf("不支持的任务类型: %s", task.Type)
-	}
-
+	// 使用执行记录中的种子，重新执行时生成相同的数据
+	runner := *s
+	runner.seed = &run.Seed
+	runner.reporter = newRunReporter(task, run)
+	runner.logger = logger
+	result, err = runner.runTask(task)
 	if err != nil {
 		s.updateTaskStatus(task.ID, models.TaskStatusFailed, 0, err.Error())
-		return
+		return err
 	}
 
 	// 更新完成状态
 	now := time.Now()
+	task.SetResult(result)
 	models.DB.Model(task).Updates(map[string]interface{}{
 		"status":       models.TaskStatusCompleted,
 		"progress":     100.0,
 		"completed_at": &now,
 		"error_msg":    "", // 清除错误信息
+		"result":       task.Result,
 	})
+	return nil
+}
 
-	fmt.Printf("任务 %d 执行完成，耗时: %v\n", task.ID, time.Since(start))
+// 创建任务使用的生成器
+func (s *TaskService) newGenerator() *GeneratorService {
+	return NewGenerator(GeneratorOptions{Seed: s.seed, Lookup: &databaseLookup{dbService: s.dbService}})
+}
+
+// 同步执行任务并返回结果，不更新任务表，用于命令行等无界面场景；progress 接收 0-100 的进度
+func (s *TaskService) RunTask(task *models.Task, progress func(float64)) (*models.TaskResult, error) {
+	if err := s.validateTask(task); err != nil {
+		return nil, err
+	}
+	runner := *s
+	runner.progress = progress
+	return runner.runTask(task)
+}
+
+// 按任务类型执行任务并统计耗时和吞吐量
+func (s *TaskService) runTask(task *models.Task) (*models.TaskResult, error) {
+	start := time.Now()
+	result := &models.TaskResult{
+		TaskID:    task.ID,
+		CreatedAt: start,
+	}
+
+	var err error
+	switch task.Type {
+	case models.TaskTypeDatabase:
+		err = s.executeDatabaseTask(task, result)
+	case models.TaskTypeJSON:
+		err = s.executeJSONTask(task, result)
+	case models.TaskTypeCSV:
+		err = s.executeCSVTask(task, result)
+	case models.TaskTypeSubset:
+		err = s.executeSubsetTask(task, result)
+	case models.TaskTypeMask:
+		err = s.executeMaskTask(task, result)
+	default:
+		err = fmt.Errorf("不支持的任务类型: %s", task.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result.Duration = time.Since(start)
+	if seconds := result.Duration.Seconds(); seconds > 0 {
+		result.RowsPerSecond = math.Round(float64(result.GeneratedCount) / seconds)
+	}
+	return result, nil
 }
 
 // 执行数据库任务
-func (s *TaskService) executeDatabaseTask(task *models.Task) error {
+func (s *TaskService) executeDatabaseTask(task *models.Task, result *models.TaskResult) error {
 	// 获取数据源
 	if task.DataSource == nil {
 		return fmt.Errorf("数据源不能为空")
 	}
 
 	// 获取表结构
+	s.setPhase(PhaseIntrospecting)
 	tableInfo, err := s.dbService.GetTableStructure(task.DataSource, task.TableName)
 	if err != nil {
 		return fmt.Errorf("获取表结构失败: %v", err)
@@ -147,61 +228,76 @@ func (s *TaskService) executeDatabaseTask(task *models.Task) error {
 		return fmt.Errorf("解析唯一字段失败: %v", err)
 	}
 
+	headers := make([]string, len(tableInfo.Columns))
+	for i, col := range tableInfo.Columns {
+		headers[i] = col.Name
+	}
+	writer, err := s.openWriter(task, &writerTarget{
+		name:       task.TableName,
+		dataSource: task.DataSource,
+		tableInfo:  tableInfo,
+		headers:    headers,
+		schema:     RecordSchemaFromTable(tableInfo),
+	})
+	if err != nil {
+		return err
+	}
+
 	// 为每个任务创建独立的生成器实例，避免并发冲突
-	generatorService := NewGeneratorService(s.dbService)
+	generatorService := s.newGenerator()
 
 	// 分批生成数据
+	s.setPhase(PhaseGenerating)
 	batchSize := int64(10000) // 每批1万条
 	var generated int64
 
-	for generated < task.Count {
-		currentBatch := batchSize
-		if generated+batchSize > task.Count {
-			currentBatch = task.Count - generated
-		}
-
-		// 生成一批数据
-		records := make([]map[string]interface{}, currentBatch)
-		for i := int64(0); i < currentBatch; i++ {
-			// 构造上下文
-			context := map[string]interface{}{
-				"rowIndex":   generated + i,
-				"dataSource": task.DataSource,
+	err = writeAll(writer, result, func(write func([]map[string]interface{}) error) error {
+		for generated < task.Count {
+			batchStart := time.Now()
+			currentBatch := batchSize
+			if generated+batchSize > task.Count {
+				currentBatch = task.Count - generated
 			}
 
-			record, err := generatorService.GenerateRecord(tableInfo, rules, uniqueFields, context)
-			if err != nil {
-				return fmt.Errorf("生成记录失败: %v", err)
+			// 生成一批数据
+			records := make([]map[string]interface{}, currentBatch)
+			for i := int64(0); i < currentBatch; i++ {
+				// 构造上下文
+				context := map[string]interface{}{
+					"rowIndex":   generated + i,
+					"dataSource": task.DataSource,
+				}
+
+				record, err := generatorService.GenerateRecord(tableInfo, rules, uniqueFields, context)
+				if err != nil {
+					return fmt.Errorf("生成记录失败: %w", err)
+				}
+				records[i] = record
 			}
-			records[i] = record
-		}
 
-		// 输出数据
-		switch task.OutputType {
-		case models.OutputTypeDatabase:
-			err = s.exportService.InsertToDatabase(task.DataSource, task.TableName, records)
-		case models.OutputTypeSQL:
-			err = s.exportService.ExportToSQL(task.OutputPath, task.TableName, records, generated == 0)
-		case models.OutputTypeMockServer:
-			err = s.pushToMockServer(task, records)
-		default:
-			return fmt.Errorf("不支持的输出类型: %s", task.OutputType)
-		}
+			// 输出数据
+			if err := write(records); err != nil {
+				return err
+			}
 
-		if err != nil {
-			return fmt.Errorf("输出数据失败: %v", err)
+			generated += currentBatch
+			s.logBatch(currentBatch, generated, batchStart)
+			progress := math.Round(float64(generated) / float64(task.Count) * 100)
+			s.updateTaskProgress(task.ID, progress, generated)
 		}
-
-		generated += currentBatch
-		progress := math.Round(float64(generated) / float64(task.Count) * 100)
-		s.updateTaskProgress(task.ID, progress)
+		s.setPhase(PhaseWriting)
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
+	result.GeneratedCount = generated
 	return nil
 }
 
 // 执行JSON任务
-func (s *TaskService) executeJSONTask(task *models.Task) error {
+func (s *TaskService) executeJSONTask(task *models.Task, result *models.TaskResult) error {
 	// 解析JSON结构
 	var schema map[string]interface{}
 	if err := json.Unmarshal([]byte(task.JSONSchema), &schema); err != nil {
@@ -220,66 +316,263 @@ func (s *TaskService) executeJSONTask(task *models.Task) error {
 		return fmt.Errorf("解析唯一字段失败: %v", err)
 	}
 
+	target := &writerTarget{name: task.Name}
+	switch task.OutputType {
+	case models.OutputTypeParquet, models.OutputTypeAvro, models.OutputTypeKafka:
+		target.schema, err = RecordSchemaFromJSON(schema, rules)
+		if err != nil {
+			return err
+		}
+	case models.OutputTypeXLSX:
+		// 以顶层字段作为列，嵌套对象和数组以JSON文本写入单元格
+		for key := range schema {
+			target.headers = append(target.headers, key)
+		}
+		sort.Strings(target.headers)
+	}
+	writer, err := s.openWriter(task, target)
+	if err != nil {
+		return err
+	}
+
 	// 为每个任务创建独立的生成器实例，避免并发冲突
-	generatorService := NewGeneratorService(s.dbService)
+	generatorService := s.newGenerator()
 
 	// 分批生成数据
+	s.setPhase(PhaseGenerating)
 	batchSize := int64(1000) // JSON数据每批1000条
 	var generated int64
 
-	for generated < task.Count {
-		currentBatch := batchSize
-		if generated+batchSize > task.Count {
-			currentBatch = task.Count - generated
+	err = writeAll(writer, result, func(write func([]map[string]interface{}) error) error {
+		for generated < task.Count {
+			batchStart := time.Now()
+			currentBatch := batchSize
+			if generated+batchSize > task.Count {
+				currentBatch = task.Count - generated
+			}
+
+			// 生成一批数据
+			jsonObjects := make([]map[string]interface{}, currentBatch)
+			for i := int64(0); i < currentBatch; i++ {
+				// 构造上下文
+				context := map[string]interface{}{
+					"rowIndex":   generated + i,
+					"dataSource": task.DataSource,
+				}
+
+				jsonObj, err := generatorService.GenerateJSON(schema, rules, uniqueFields, context)
+				if err != nil {
+					return fmt.Errorf("生成JSON对象失败: %w", err)
+				}
+				jsonObjects[i] = jsonObj
+			}
+
+			// 输出数据
+			if err := write(jsonObjects); err != nil {
+				return err
+			}
+
+			generated += currentBatch
+			s.logBatch(currentBatch, generated, batchStart)
+			progress := math.Round(float64(generated) / float64(task.Count) * 100)
+			s.updateTaskProgress(task.ID, progress, generated)
 		}
+		s.setPhase(PhaseWriting)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	result.GeneratedCount = generated
+	return nil
+}
 
-		// 生成一批数据
-		jsonObjects := make([]map[string]interface{}, currentBatch)
-		for i := int64(0); i < currentBatch; i++ {
-			// 构造上下文
-			context := map[string]interface{}{
-				"rowIndex":   generated + i,
-				"dataSource": task.DataSource,
+// 执行子集抽取任务
+func (s *TaskService) executeSubsetTask(task *models.Task, result *models.TaskResult) error {
+	var config SubsetConfig
+	if err := task.GetConfiguration(&config); err != nil {
+		return fmt.Errorf("解析子集配置失败: %v", err)
+	}
+
+	// 输出到数据库时获取目标数据源
+	var target *models.DataSource
+	if task.OutputType == models.OutputTypeDatabase {
+		var dataSource models.DataSource
+		if err := models.DB.First(&dataSource, config.TargetDataSourceID).Error; err != nil {
+			return fmt.Errorf("获取目标数据源失败: %v", err)
+		}
+		target = &dataSource
+	}
+
+	// 前半段沿外键收集记录，进度过半后开始写出
+	s.setPhase(PhaseIntrospecting)
+	subsetService := NewSubsetService(s.dbService, s.exportService)
+	subsetService.logger = s.logger
+	counts, rejected, err := subsetService.Extract(task, &config, target, func(progress float64) {
+		if progress >= 50 {
+			s.setPhase(PhaseGenerating)
+		}
+		s.updateTaskProgress(task.ID, progress, 0)
+	})
+	if err != nil {
+		return err
+	}
+
+	result.TableCounts = counts
+	result.RejectedCount = rejected
+	for _, count := range counts {
+		result.GeneratedCount += count
+	}
+	return nil
+}
+
+// 执行脱敏任务：流式读取源表，按字段规则脱敏后写出
+func (s *TaskService) executeMaskTask(task *models.Task, result *models.TaskResult) error {
+	if task.DataSource == nil {
+		return fmt.Errorf("数据源不能为空")
+	}
+
+	var config MaskConfig
+	if err := task.GetConfiguration(&config); err != nil {
+		return fmt.Errorf("解析脱敏配置失败: %v", err)
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 1000
+	}
+	targetTable := config.TargetTable
+	if targetTable == "" {
+		targetTable = task.TableName
+	}
+
+	rules, err := task.GetFieldRules()
+	if err != nil {
+		return fmt.Errorf("解析字段规则失败: %v", err)
+	}
+
+	// 输出到数据库时获取目标数据源
+	var target *models.DataSource
+	if task.OutputType == models.OutputTypeDatabase {
+		var dataSource models.DataSource
+		if err := models.DB.First(&dataSource, config.TargetDataSourceID).Error; err != nil {
+			return fmt.Errorf("获取目标数据源失败: %v", err)
+		}
+		target = &dataSource
+	}
+
+	// 获取表结构，用于CSV表头和SQL文件
+	s.setPhase(PhaseIntrospecting)
+	tableInfo, err := s.dbService.GetTableStructure(task.DataSource, task.TableName)
+	if err != nil {
+		return fmt.Errorf("获取表结构失败: %v", err)
+	}
+	headers := make([]string, len(tableInfo.Columns))
+	for i, col := range tableInfo.Columns {
+		headers[i] = col.Name
+	}
+
+	db, err := s.dbService.openConnection(task.DataSource)
+	if err != nil {
+		return fmt.Errorf("连接数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	// 统计总行数用于计算进度
+	where := ""
+	if strings.TrimSpace(config.Filter) != "" {
+		where = " WHERE " + config.Filter
+	}
+	var total int64
+	if err := db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s%s", task.TableName, where)).Scan(&total); err != nil {
+		return fmt.Errorf("统计源表行数失败: %v", err)
+	}
+
+	rows, err := db.Query(fmt.Sprintf("SELECT * FROM %s%s", task.TableName, where))
+	if err != nil {
+		return fmt.Errorf("读取源表失败: %v", err)
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	// SQL文件中的表结构使用目标表名
+	targetInfo := *tableInfo
+	targetInfo.TableName = targetTable
+
+	writer, err := s.openWriter(task, &writerTarget{
+		name:       targetTable,
+		dataSource: target,
+		tableInfo:  &targetInfo,
+		headers:    headers,
+	})
+	if err != nil {
+		return err
+	}
+
+	maskingService := NewMaskingService(config.Salt)
+	var processed int64
+	s.setPhase(PhaseGenerating)
+
+	err = writeAll(writer, result, func(write func([]map[string]interface{}) error) error {
+		batch := make([]map[string]interface{}, 0, config.BatchSize)
+
+		// 写出一批脱敏后的数据
+		batchStart := time.Now()
+		flush := func() error {
+			if len(batch) == 0 {
+				return nil
+			}
+			if err := write(batch); err != nil {
+				return err
 			}
 
-			jsonObj, err := generatorService.GenerateJSON(schema, rules, uniqueFields, context)
-			if err != nil {
-				return fmt.Errorf("生成JSON对象失败: %v", err)
+			processed += int64(len(batch))
+			s.logBatch(int64(len(batch)), processed, batchStart)
+			batchStart = time.Now()
+			batch = batch[:0]
+			if total > 0 {
+				s.updateTaskProgress(task.ID, math.Round(float64(processed)/float64(total)*100), processed)
 			}
-			jsonObjects[i] = jsonObj
+			return nil
 		}
 
-		// 根据输出类型导出到文件
-		switch task.OutputType {
-		case models.OutputTypeJSON:
-			err = s.exportService.ExportToJSON(task.OutputPath, jsonObjects, generated == 0)
+		for rows.Next() {
+			record, err := scanRecord(rows, columns)
 			if err != nil {
-				return fmt.Errorf("导出JSON失败: %v", err)
+				return fmt.Errorf("读取记录失败: %v", err)
 			}
-		case models.OutputTypeTXT:
-			err = s.exportService.ExportToTXT(task.OutputPath, jsonObjects, generated == 0)
+			masked, err := maskingService.MaskRecord(record, rules)
 			if err != nil {
-				return fmt.Errorf("导出TXT失败: %v", err)
+				return err
 			}
-		case models.OutputTypeMockServer:
-			err = s.pushToMockServer(task, jsonObjects)
-			if err != nil {
-				return fmt.Errorf("推送至Mock Server失败: %v", err)
+			batch = append(batch, masked)
+			if len(batch) >= config.BatchSize {
+				if err := flush(); err != nil {
+					return err
+				}
 			}
-		default:
-			return fmt.Errorf("不支持的输出类型: %s", task.OutputType)
 		}
-
-		generated += currentBatch
-		progress := math.Round(float64(generated) / float64(task.Count) * 100)
-		s.updateTaskProgress(task.ID, progress)
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("读取源表失败: %v", err)
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+		s.setPhase(PhaseWriting)
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
+	result.GeneratedCount = processed
 	return nil
 }
 
-// 推送数据到 Mock Server
-func (s *TaskService) pushToMockServer(task *models.Task, data []map[string]interface{}) error {
+// Mock Server 输出：每批数据以 {"type", "data"} 的形式整批推送，基于HTTP输出实现
+func (s *TaskService) newMockServerWriter(task *models.Task) (*HTTPWriter, error) {
 	var config struct {
 		URL   string `json:"url"`
 		Token string `json:"token"`
@@ -288,58 +581,41 @@ func (s *TaskService) pushToMockServer(task *models.Task, data []map[string]inte
 	// 如果 Configuration 为空，尝试使用默认配置或报错
 	if task.Configuration != "" {
 		if err := json.Unmarshal([]byte(task.Configuration), &config); err != nil {
-			return fmt.Errorf("解析Mock Server配置失败: %v", err)
+			return nil, fmt.Errorf("解析Mock Server配置失败: %v", err)
 		}
 	}
 
 	if config.URL == "" {
 		// 尝试从 OutputPath 获取 URL (兼容性考虑)
-		if task.OutputPath != "" && (task.OutputPath[:7] == "http://" || task.OutputPath[:8] == "https://") {
+		if strings.HasPrefix(task.OutputPath, "http://") || strings.HasPrefix(task.OutputPath, "https://") {
 			config.URL = task.OutputPath
 		} else {
-			return fmt.Errorf("Mock Server URL不能为空")
+			return nil, fmt.Errorf("Mock Server URL不能为空")
 		}
 	}
 
-	// 构造请求体
-	payload := map[string]interface{}{
-		"type": config.Type,
-		"data": data,
-	}
-	jsonData, err := json.Marshal(payload)
-	if err != nil {
-		return err
-	}
-
-	req, err := http.NewRequest("POST", config.URL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Content-Type", "application/json")
+	options := &HTTPOptions{URL: config.URL}
 	if config.Token != "" {
-		// 自动添加 Bearer 前缀（如果用户未提供）
-		token := config.Token
-		if len(token) < 7 || (token[:7] != "Bearer " && token[:7] != "bearer ") {
-			token = "Bearer " + token
-		}
-		req.Header.Set("Authorization", token)
+		options.Auth = "bearer"
+		options.Token = config.Token
 	}
-
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-	}
-	resp, err := client.Do(req)
+	writer, err := s.exportService.NewHTTPWriter(options)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("Mock Server返回错误 (%d): %s", resp.StatusCode, string(body))
+	writer.batchSize = 0
+	writer.body = func(data interface{}) ([]byte, error) {
+		return json.Marshal(map[string]interface{}{
+			"type": config.Type,
+			"data": data.(map[string]interface{})["records"],
+		})
 	}
+	return writer, nil
+}
 
-	return nil
+// 验证任务配置，不保存任务
+func (s *TaskService) ValidateTask(task *models.Task) error {
+	return s.validateTask(task)
 }
 
 // 验证任务配置
@@ -348,10 +624,162 @@ func (s *TaskService) validateTask(task *models.Task) error {
 		return fmt.Errorf("任务名称不能为空")
 	}
 
-	if task.Count <= 0 {
+	if task.Count <= 0 && task.Type != models.TaskTypeSubset && task.Type != models.TaskTypeMask {
 		return fmt.Errorf("生成数量必须大于0")
 	}
 
+	if task.OutputType == models.OutputTypeDatabase {
+		var insertOptions InsertOptions
+		if err := task.GetConfiguration(&insertOptions); err != nil {
+			return fmt.Errorf("解析写入配置失败: %v", err)
+		}
+		if err := validateInsertOptions(insertOptions.withDefaults()); err != nil {
+			return err
+		}
+	}
+
+	switch task.OutputType {
+	case models.OutputTypeParquet, models.OutputTypeAvro, models.OutputTypeProtobuf:
+		if task.OutputPath == "" {
+			return fmt.Errorf("%s 输出必须指定输出路径", task.OutputType)
+		}
+		if task.Type != models.TaskTypeDatabase && task.Type != models.TaskTypeJSON && task.Type != models.TaskTypeCSV {
+			return fmt.Errorf("%s 任务不支持 %s 输出", task.Type, task.OutputType)
+		}
+	case models.OutputTypeXLSX:
+		if task.OutputPath == "" {
+			return fmt.Errorf("%s 输出必须指定输出路径", task.OutputType)
+		}
+	case models.OutputTypeXML, models.OutputTypeYAML:
+		if task.Type != models.TaskTypeJSON {
+			return fmt.Errorf("%s 任务不支持 %s 输出", task.Type, task.OutputType)
+		}
+	case models.OutputTypeJSONL:
+		if task.Type != models.TaskTypeJSON {
+			return fmt.Errorf("%s 任务不支持 %s 输出", task.Type, task.OutputType)
+		}
+		var jsonOptions JSONOptions
+		if err := task.GetConfiguration(&jsonOptions); err != nil {
+			return fmt.Errorf("解析JSON配置失败: %v", err)
+		}
+		if jsonOptions.Pretty != nil && *jsonOptions.Pretty {
+			return fmt.Errorf("jsonl 输出每行一个对象，不支持 pretty")
+		}
+	case models.OutputTypeTemplate:
+		if task.OutputPath == "" {
+			return fmt.Errorf("%s 输出必须指定输出路径", task.OutputType)
+		}
+		if task.Type != models.TaskTypeDatabase && task.Type != models.TaskTypeJSON && task.Type != models.TaskTypeCSV {
+			return fmt.Errorf("%s 任务不支持 %s 输出", task.Type, task.OutputType)
+		}
+		var templateOptions TemplateOptions
+		if err := task.GetConfiguration(&templateOptions); err != nil {
+			return fmt.Errorf("解析模板配置失败: %v", err)
+		}
+		if err := templateOptions.validate(); err != nil {
+			return err
+		}
+	case models.OutputTypeMockAPI:
+		if task.Type != models.TaskTypeDatabase && task.Type != models.TaskTypeJSON && task.Type != models.TaskTypeCSV {
+			return fmt.Errorf("%s 任务不支持 %s 输出", task.Type, task.OutputType)
+		}
+		var mockOptions MockAPIOptions
+		if err := task.GetConfiguration(&mockOptions); err != nil {
+			return fmt.Errorf("解析Mock接口配置失败: %v", err)
+		}
+		if err := mockOptions.validate(); err != nil {
+			return err
+		}
+	case models.OutputTypeHTTP:
+		if task.Type != models.TaskTypeDatabase && task.Type != models.TaskTypeJSON && task.Type != models.TaskTypeCSV {
+			return fmt.Errorf("%s 任务不支持 %s 输出", task.Type, task.OutputType)
+		}
+		var httpOptions HTTPOptions
+		if err := task.GetConfiguration(&httpOptions); err != nil {
+			return fmt.Errorf("解析HTTP配置失败: %v", err)
+		}
+		if err := httpOptions.validate(); err != nil {
+			return err
+		}
+	case models.OutputTypeKafka:
+		if task.Type != models.TaskTypeDatabase && task.Type != models.TaskTypeJSON && task.Type != models.TaskTypeCSV {
+			return fmt.Errorf("%s 任务不支持 %s 输出", task.Type, task.OutputType)
+		}
+		var kafkaOptions KafkaOptions
+		if err := task.GetConfiguration(&kafkaOptions); err != nil {
+			return fmt.Errorf("解析Kafka配置失败: %v", err)
+		}
+		if err := kafkaOptions.validate(); err != nil {
+			return err
+		}
+		if kafkaOptions.format() == "template" {
+			var templateOptions TemplateOptions
+			if err := task.GetConfiguration(&templateOptions); err != nil {
+				return fmt.Errorf("解析模板配置失败: %v", err)
+			}
+			if err := templateOptions.validate(); err != nil {
+				return err
+			}
+		}
+	case models.OutputTypeDatabase, models.OutputTypeSQL, models.OutputTypeJSON, models.OutputTypeTXT, models.OutputTypeCSV, models.OutputTypeMockServer:
+	case "":
+		// 未指定输出类型的旧CSV任务按CSV输出
+		if task.Type != models.TaskTypeCSV {
+			return fmt.Errorf("必须指定输出类型")
+		}
+	default:
+		return fmt.Errorf("不支持的输出类型: %s", task.OutputType)
+	}
+
+	switch task.OutputType {
+	case models.OutputTypeParquet:
+		var parquetOptions ParquetOptions
+		if err := task.GetConfiguration(&parquetOptions); err != nil {
+			return fmt.Errorf("解析Parquet配置失败: %v", err)
+		}
+		if _, err := parquetOptions.codec(); err != nil {
+			return err
+		}
+	case models.OutputTypeAvro:
+		var avroOptions AvroOptions
+		if err := task.GetConfiguration(&avroOptions); err != nil {
+			return fmt.Errorf("解析Avro配置失败: %v", err)
+		}
+		if _, err := avroOptions.codec(); err != nil {
+			return err
+		}
+	case models.OutputTypeProtobuf:
+		var protoOptions ProtobufOptions
+		if err := task.GetConfiguration(&protoOptions); err != nil {
+			return fmt.Errorf("解析Protobuf配置失败: %v", err)
+		}
+		if protoOptions.ProtoFile == "" {
+			return fmt.Errorf("Protobuf输出必须指定 .proto 文件")
+		}
+	}
+
+	if task.OutputType == models.OutputTypeSQL {
+		var sqlOptions SQLOptions
+		if err := task.GetConfiguration(&sqlOptions); err != nil {
+			return fmt.Errorf("解析SQL导出配置失败: %v", err)
+		}
+		if _, err := sqlOptions.dialect(); err != nil {
+			return err
+		}
+	}
+
+	// 压缩、分片和清单只适用于逐条编码的文件输出，子集任务的SQL文件按表顺序写出，不支持分片
+	var outputOptions OutputOptions
+	if err := task.GetConfiguration(&outputOptions); err != nil {
+		return fmt.Errorf("解析文件输出配置失败: %v", err)
+	}
+	if err := outputOptions.validate(); err != nil {
+		return err
+	}
+	if outputOptions.enabled() && (!isFileOutput(task.OutputType) || task.Type == models.TaskTypeSubset) {
+		return fmt.Errorf("%s 输出不支持压缩和分片", task.OutputType)
+	}
+
 	switch task.Type {
 	case models.TaskTypeDatabase:
 		if task.DataSourceID == nil {
@@ -364,16 +792,68 @@ func (s *TaskService) validateTask(task *models.Task) error {
 		if task.JSONSchema == "" {
 			return fmt.Errorf("JSON任务必须指定JSON结构")
 		}
-		if task.OutputPath == "" && task.OutputType != models.OutputTypeMockServer {
+		if task.OutputPath == "" && !isStreamOutput(task.OutputType) {
 			return fmt.Errorf("JSON任务必须指定输出路径")
 		}
 	case models.TaskTypeCSV:
 		if task.JSONSchema == "" {
 			return fmt.Errorf("CSV任务必须指定列结构")
 		}
-		if task.OutputPath == "" && task.OutputType != models.OutputTypeMockServer {
+		if task.OutputPath == "" && !isStreamOutput(task.OutputType) {
 			return fmt.Errorf("CSV任务必须指定输出路径")
 		}
+	case models.TaskTypeSubset:
+		if task.DataSourceID == nil {
+			return fmt.Errorf("子集任务必须指定源数据源")
+		}
+		if task.TableName == "" {
+			return fmt.Errorf("子集任务必须指定根表")
+		}
+		var config SubsetConfig
+		if err := task.GetConfiguration(&config); err != nil {
+			return fmt.Errorf("解析子集配置失败: %v", err)
+		}
+		if config.SamplePercent < 0 || config.SamplePercent > 100 {
+			return fmt.Errorf("抽样百分比必须在0到100之间")
+		}
+		switch task.OutputType {
+		case models.OutputTypeDatabase:
+			if config.TargetDataSourceID == 0 {
+				return fmt.Errorf("子集任务输出到数据库时必须指定目标数据源")
+			}
+		case models.OutputTypeSQL, models.OutputTypeXLSX:
+			if task.OutputPath == "" {
+				return fmt.Errorf("子集任务必须指定输出路径")
+			}
+		default:
+			return fmt.Errorf("子集任务不支持的输出类型: %s", task.OutputType)
+		}
+	case models.TaskTypeMask:
+		if task.DataSourceID == nil {
+			return fmt.Errorf("脱敏任务必须指定源数据源")
+		}
+		if task.TableName == "" {
+			return fmt.Errorf("脱敏任务必须指定源表")
+		}
+		var config MaskConfig
+		if err := task.GetConfiguration(&config); err != nil {
+			return fmt.Errorf("解析脱敏配置失败: %v", err)
+		}
+		if config.Salt == "" {
+			return fmt.Errorf("脱敏任务必须指定盐值")
+		}
+		switch task.OutputType {
+		case models.OutputTypeDatabase:
+			if config.TargetDataSourceID == 0 {
+				return fmt.Errorf("脱敏任务输出到数据库时必须指定目标数据源")
+			}
+		case models.OutputTypeSQL, models.OutputTypeCSV, models.OutputTypeXLSX:
+			if task.OutputPath == "" {
+				return fmt.Errorf("脱敏任务必须指定输出路径")
+			}
+		default:
+			return fmt.Errorf("脱敏任务不支持的输出类型: %s", task.OutputType)
+		}
 	default:
 		return fmt.Errorf("不支持的任务类型: %s", task.Type)
 	}
@@ -391,11 +871,50 @@ func (s *TaskService) updateTaskStatus(taskID uint, status models.TaskStatus, pr
 	models.DB.Model(&models.Task{}).Where("id = ?", taskID).Updates(updates)
 }
 
-// 更新任务进度
-func (s *TaskService) updateTaskProgress(taskID uint, progress float64) {
+// 更新任务进度，rows 为已输出的行数
+func (s *TaskService) updateTaskProgress(taskID uint, progress float64, rows int64) {
+	if s.progress != nil {
+		s.progress(progress)
+		return
+	}
+	if s.reporter != nil {
+		s.reporter.progress(progress, rows)
+		return
+	}
 	models.DB.Model(&models.Task{}).Where("id = ?", taskID).Update("progress", progress)
 }
 
+// 进入新的执行阶段
+func (s *TaskService) setPhase(phase string) {
+	if s.reporter != nil && s.reporter.phase != phase {
+		s.logger.info("进入阶段: "+phase, nil)
+		s.reporter.setPhase(phase)
+	}
+}
+
+// 在执行日志中记录一批数据的行数和耗时（包含生成和输出）
+func (s *TaskService) logBatch(rows, total int64, started time.Time) {
+	s.logger.debug(fmt.Sprintf("批次完成: %d 行", rows), map[string]interface{}{
+		"rows":       rows,
+		"total":      total,
+		"durationMs": time.Since(started).Milliseconds(),
+	})
+}
+
+// 在执行日志中记录执行结果
+func logRunResult(logger *runLogger, result *models.TaskResult) {
+	if result.RejectedCount > maxRejectedRowLogs {
+		logger.warn(fmt.Sprintf("共 %d 行被拒绝，仅记录了前 %d 行", result.RejectedCount, maxRejectedRowLogs), nil)
+	}
+	logger.info(fmt.Sprintf("执行完成，耗时: %v，共 %d 行，%.0f 行/秒", result.Duration, result.GeneratedCount, result.RowsPerSecond), map[string]interface{}{
+		"generated":     result.GeneratedCount,
+		"rejected":      result.RejectedCount,
+		"durationMs":    result.Duration.Milliseconds(),
+		"rowsPerSecond": result.RowsPerSecond,
+		"files":         result.Files,
+	})
+}
+
 // 获取任务状态
 func (s *TaskService) GetTaskStatus(taskID uint) (*models.Task, error) {
 	var task models.Task
@@ -410,8 +929,8 @@ func (s *TaskService) UpdateTask(task *models.Task) error {
 		return err
 	}
 
-	// 更新数据库中的任务
-	return models.DB.Save(task).Error
+	// 更新数据库中的任务，执行状态和结果由执行过程维护
+	return models.DB.Omit("status", "progress", "error_msg", "result", "completed_at").Save(task).Error
 }
 
 // 导出任务规则模板
@@ -571,7 +1090,7 @@ func (s *TaskService) generateJSONPreview(task *models.Task, fieldRules map[stri
 }
 
 // 执行CSV任务
-func (s *TaskService) executeCSVTask(task *models.Task) error {
+func (s *TaskService) executeCSVTask(task *models.Task, result *models.TaskResult) error {
 	// 解析列结构 (复用JSONSchema字段存储列信息)
 	// 格式: [{"name": "col1", "type": "string"}, ...]
 	var columns []models.ColumnInfo
@@ -598,7 +1117,7 @@ func (s *TaskService) executeCSVTask(task *models.Task) error {
 	}
 
 	// 为每个任务创建独立的生成器实例
-	generatorService := NewGeneratorService(s.dbService)
+	generatorService := s.newGenerator()
 
 	// 构造 TableInfo 供生成器使用
 	tableInfo := &models.TableInfo{
@@ -606,56 +1125,66 @@ func (s *TaskService) executeCSVTask(task *models.Task) error {
 		Columns:   columns,
 	}
 
+	// 未指定输出类型的旧任务默认为CSV
+	if task.OutputType == "" {
+		task.OutputType = models.OutputTypeCSV
+	}
+	writer, err := s.openWriter(task, &writerTarget{
+		name:      task.Name,
+		tableInfo: tableInfo,
+		headers:   headers,
+		schema:    RecordSchemaFromTable(tableInfo),
+	})
+	if err != nil {
+		return err
+	}
+
 	// 分批生成数据
+	s.setPhase(PhaseGenerating)
 	batchSize := int64(5000) // CSV每批5000条
 	var generated int64
 
-	for generated < task.Count {
-		currentBatch := batchSize
-		if generated+batchSize > task.Count {
-			currentBatch = task.Count - generated
-		}
-
-		// 生成一批数据
-		records := make([]map[string]interface{}, currentBatch)
-		for i := int64(0); i < currentBatch; i++ {
-			// 构造上下文
-			context := map[string]interface{}{
-				"rowIndex":   generated + i,
-				"dataSource": task.DataSource,
+	err = writeAll(writer, result, func(write func([]map[string]interface{}) error) error {
+		for generated < task.Count {
+			batchStart := time.Now()
+			currentBatch := batchSize
+			if generated+batchSize > task.Count {
+				currentBatch = task.Count - generated
 			}
 
-			record, err := generatorService.GenerateRecord(tableInfo, rules, uniqueFields, context)
-			if err != nil {
-				return fmt.Errorf("生成记录失败: %v", err)
+			// 生成一批数据
+			records := make([]map[string]interface{}, currentBatch)
+			for i := int64(0); i < currentBatch; i++ {
+				// 构造上下文
+				context := map[string]interface{}{
+					"rowIndex":   generated + i,
+					"dataSource": task.DataSource,
+				}
+
+				record, err := generatorService.GenerateRecord(tableInfo, rules, uniqueFields, context)
+				if err != nil {
+					return fmt.Errorf("生成记录失败: %w", err)
+				}
+				records[i] = record
 			}
-			records[i] = record
-		}
 
-		// 导出数据
-		switch task.OutputType {
-		case models.OutputTypeCSV:
-			err = s.exportService.ExportToCSV(task.OutputPath, headers, records, generated == 0)
-			if err != nil {
-				return fmt.Errorf("导出CSV失败: %v", err)
-			}
-		case models.OutputTypeMockServer:
-			err = s.pushToMockServer(task, records)
-			if err != nil {
-				return fmt.Errorf("推送至Mock Server失败: %v", err)
+			// 导出数据
+			if err := write(records); err != nil {
+				return err
 			}
-		default:
-			// 默认为CSV (兼容旧数据)
-			err = s.exportService.ExportToCSV(task.OutputPath, headers, records, generated == 0)
-			if err != nil {
-				return fmt.Errorf("导出CSV失败: %v", err)
-			}
-		}
 
-		generated += currentBatch
-		progress := math.Round(float64(generated) / float64(task.Count) * 100)
-		s.updateTaskProgress(task.ID, progress)
+			generated += currentBatch
+			s.logBatch(currentBatch, generated, batchStart)
+			progress := math.Round(float64(generated) / float64(task.Count) * 100)
+			s.updateTaskProgress(task.ID, progress, generated)
+		}
+		s.setPhase(PhaseWriting)
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
+	result.GeneratedCount = generated
 	return nil
 }
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"generateTestData/backend/models"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 任务定义文件（YAML或JSON），字段与创建任务接口一致。
+// jsonSchema、fieldRules、uniqueFields、configuration 既可以写成JSON字符串，也可以直接写成YAML结构。
+type TaskFile struct {
+	Name          string              `yaml:"name"`
+	Type          models.TaskType     `yaml:"type"`
+	DataSourceID  *uint               `yaml:"dataSourceId"` // 引用 dataSources 中的数据源
+	DataSource    *models.DataSource  `yaml:"dataSource"`   // 直接定义任务使用的数据源
+	DataSources   []models.DataSource `yaml:"dataSources"`  // 按ID引用的数据源，如子集、脱敏任务的目标库和 db_lookup 规则
+	TableName     string              `yaml:"tableName"`
+	JSONSchema    interface{}         `yaml:"jsonSchema"`
+	FieldRules    interface{}         `yaml:"fieldRules"`
+	UniqueFields  interface{}         `yaml:"uniqueFields"`
+	Count         int64               `yaml:"count"`
+	OutputType    models.OutputType   `yaml:"outputType"`
+	OutputPath    string              `yaml:"outputPath"`
+	Configuration interface{}         `yaml:"configuration"`
+}
+
+// 读取任务定义文件，JSON是YAML的子集，两种格式使用同一解析器
+func LoadTaskFile(path string) (*TaskFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取任务文件失败: %v", err)
+	}
+	var file TaskFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("解析任务文件失败: %v", err)
+	}
+	if file.Name == "" {
+		file.Name = path
+	}
+	return &file, nil
+}
+
+// 转换为任务，数据源保存到 models.DB 以便按ID引用
+func (f *TaskFile) Task() (*models.Task, error) {
+	task := &models.Task{
+		Name:       f.Name,
+		Type:       f.Type,
+		TableName:  f.TableName,
+		Count:      f.Count,
+		OutputType: f.OutputType,
+		OutputPath: f.OutputPath,
+	}
+
+	fields := []struct {
+		name   string
+		value  interface{}
+		target *string
+	}{
+		{"jsonSchema", f.JSONSchema, &task.JSONSchema},
+		{"fieldRules", f.FieldRules, &task.FieldRules},
+		{"uniqueFields", f.UniqueFields, &task.UniqueFields},
+		{"configuration", f.Configuration, &task.Configuration},
+	}
+	for _, field := range fields {
+		text, err := jsonText(field.value)
+		if err != nil {
+			return nil, fmt.Errorf("解析 %s 失败: %v", field.name, err)
+		}
+		*field.target = text
+	}
+
+	for i := range f.DataSources {
+		if err := models.DB.Save(&f.DataSources[i]).Error; err != nil {
+			return nil, fmt.Errorf("保存数据源失败: %v", err)
+		}
+	}
+	switch {
+	case f.DataSource != nil:
+		if err := models.DB.Save(f.DataSource).Error; err != nil {
+			return nil, fmt.Errorf("保存数据源失败: %v", err)
+		}
+		task.DataSourceID = &f.DataSource.ID
+		task.DataSource = f.DataSource
+	case f.DataSourceID != nil:
+		var dataSource models.DataSource
+		if err := models.DB.First(&dataSource, *f.DataSourceID).Error; err != nil {
+			return nil, fmt.Errorf("数据源 %d 不存在", *f.DataSourceID)
+		}
+		task.DataSourceID = f.DataSourceID
+		task.DataSource = &dataSource
+	}
+	return task, nil
+}
+
+// 字符串原样使用，其他结构序列化为JSON
+func jsonText(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
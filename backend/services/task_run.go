@@ -0,0 +1,109 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"generateTestData/backend/config"
+	"generateTestData/backend/models"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// 获取任务的执行记录，最近的在前
+func (s *TaskService) GetTaskRuns(taskID uint) ([]models.TaskRun, error) {
+	var runs []models.TaskRun
+	err := models.DB.Where("task_id = ?", taskID).Order("id DESC").Find(&runs).Error
+	return runs, err
+}
+
+// 获取任务的一次执行记录
+func (s *TaskService) GetTaskRun(taskID, runID uint) (*models.TaskRun, error) {
+	var run models.TaskRun
+	if err := models.DB.Where("task_id = ?", taskID).First(&run, runID).Error; err != nil {
+		return nil, fmt.Errorf("执行记录不存在")
+	}
+	return &run, nil
+}
+
+// 按历史执行的配置快照和随机种子重新执行，任务当前的配置不变
+func (s *TaskService) RerunTask(taskID, runID uint, trigger models.RunTrigger) (*models.TaskRun, error) {
+	past, err := s.GetTaskRun(taskID, runID)
+	if err != nil {
+		return nil, err
+	}
+	task, err := snapshotTask(past)
+	if err != nil {
+		return nil, err
+	}
+	return s.enqueue(task, trigger, past.Seed, &past.ID)
+}
+
+// 还原执行记录中的任务配置，数据源按ID重新加载
+func snapshotTask(run *models.TaskRun) (*models.Task, error) {
+	task, err := run.GetSnapshot()
+	if err != nil {
+		return nil, fmt.Errorf("解析配置快照失败: %v", err)
+	}
+	if task.DataSourceID != nil {
+		var dataSource models.DataSource
+		if err := models.DB.First(&dataSource, *task.DataSourceID).Error; err != nil {
+			return nil, fmt.Errorf("数据源不存在")
+		}
+		task.DataSource = &dataSource
+	}
+	return task, nil
+}
+
+// 记录执行结果并推送结果事件，成功时记录输出文件的大小和校验和
+func (s *TaskService) finishRun(run *models.TaskRun, result *models.TaskResult, err error) {
+	now := time.Now()
+	run.FinishedAt = &now
+	if err != nil {
+		run.Status = models.TaskStatusFailed
+		run.ErrorMsg = err.Error()
+	} else {
+		run.Status = models.TaskStatusCompleted
+		run.GeneratedCount = result.GeneratedCount
+		run.RejectedCount = result.RejectedCount
+		run.RowsPerSecond = result.RowsPerSecond
+		if data, err := json.Marshal(runFiles(result.Files)); err == nil {
+			run.Files = string(data)
+		}
+	}
+	models.DB.Model(run).Select("status", "error_msg", "generated_count", "rejected_count", "rows_per_second", "files", "finished_at").Updates(run)
+
+	event := TaskEvent{Type: TaskEventResult, TaskID: run.TaskID, RunID: run.ID, Status: run.Status, Message: run.ErrorMsg}
+	if err == nil {
+		if run.RejectedCount > 0 {
+			publishTaskEvent(TaskEvent{Type: TaskEventWarning, TaskID: run.TaskID, RunID: run.ID, Message: fmt.Sprintf("%d 行被目标数据库拒绝", run.RejectedCount)})
+		}
+		event.Progress = 100
+		event.Rows = run.GeneratedCount
+		event.RowsPerSecond = run.RowsPerSecond
+		event.Result = result
+	}
+	publishTaskEvent(event)
+}
+
+// 统计生成目录下输出文件的大小和SHA-256校验和，不存在的文件被忽略
+func runFiles(names []string) []models.RunFile {
+	files := make([]models.RunFile, 0, len(names))
+	for _, name := range names {
+		file, err := os.Open(filepath.Join(config.AppConfig.GenerateDir, name))
+		if err != nil {
+			continue
+		}
+		hash := sha256.New()
+		size, err := io.Copy(hash, file)
+		file.Close()
+		if err != nil {
+			continue
+		}
+		files = append(files, models.RunFile{Name: name, Size: size, SHA256: hex.EncodeToString(hash.Sum(nil))})
+	}
+	return files
+}
@@ -0,0 +1,277 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+)
+
+// 模板导出选项（存储在 Task.Configuration 中）
+type TemplateOptions struct {
+	Header     string `json:"templateHeader"`     // 文件头模板，开始时渲染一次
+	Row        string `json:"templateRow"`        // 行模板，每条记录渲染一次
+	Footer     string `json:"templateFooter"`     // 文件尾模板，结束时渲染一次
+	LineEnding string `json:"templateLineEnding"` // 每行之后追加的换行符，默认 \n，可设为 \r\n 或 \r
+	Extension  string `json:"templateExtension"`  // 输出文件后缀，默认沿用输出路径的后缀，没有时为 txt
+}
+
+// 文件头和文件尾模板的数据
+type templateSummary struct {
+	Rows int64 // 当前文件已写入的记录数，文件头中为0
+}
+
+// 解析后的模板
+type templateSet struct {
+	header *template.Template
+	row    *template.Template
+	footer *template.Template
+}
+
+// 解析模板，rowNumber 返回当前记录的行号
+func (o *TemplateOptions) parse(rowNumber func() int64) (*templateSet, error) {
+	if o == nil || strings.TrimSpace(o.Row) == "" {
+		return nil, fmt.Errorf("模板输出必须指定行模板")
+	}
+	funcs := templateFuncs(rowNumber)
+	parse := func(name, text string) (*template.Template, error) {
+		if text == "" {
+			return nil, nil
+		}
+		// 记录中不存在的字段直接报错，避免拼写错误时输出 <no value>
+		t, err := template.New(name).Funcs(funcs).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("解析%s模板失败: %v", name, err)
+		}
+		return t, nil
+	}
+
+	set := &templateSet{}
+	var err error
+	if set.header, err = parse("header", o.Header); err != nil {
+		return nil, err
+	}
+	if set.row, err = parse("row", o.Row); err != nil {
+		return nil, err
+	}
+	if set.footer, err = parse("footer", o.Footer); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// 校验模板语法
+func (o *TemplateOptions) validate() error {
+	_, err := o.parse(func() int64 { return 0 })
+	return err
+}
+
+// 创建模板输出文件
+func (s *ExportService) NewTemplateWriter(fileName string, options *TemplateOptions) (*FileWriter, error) {
+	encoder, err := newTemplateEncoder(fileName, options)
+	if err != nil {
+		return nil, err
+	}
+	return newFileWriter(fileName, encoder, nil)
+}
+
+// 模板编码器，每个分片都渲染文件头和文件尾
+type templateEncoder struct {
+	templates  *templateSet
+	lineEnding string
+	ext        string
+	rows       int64 // 已渲染的记录数，用于 rowNumber
+	partRows   int64 // 当前分片已渲染的记录数
+}
+
+func newTemplateEncoder(fileName string, options *TemplateOptions) (*templateEncoder, error) {
+	e := &templateEncoder{lineEnding: "\n"}
+	templates, err := options.parse(func() int64 { return e.rows + 1 })
+	if err != nil {
+		return nil, err
+	}
+	e.templates = templates
+	if options.LineEnding != "" {
+		e.lineEnding = options.LineEnding
+	}
+
+	// 文件后缀：优先使用配置，其次沿用输出路径的后缀
+	e.ext = strings.TrimPrefix(options.Extension, ".")
+	if e.ext == "" {
+		e.ext = strings.TrimPrefix(filepath.Ext(fileName), ".")
+	}
+	if e.ext == "" {
+		e.ext = "txt"
+	}
+	return e, nil
+}
+
+func (e *templateEncoder) extension() string { return e.ext }
+
+func (e *templateEncoder) begin(w io.Writer, part int) error {
+	e.partRows = 0
+	if e.templates.header != nil {
+		if err := e.templates.header.Execute(w, templateSummary{}); err != nil {
+			return fmt.Errorf("渲染文件头失败: %v", err)
+		}
+	}
+	return nil
+}
+
+func (e *templateEncoder) encode(w io.Writer, records []map[string]interface{}) error {
+	for _, record := range records {
+		if err := e.templates.row.Execute(w, record); err != nil {
+			return fmt.Errorf("渲染第%d条记录失败: %v", e.rows+1, err)
+		}
+		if _, err := io.WriteString(w, e.lineEnding); err != nil {
+			return fmt.Errorf("写入文件失败: %v", err)
+		}
+		e.rows++
+		e.partRows++
+	}
+	return nil
+}
+
+func (e *templateEncoder) end(w io.Writer) error {
+	if e.templates.footer != nil {
+		if err := e.templates.footer.Execute(w, templateSummary{Rows: e.partRows}); err != nil {
+			return fmt.Errorf("渲染文件尾失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// 解析单个值的模板（如消息头、URL），可使用全部辅助函数，rowNumber 固定为0
+func parseValueTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(templateFuncs(func() int64 { return 0 })).Option("missingkey=error").Parse(text)
+}
+
+// 模板辅助函数，宽度按字符数计算；值为最后一个参数，便于在管道中使用，如 {{.name | rpad 10}}
+func templateFuncs(rowNumber func() int64) template.FuncMap {
+	return template.FuncMap{
+		// 填充与截断
+		"lpad": func(width int, value interface{}) string {
+			return padText(templateText(value), width, ' ', true)
+		},
+		"rpad": func(width int, value interface{}) string {
+			return padText(templateText(value), width, ' ', false)
+		},
+		"zpad": func(width int, value interface{}) string {
+			text := templateText(value)
+			if strings.HasPrefix(text, "-") {
+				return "-" + padText(text[1:], width-1, '0', true)
+			}
+			return padText(text, width, '0', true)
+		},
+		"truncate": func(width int, value interface{}) string {
+			return truncateText(templateText(value), width)
+		},
+		// 定宽字段：左对齐，超出部分截断
+		"fixed": func(width int, value interface{}) string {
+			return padText(truncateText(templateText(value), width), width, ' ', false)
+		},
+
+		// 转义
+		"sqlEscape": func(value interface{}) string {
+			return strings.ReplaceAll(templateText(value), "'", "''")
+		},
+		"xmlEscape": func(value interface{}) string {
+			return html.EscapeString(templateText(value))
+		},
+		"csvEscape": func(value interface{}) string {
+			text := templateText(value)
+			if strings.ContainsAny(text, ",\"\r\n") {
+				return `"` + strings.ReplaceAll(text, `"`, `""`) + `"`
+			}
+			return text
+		},
+		// HL7 v2 分隔符转义
+		"hl7Escape": func(value interface{}) string {
+			return strings.NewReplacer(`\`, `\E\`, "|", `\F\`, "^", `\S\`, "~", `\R\`, "&", `\T\`).
+				Replace(templateText(value))
+		},
+		"json": func(value interface{}) (string, error) {
+			data, err := json.Marshal(value)
+			return string(data), err
+		},
+
+		// 格式化
+		"upper": func(value interface{}) string { return strings.ToUpper(templateText(value)) },
+		"lower": func(value interface{}) string { return strings.ToLower(templateText(value)) },
+		"trim":  func(value interface{}) string { return strings.TrimSpace(templateText(value)) },
+		"date": func(layout string, value interface{}) (string, error) {
+			if value == nil {
+				return "", nil
+			}
+			t, err := toTime(value)
+			if err != nil {
+				return "", err
+			}
+			return t.Format(layout), nil
+		},
+		"number": func(decimals int, value interface{}) (string, error) {
+			if value == nil {
+				return "", nil
+			}
+			f, err := toFloat64(value)
+			if err != nil {
+				return "", err
+			}
+			return strconv.FormatFloat(f, 'f', decimals, 64), nil
+		},
+		"default": func(fallback, value interface{}) interface{} {
+			if value == nil || value == "" {
+				return fallback
+			}
+			return value
+		},
+		"join": func(separator string, value interface{}) string {
+			items, ok := value.([]interface{})
+			if !ok {
+				return templateText(value)
+			}
+			texts := make([]string, len(items))
+			for i, item := range items {
+				texts[i] = templateText(item)
+			}
+			return strings.Join(texts, separator)
+		},
+		"now":       func(layout string) string { return time.Now().Format(layout) },
+		"rowNumber": rowNumber,
+	}
+}
+
+// 转换为文本，空值为空字符串
+func templateText(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return toText(value)
+}
+
+// 按字符数填充到指定宽度
+func padText(text string, width int, pad rune, left bool) string {
+	n := width - utf8.RuneCountInString(text)
+	if n <= 0 {
+		return text
+	}
+	padding := strings.Repeat(string(pad), n)
+	if left {
+		return padding + text
+	}
+	return text + padding
+}
+
+// 按字符数截断
+func truncateText(text string, width int) string {
+	if width < 0 || utf8.RuneCountInString(text) <= width {
+		return text
+	}
+	return string([]rune(text)[:width])
+}
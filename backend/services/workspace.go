@@ -0,0 +1,712 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"generateTestData/backend/models"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+// 工作区目录中的资源类型，每种资源一个子目录，每个资源一个YAML文件
+const (
+	WorkspaceTasks     = "tasks"
+	WorkspaceTemplates = "templates"
+	WorkspaceRules     = "rules"
+)
+
+var workspaceKinds = []string{WorkspaceTasks, WorkspaceTemplates, WorkspaceRules}
+
+// 工作区中的任务，数据源按名称引用，不包含ID、状态和执行结果
+type WorkspaceTask struct {
+	Name          string            `yaml:"name"`
+	Type          models.TaskType   `yaml:"type"`
+	DataSource    string            `yaml:"dataSource,omitempty"` // 数据源名称
+	TableName     string            `yaml:"tableName,omitempty"`
+	JSONSchema    interface{}       `yaml:"jsonSchema,omitempty"`
+	FieldRules    interface{}       `yaml:"fieldRules,omitempty"`
+	UniqueFields  interface{}       `yaml:"uniqueFields,omitempty"`
+	Count         int64             `yaml:"count,omitempty"`
+	OutputType    models.OutputType `yaml:"outputType,omitempty"`
+	OutputPath    string            `yaml:"outputPath,omitempty"`
+	Priority      int               `yaml:"priority,omitempty"`
+	Configuration interface{}       `yaml:"configuration,omitempty"`
+}
+
+// 工作区中的规则模板
+type WorkspaceTemplate struct {
+	Name        string          `yaml:"name"`
+	Description string          `yaml:"description,omitempty"`
+	Type        models.TaskType `yaml:"type"`
+	JSONSchema  interface{}     `yaml:"jsonSchema,omitempty"`
+	FieldRules  interface{}     `yaml:"fieldRules,omitempty"`
+}
+
+// 工作区中的规则库
+type WorkspaceRuleLibrary struct {
+	Name        string      `yaml:"name"`
+	Description string      `yaml:"description,omitempty"`
+	FieldRules  interface{} `yaml:"fieldRules,omitempty"`
+}
+
+// 同步工作区时的一项变更
+type WorkspaceChange struct {
+	Kind   string `json:"kind"`   // tasks, templates, rules
+	Name   string `json:"name"`   // 资源名称
+	Action string `json:"action"` // create, update, delete
+	Diff   string `json:"diff"`   // 以 +/- 标记的YAML差异
+}
+
+type WorkspaceService struct {
+	taskService *TaskService
+}
+
+func NewWorkspaceService() *WorkspaceService {
+	return &WorkspaceService{
+		taskService: NewTaskService(),
+	}
+}
+
+// 工作区中的一个资源
+type workspaceResource struct {
+	kind    string
+	name    string
+	content []byte      // 规范化的YAML，用于比较和生成差异
+	record  interface{} // 数据库中的记录，或按文件构造的待保存记录
+}
+
+// 把工作区导出到目录，目录中已有的资源文件会被替换，使目录与数据库一致
+func (s *WorkspaceService) Export(dir string) error {
+	refs, err := loadDataSourceRefs(models.DB)
+	if err != nil {
+		return err
+	}
+	resources, err := s.current(models.DB, refs)
+	if err != nil {
+		return err
+	}
+
+	for _, kind := range workspaceKinds {
+		if _, err := workspaceByName(kind, resources[kind]); err != nil {
+			return err
+		}
+	}
+
+	for _, kind := range workspaceKinds {
+		kindDir := filepath.Join(dir, kind)
+		if err := os.MkdirAll(kindDir, 0755); err != nil {
+			return fmt.Errorf("创建目录失败: %v", err)
+		}
+		files, err := workspaceFiles(kindDir)
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			if err := os.Remove(file); err != nil {
+				return fmt.Errorf("删除旧文件失败: %v", err)
+			}
+		}
+
+		used := make(map[string]bool)
+		for _, resource := range resources[kind] {
+			fileName := workspaceFileName(resource.name)
+			for i := 2; used[fileName]; i++ {
+				fileName = fmt.Sprintf("%s-%d", workspaceFileName(resource.name), i)
+			}
+			used[fileName] = true
+			if err := os.WriteFile(filepath.Join(kindDir, fileName+".yaml"), resource.content, 0644); err != nil {
+				return fmt.Errorf("写入文件失败: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// 按目录中的文件同步数据库：新增、更新或删除资源，使数据库与文件一致。
+// 目录中不存在的资源子目录不参与同步；dryRun 时只返回变更，不修改数据库。
+func (s *WorkspaceService) Apply(dir string, dryRun bool) ([]WorkspaceChange, error) {
+	var changes []WorkspaceChange
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		refs, err := loadDataSourceRefs(tx)
+		if err != nil {
+			return err
+		}
+		desired, err := s.load(dir, refs)
+		if err != nil {
+			return err
+		}
+		current, err := s.current(tx, refs)
+		if err != nil {
+			return err
+		}
+
+		for _, kind := range workspaceKinds {
+			wanted, managed := desired[kind]
+			if !managed {
+				continue
+			}
+			existing, err := workspaceByName(kind, current[kind])
+			if err != nil {
+				return err
+			}
+
+			for _, resource := range wanted {
+				old := existing[resource.name]
+				delete(existing, resource.name)
+				change := WorkspaceChange{Kind: kind, Name: resource.name}
+				switch {
+				case old == nil:
+					change.Action = "create"
+					change.Diff = lineDiff("", string(resource.content))
+				case !bytes.Equal(old.content, resource.content):
+					change.Action = "update"
+					change.Diff = lineDiff(string(old.content), string(resource.content))
+				default:
+					continue
+				}
+				changes = append(changes, change)
+				if !dryRun {
+					if err := applyWorkspaceChange(tx, old, resource); err != nil {
+						return fmt.Errorf("同步 %s/%s 失败: %v", kind, resource.name, err)
+					}
+				}
+			}
+
+			var removed []string
+			for name := range existing {
+				removed = append(removed, name)
+			}
+			sort.Strings(removed)
+			for _, name := range removed {
+				old := existing[name]
+				// 正在排队或执行的任务删除后执行仍会继续，预览时也直接报错
+				if task, ok := old.record.(*models.Task); ok && (task.Status == models.TaskStatusQueued || task.Status == models.TaskStatusRunning) {
+					return fmt.Errorf("任务 %s 正在排队或执行中，无法删除", name)
+				}
+				changes = append(changes, WorkspaceChange{
+					Kind:   kind,
+					Name:   name,
+					Action: "delete",
+					Diff:   lineDiff(string(old.content), ""),
+				})
+				if !dryRun {
+					if err := deleteWorkspaceResource(tx, old.record); err != nil {
+						return fmt.Errorf("删除 %s/%s 失败: %v", kind, name, err)
+					}
+				}
+			}
+		}
+		return nil
+	})
+	return changes, err
+}
+
+// 删除资源，任务的执行记录、执行日志和定时调度一并删除
+func deleteWorkspaceResource(tx *gorm.DB, record interface{}) error {
+	if task, ok := record.(*models.Task); ok {
+		return deleteTask(tx, task.ID)
+	}
+	return tx.Delete(record).Error
+}
+
+// 按名称索引数据库中的资源，工作区按名称同步，名称必须唯一
+func workspaceByName(kind string, resources []*workspaceResource) (map[string]*workspaceResource, error) {
+	byName := make(map[string]*workspaceResource)
+	for _, resource := range resources {
+		if byName[resource.name] != nil {
+			return nil, fmt.Errorf("%s 中存在多个名为 %s 的资源，请先重命名", kind, resource.name)
+		}
+		byName[resource.name] = resource
+	}
+	return byName, nil
+}
+
+// 读取数据库中的资源，按类型和名称排序
+func (s *WorkspaceService) current(db *gorm.DB, refs *dataSourceRefs) (map[string][]*workspaceResource, error) {
+	resources := make(map[string][]*workspaceResource)
+
+	var tasks []models.Task
+	if err := db.Order("name, id").Find(&tasks).Error; err != nil {
+		return nil, fmt.Errorf("获取任务失败: %v", err)
+	}
+	for i := range tasks {
+		resource, err := newWorkspaceResource(WorkspaceTasks, &tasks[i], refs)
+		if err != nil {
+			return nil, err
+		}
+		resources[WorkspaceTasks] = append(resources[WorkspaceTasks], resource)
+	}
+
+	var templates []models.TaskTemplate
+	if err := db.Order("name, id").Find(&templates).Error; err != nil {
+		return nil, fmt.Errorf("获取模板失败: %v", err)
+	}
+	for i := range templates {
+		resource, err := newWorkspaceResource(WorkspaceTemplates, &templates[i], refs)
+		if err != nil {
+			return nil, err
+		}
+		resources[WorkspaceTemplates] = append(resources[WorkspaceTemplates], resource)
+	}
+
+	var libraries []models.RuleLibrary
+	if err := db.Order("name, id").Find(&libraries).Error; err != nil {
+		return nil, fmt.Errorf("获取规则库失败: %v", err)
+	}
+	for i := range libraries {
+		resource, err := newWorkspaceResource(WorkspaceRules, &libraries[i], refs)
+		if err != nil {
+			return nil, err
+		}
+		resources[WorkspaceRules] = append(resources[WorkspaceRules], resource)
+	}
+	return resources, nil
+}
+
+// 读取目录中的资源文件并转换为待保存的记录，只包含存在的资源子目录
+func (s *WorkspaceService) load(dir string, refs *dataSourceRefs) (map[string][]*workspaceResource, error) {
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("工作区目录不存在: %s", dir)
+	}
+
+	resources := make(map[string][]*workspaceResource)
+	for _, kind := range workspaceKinds {
+		kindDir := filepath.Join(dir, kind)
+		if _, err := os.Stat(kindDir); os.IsNotExist(err) {
+			continue
+		}
+		files, err := workspaceFiles(kindDir)
+		if err != nil {
+			return nil, err
+		}
+
+		resources[kind] = []*workspaceResource{}
+		seen := make(map[string]string)
+		for _, file := range files {
+			record, err := s.decodeFile(kind, file, refs)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", file, err)
+			}
+			resource, err := newWorkspaceResource(kind, record, refs)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", file, err)
+			}
+			if previous, ok := seen[resource.name]; ok {
+				return nil, fmt.Errorf("%s 与 %s 的名称重复: %s", file, previous, resource.name)
+			}
+			seen[resource.name] = file
+			resources[kind] = append(resources[kind], resource)
+		}
+		sort.Slice(resources[kind], func(i, j int) bool {
+			return resources[kind][i].name < resources[kind][j].name
+		})
+	}
+	return resources, nil
+}
+
+// 解析资源文件为数据库记录，未知字段视为错误
+func (s *WorkspaceService) decodeFile(kind, file string, refs *dataSourceRefs) (interface{}, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("读取文件失败: %v", err)
+	}
+	decode := func(v interface{}) error {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(v); err != nil {
+			return fmt.Errorf("解析文件失败: %v", err)
+		}
+		return nil
+	}
+
+	switch kind {
+	case WorkspaceTasks:
+		var file WorkspaceTask
+		if err := decode(&file); err != nil {
+			return nil, err
+		}
+		task := &models.Task{
+			Name:       file.Name,
+			Type:       file.Type,
+			TableName:  file.TableName,
+			Count:      file.Count,
+			OutputType: file.OutputType,
+			OutputPath: file.OutputPath,
+			Priority:   file.Priority,
+		}
+		if file.DataSource != "" {
+			id, err := refs.id(file.DataSource)
+			if err != nil {
+				return nil, err
+			}
+			task.DataSourceID = &id
+		}
+		if err := workspaceFields(refs, []workspaceField{
+			{"jsonSchema", file.JSONSchema, &task.JSONSchema, false},
+			{"fieldRules", file.FieldRules, &task.FieldRules, true},
+			{"uniqueFields", file.UniqueFields, &task.UniqueFields, false},
+			{"configuration", file.Configuration, &task.Configuration, true},
+		}); err != nil {
+			return nil, err
+		}
+		if err := s.taskService.validateTask(task); err != nil {
+			return nil, err
+		}
+		return task, nil
+	case WorkspaceTemplates:
+		var file WorkspaceTemplate
+		if err := decode(&file); err != nil {
+			return nil, err
+		}
+		if file.Name == "" || file.Type == "" {
+			return nil, fmt.Errorf("模板名称和类型不能为空")
+		}
+		template := &models.TaskTemplate{Name: file.Name, Description: file.Description, Type: file.Type}
+		if err := workspaceFields(refs, []workspaceField{
+			{"jsonSchema", file.JSONSchema, &template.JSONSchema, false},
+			{"fieldRules", file.FieldRules, &template.FieldRules, true},
+		}); err != nil {
+			return nil, err
+		}
+		return template, nil
+	default:
+		var file WorkspaceRuleLibrary
+		if err := decode(&file); err != nil {
+			return nil, err
+		}
+		if file.Name == "" {
+			return nil, fmt.Errorf("规则库名称不能为空")
+		}
+		library := &models.RuleLibrary{Name: file.Name, Description: file.Description}
+		if err := workspaceFields(refs, []workspaceField{
+			{"fieldRules", file.FieldRules, &library.FieldRules, true},
+		}); err != nil {
+			return nil, err
+		}
+		return library, nil
+	}
+}
+
+// 以JSON字符串保存的字段
+type workspaceField struct {
+	name   string
+	value  interface{}
+	target *string
+	refs   bool // 其中的数据源名称需要换回ID
+}
+
+// 文件中的结构转换为JSON字符串
+func workspaceFields(refs *dataSourceRefs, fields []workspaceField) error {
+	for _, field := range fields {
+		value := field.value
+		if field.refs {
+			var err error
+			if value, err = refs.toIDs(value); err != nil {
+				return err
+			}
+		}
+		text, err := jsonText(value)
+		if err != nil {
+			return fmt.Errorf("解析 %s 失败: %v", field.name, err)
+		}
+		*field.target = text
+	}
+	return nil
+}
+
+// 由记录生成资源，文件和数据库中的记录都转换为同样格式的YAML，内容相同即无需变更
+func newWorkspaceResource(kind string, record interface{}, refs *dataSourceRefs) (*workspaceResource, error) {
+	var name string
+	var file interface{}
+	switch r := record.(type) {
+	case *models.Task:
+		task := &WorkspaceTask{
+			Name:          r.Name,
+			Type:          r.Type,
+			TableName:     r.TableName,
+			JSONSchema:    yamlValue(r.JSONSchema),
+			FieldRules:    refs.toNames(yamlValue(r.FieldRules)),
+			UniqueFields:  yamlValue(r.UniqueFields),
+			Count:         r.Count,
+			OutputType:    r.OutputType,
+			OutputPath:    r.OutputPath,
+			Priority:      r.Priority,
+			Configuration: refs.toNames(yamlValue(r.Configuration)),
+		}
+		if r.DataSourceID != nil {
+			task.DataSource = refs.names[*r.DataSourceID]
+		}
+		name, file = r.Name, task
+	case *models.TaskTemplate:
+		name, file = r.Name, &WorkspaceTemplate{
+			Name:        r.Name,
+			Description: r.Description,
+			Type:        r.Type,
+			JSONSchema:  yamlValue(r.JSONSchema),
+			FieldRules:  refs.toNames(yamlValue(r.FieldRules)),
+		}
+	case *models.RuleLibrary:
+		name, file = r.Name, &WorkspaceRuleLibrary{
+			Name:        r.Name,
+			Description: r.Description,
+			FieldRules:  refs.toNames(yamlValue(r.FieldRules)),
+		}
+	}
+
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(file); err != nil {
+		return nil, fmt.Errorf("生成YAML失败: %v", err)
+	}
+	encoder.Close()
+	return &workspaceResource{kind: kind, name: name, content: buffer.Bytes(), record: record}, nil
+}
+
+// 新增资源，或用文件中的内容更新已有记录（保留ID、创建时间和任务的执行状态）
+func applyWorkspaceChange(tx *gorm.DB, current, desired *workspaceResource) error {
+	if current == nil {
+		return tx.Create(desired.record).Error
+	}
+	switch record := current.record.(type) {
+	case *models.Task:
+		task := desired.record.(*models.Task)
+		record.Type = task.Type
+		record.DataSourceID = task.DataSourceID
+		record.TableName = task.TableName
+		record.JSONSchema = task.JSONSchema
+		record.FieldRules = task.FieldRules
+		record.UniqueFields = task.UniqueFields
+		record.Count = task.Count
+		record.OutputType = task.OutputType
+		record.OutputPath = task.OutputPath
+		record.Priority = task.Priority
+		record.Configuration = task.Configuration
+	case *models.TaskTemplate:
+		template := desired.record.(*models.TaskTemplate)
+		record.Description = template.Description
+		record.Type = template.Type
+		record.JSONSchema = template.JSONSchema
+		record.FieldRules = template.FieldRules
+	case *models.RuleLibrary:
+		library := desired.record.(*models.RuleLibrary)
+		record.Description = library.Description
+		record.FieldRules = library.FieldRules
+	}
+	return tx.Save(current.record).Error
+}
+
+// 数据源ID和名称的对应关系，工作区文件中按名称引用数据源
+type dataSourceRefs struct {
+	names     map[uint]string
+	ids       map[string]uint
+	ambiguous map[string]bool
+}
+
+func loadDataSourceRefs(db *gorm.DB) (*dataSourceRefs, error) {
+	var dataSources []models.DataSource
+	if err := db.Find(&dataSources).Error; err != nil {
+		return nil, fmt.Errorf("获取数据源失败: %v", err)
+	}
+	refs := &dataSourceRefs{
+		names:     make(map[uint]string),
+		ids:       make(map[string]uint),
+		ambiguous: make(map[string]bool),
+	}
+	for _, dataSource := range dataSources {
+		refs.names[dataSource.ID] = dataSource.Name
+		if _, ok := refs.ids[dataSource.Name]; ok {
+			refs.ambiguous[dataSource.Name] = true
+		}
+		refs.ids[dataSource.Name] = dataSource.ID
+	}
+	return refs, nil
+}
+
+func (r *dataSourceRefs) id(name string) (uint, error) {
+	if r.ambiguous[name] {
+		return 0, fmt.Errorf("存在多个名为 %s 的数据源", name)
+	}
+	id, ok := r.ids[name]
+	if !ok {
+		return 0, fmt.Errorf("数据源不存在: %s", name)
+	}
+	return id, nil
+}
+
+// 把配置和字段规则中的数据源ID（dataSourceId、targetDataSourceId）换成名称（dataSource、targetDataSource）
+func (r *dataSourceRefs) toNames(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if id, ok := item.(float64); ok && strings.HasSuffix(key, "ataSourceId") {
+				if name, ok := r.names[uint(id)]; ok {
+					delete(v, key)
+					v[strings.TrimSuffix(key, "Id")] = name
+					continue
+				}
+			}
+			v[key] = r.toNames(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = r.toNames(item)
+		}
+	}
+	return value
+}
+
+// toNames 的逆操作
+func (r *dataSourceRefs) toIDs(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if name, ok := item.(string); ok && strings.HasSuffix(key, "ataSource") {
+				id, err := r.id(name)
+				if err != nil {
+					return nil, err
+				}
+				delete(v, key)
+				v[key+"Id"] = id
+				continue
+			}
+			converted, err := r.toIDs(item)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = converted
+		}
+	case []interface{}:
+		for i, item := range v {
+			converted, err := r.toIDs(item)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = converted
+		}
+	}
+	return value, nil
+}
+
+// JSON字符串转换为结构以便写成可读的YAML，不是合法JSON时原样保留
+func yamlValue(text string) interface{} {
+	if text == "" {
+		return nil
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(text), &value); err != nil {
+		return text
+	}
+	return value
+}
+
+// 目录中的资源文件
+func workspaceFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("读取目录失败: %v", err)
+	}
+	var files []string
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if !entry.IsDir() && (ext == ".yaml" || ext == ".yml") {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return files, nil
+}
+
+// 由资源名称生成文件名，保留字母（含中文）、数字、- 和 _
+func workspaceFileName(name string) string {
+	fileName := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+	if fileName == "" {
+		return "unnamed"
+	}
+	return fileName
+}
+
+// 逐行比较两段文本，输出带 +/- 标记的差异，未变化的行只保留变更附近的两行上下文
+func lineDiff(old, new string) string {
+	a := splitLines(old)
+	b := splitLines(new)
+
+	// 最长公共子序列
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type line struct {
+		mark byte
+		text string
+	}
+	var lines []line
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, line{' ', a[i]})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, line{'-', a[i]})
+			i++
+		default:
+			lines = append(lines, line{'+', b[j]})
+			j++
+		}
+	}
+
+	const context = 2
+	var out strings.Builder
+	skipped := false
+	for k, l := range lines {
+		keep := l.mark != ' '
+		for d := -context; d <= context && !keep; d++ {
+			if n := k + d; n >= 0 && n < len(lines) && lines[n].mark != ' ' {
+				keep = true
+			}
+		}
+		if !keep {
+			skipped = true
+			continue
+		}
+		if skipped {
+			out.WriteString("  ...\n")
+			skipped = false
+		}
+		out.WriteByte(l.mark)
+		out.WriteByte(' ')
+		out.WriteString(l.text)
+		out.WriteByte('\n')
+	}
+	return out.String()
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"generateTestData/backend/config"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 数据库写入模式
+const (
+	WriteModeAppend      = "append"       // 直接追加
+	WriteModeTruncate    = "truncate"     // 写入前清空表
+	WriteModeDeleteWhere = "delete_where" // 写入前按条件删除
+	WriteModeIgnore      = "ignore"       // 冲突时忽略（INSERT IGNORE / ON CONFLICT DO NOTHING）
+	WriteModeUpsert      = "upsert"       // 冲突时更新（ON DUPLICATE KEY UPDATE / ON CONFLICT DO UPDATE）
+)
+
+// 写入出错时的处理策略
+const (
+	ErrorPolicyAbort      = "abort"       // 终止任务
+	ErrorPolicySkip       = "skip"        // 跳过出错的行
+	ErrorPolicyRejectFile = "reject_file" // 跳过出错的行并记录到拒绝文件
+)
+
+// 被拒绝的记录
+type rejectedRecord struct {
+	Table  string                 `json:"table"`
+	Record map[string]interface{} `json:"record"`
+	Error  string                 `json:"error"`
+}
+
+// 写入前准备目标表：按写入模式清空或删除数据，并解析冲突列。
+// 清理在任务的事务中执行，任务失败时与写入的数据一起回滚。
+// 每次任务执行只需在写入该表的第一批数据前调用一次。
+func (w *DatabaseWriter) PrepareTable(tableName string) error {
+	opts := w.options
+	dbType := w.dbType
+
+	// upsert 在 PostgreSQL/SQLite 下需要冲突列，未指定时使用各表的主键
+	if opts.WriteMode == WriteModeUpsert && len(opts.ConflictColumns) == 0 && dbType != "mysql" {
+		tableInfo, err := NewDatabaseService().GetTableStructure(w.dataSource, tableName)
+		if err != nil {
+			return fmt.Errorf("获取表结构失败: %v", err)
+		}
+		var columns []string
+		for _, col := range tableInfo.Columns {
+			if col.IsPrimaryKey {
+				columns = append(columns, col.Name)
+			}
+		}
+		if len(columns) == 0 {
+			return fmt.Errorf("表 %s 没有主键，upsert 需要指定冲突列", tableName)
+		}
+		w.conflicts[tableName] = columns
+	}
+
+	// 每次执行重新生成拒绝文件
+	if opts.ErrorPolicy == ErrorPolicyRejectFile {
+		if err := os.Remove(rejectFilePath(tableName, opts)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("清理拒绝文件失败: %v", err)
+		}
+	}
+
+	var statement string
+	switch opts.WriteMode {
+	case WriteModeTruncate:
+		// 不使用 TRUNCATE：MySQL 的 TRUNCATE 会隐式提交事务，
+		// PostgreSQL 和 MySQL 在表被外键引用时无法 TRUNCATE（子集任务中的父表）
+		statement = fmt.Sprintf("DELETE FROM %s", tableName)
+	case WriteModeDeleteWhere:
+		if strings.TrimSpace(opts.DeleteWhere) == "" {
+			return fmt.Errorf("delete_where 模式必须指定删除条件")
+		}
+		statement = fmt.Sprintf("DELETE FROM %s WHERE %s", tableName, opts.DeleteWhere)
+	case WriteModeAppend, WriteModeIgnore, WriteModeUpsert:
+		return nil
+	default:
+		return fmt.Errorf("不支持的写入模式: %s", opts.WriteMode)
+	}
+
+	if _, err := w.exec(statement); err != nil {
+		return fmt.Errorf("清理目标表失败: %v", err)
+	}
+	return nil
+}
+
+// 校验写入选项
+func validateInsertOptions(opts InsertOptions) error {
+	switch opts.LoadMode {
+	case LoadModeRow, LoadModeBatch, LoadModeCopy, LoadModeLoadData:
+	default:
+		return fmt.Errorf("不支持的写入方式: %s", opts.LoadMode)
+	}
+	switch opts.WriteMode {
+	case WriteModeAppend, WriteModeTruncate, WriteModeIgnore, WriteModeUpsert:
+	case WriteModeDeleteWhere:
+		if strings.TrimSpace(opts.DeleteWhere) == "" {
+			return fmt.Errorf("delete_where 模式必须指定删除条件")
+		}
+	default:
+		return fmt.Errorf("不支持的写入模式: %s", opts.WriteMode)
+	}
+	switch opts.ErrorPolicy {
+	case ErrorPolicyAbort, ErrorPolicySkip, ErrorPolicyRejectFile:
+	default:
+		return fmt.Errorf("不支持的错误策略: %s", opts.ErrorPolicy)
+	}
+	// COPY 和 LOAD DATA 无法处理冲突，也无法逐行容错
+	if opts.LoadMode == LoadModeCopy || opts.LoadMode == LoadModeLoadData {
+		if opts.WriteMode == WriteModeIgnore || opts.WriteMode == WriteModeUpsert {
+			return fmt.Errorf("%s 写入方式不支持 %s 写入模式，请使用 batch 或 row", opts.LoadMode, opts.WriteMode)
+		}
+		if opts.ErrorPolicy != ErrorPolicyAbort {
+			return fmt.Errorf("%s 写入方式只支持 abort 错误策略，请使用 batch 或 row", opts.LoadMode)
+		}
+	}
+	return nil
+}
+
+// 按写入模式返回 INSERT 语句的前缀和后缀
+func insertClauses(dbType string, columns []string, opts InsertOptions) (string, string, error) {
+	switch opts.WriteMode {
+	case WriteModeAppend, WriteModeTruncate, WriteModeDeleteWhere:
+		return "INSERT INTO", "", nil
+	case WriteModeIgnore:
+		switch dbType {
+		case "mysql":
+			return "INSERT IGNORE INTO", "", nil
+		case "sqlite":
+			return "INSERT OR IGNORE INTO", "", nil
+		default:
+			return "INSERT INTO", " ON CONFLICT DO NOTHING", nil
+		}
+	case WriteModeUpsert:
+		conflict := make(map[string]bool)
+		for _, col := range opts.ConflictColumns {
+			conflict[col] = true
+		}
+		var updates []string
+		for _, col := range columns {
+			if conflict[col] {
+				continue
+			}
+			if dbType == "mysql" {
+				updates = append(updates, fmt.Sprintf("%s = VALUES(%s)", col, col))
+			} else {
+				updates = append(updates, fmt.Sprintf("%s = excluded.%s", col, col))
+			}
+		}
+
+		if dbType == "mysql" {
+			if len(updates) == 0 {
+				return "INSERT IGNORE INTO", "", nil
+			}
+			return "INSERT INTO", " ON DUPLICATE KEY UPDATE " + strings.Join(updates, ", "), nil
+		}
+		if len(opts.ConflictColumns) == 0 {
+			return "", "", fmt.Errorf("upsert 需要指定冲突列")
+		}
+		target := strings.Join(opts.ConflictColumns, ", ")
+		if len(updates) == 0 {
+			return "INSERT INTO", fmt.Sprintf(" ON CONFLICT (%s) DO NOTHING", target), nil
+		}
+		return "INSERT INTO", fmt.Sprintf(" ON CONFLICT (%s) DO UPDATE SET %s", target, strings.Join(updates, ", ")), nil
+	default:
+		return "", "", fmt.Errorf("不支持的写入模式: %s", opts.WriteMode)
+	}
+}
+
+// 拒绝文件路径
+func rejectFilePath(tableName string, opts InsertOptions) string {
+	fileName := opts.RejectFile
+	if fileName == "" {
+		fileName = tableName + "_rejects.jsonl"
+	}
+	return filepath.Join(config.AppConfig.GenerateDir, fileName)
+}
+
+// 将拒绝记录追加到拒绝文件（每行一个JSON）
+func (s *ExportService) writeRejects(tableName string, rejects []rejectedRecord, opts InsertOptions) error {
+	file, err := os.OpenFile(rejectFilePath(tableName, opts), os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("打开拒绝文件失败: %v", err)
+	}
+	defer file.Close()
+
+	for _, reject := range rejects {
+		reject.Table = tableName
+		data, err := json.Marshal(reject)
+		if err != nil {
+			return fmt.Errorf("序列化拒绝记录失败: %v", err)
+		}
+		if _, err := file.Write(append(data, '\n')); err != nil {
+			return fmt.Errorf("写入拒绝文件失败: %v", err)
+		}
+	}
+	return nil
+}
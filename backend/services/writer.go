@@ -0,0 +1,247 @@
+package services
+
+import (
+	"fmt"
+	"generateTestData/backend/models"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/hamba/avro/v2"
+)
+
+// Writer 一次任务执行的输出会话：Open 之后逐批 WriteBatch，成功时 Close 提交，失败时 Abort 清理。
+// 文件输出先写入临时文件，Close 时原子重命名，Abort 时删除，失败的任务不会留下不完整的文件。
+type Writer interface {
+	Open() error
+	WriteBatch(records []map[string]interface{}) error
+	Close() error
+	Abort() error
+}
+
+// 输出目标的描述，由各类任务按自身数据结构填写
+type writerTarget struct {
+	name       string             // 表名或工作表名
+	dataSource *models.DataSource // 输出到数据库时的目标数据源
+	tableInfo  *models.TableInfo  // 表结构，用于SQL输出
+	headers    []string           // 列顺序，用于CSV和Excel输出
+	schema     *RecordSchema      // 记录结构，用于Parquet和Avro输出
+}
+
+// 按任务的输出类型创建并打开输出
+func (s *TaskService) openWriter(task *models.Task, target *writerTarget) (Writer, error) {
+	writer, err := s.newWriter(task, target)
+	if err != nil {
+		return nil, err
+	}
+	// 数据库和HTTP输出在执行日志中记录被拒绝的行和重试
+	if logged, ok := writer.(interface{ setLogger(*runLogger) }); ok {
+		logged.setLogger(s.logger)
+	}
+	if err := writer.Open(); err != nil {
+		writer.Abort()
+		return nil, err
+	}
+	// Excel在打开后创建第一个工作表
+	if xlsxWriter, ok := writer.(*XLSXWriter); ok {
+		if err := xlsxWriter.StartSheet(target.name, target.headers); err != nil {
+			writer.Abort()
+			return nil, err
+		}
+	}
+	return writer, nil
+}
+
+func (s *TaskService) newWriter(task *models.Task, target *writerTarget) (Writer, error) {
+	if isFileOutput(task.OutputType) {
+		return s.exportService.NewFileWriter(task, target.tableInfo, target.headers)
+	}
+
+	switch task.OutputType {
+	case models.OutputTypeDatabase:
+		var options InsertOptions
+		if err := task.GetConfiguration(&options); err != nil {
+			return nil, fmt.Errorf("解析写入配置失败: %v", err)
+		}
+		return s.exportService.NewDatabaseWriter(target.dataSource, target.name, &options), nil
+	case models.OutputTypeParquet:
+		var options ParquetOptions
+		if err := task.GetConfiguration(&options); err != nil {
+			return nil, fmt.Errorf("解析Parquet配置失败: %v", err)
+		}
+		return s.exportService.NewParquetWriter(task.OutputPath, target.schema, &options)
+	case models.OutputTypeAvro:
+		var options AvroOptions
+		if err := task.GetConfiguration(&options); err != nil {
+			return nil, fmt.Errorf("解析Avro配置失败: %v", err)
+		}
+		schema, err := s.exportService.AvroSchema(target.schema, &options)
+		if err != nil {
+			return nil, err
+		}
+		return s.exportService.NewAvroWriter(task.OutputPath, schema, &options)
+	case models.OutputTypeXLSX:
+		var options XLSXOptions
+		if err := task.GetConfiguration(&options); err != nil {
+			return nil, fmt.Errorf("解析Excel配置失败: %v", err)
+		}
+		return s.exportService.NewXLSXWriter(task.OutputPath, &options)
+	case models.OutputTypeMockServer:
+		return s.newMockServerWriter(task)
+	case models.OutputTypeMockAPI:
+		var options MockAPIOptions
+		if err := task.GetConfiguration(&options); err != nil {
+			return nil, fmt.Errorf("解析Mock接口配置失败: %v", err)
+		}
+		return s.exportService.NewMockAPIWriter(&options, task.ID)
+	case models.OutputTypeHTTP:
+		var options HTTPOptions
+		if err := task.GetConfiguration(&options); err != nil {
+			return nil, fmt.Errorf("解析HTTP配置失败: %v", err)
+		}
+		return s.exportService.NewHTTPWriter(&options)
+	case models.OutputTypeKafka:
+		var options KafkaOptions
+		if err := task.GetConfiguration(&options); err != nil {
+			return nil, fmt.Errorf("解析Kafka配置失败: %v", err)
+		}
+		var schema avro.Schema
+		if options.format() == "avro" {
+			var avroOptions AvroOptions
+			if err := task.GetConfiguration(&avroOptions); err != nil {
+				return nil, fmt.Errorf("解析Avro配置失败: %v", err)
+			}
+			var err error
+			if schema, err = s.exportService.AvroSchema(target.schema, &avroOptions); err != nil {
+				return nil, err
+			}
+		}
+		var templateOptions TemplateOptions
+		if err := task.GetConfiguration(&templateOptions); err != nil {
+			return nil, fmt.Errorf("解析模板配置失败: %v", err)
+		}
+		return s.exportService.NewKafkaWriter(&options, schema, &templateOptions)
+	default:
+		return nil, fmt.Errorf("不支持的输出类型: %s", task.OutputType)
+	}
+}
+
+// 推送到外部服务或内置Mock接口的输出，不需要输出路径
+func isStreamOutput(outputType models.OutputType) bool {
+	switch outputType {
+	case models.OutputTypeMockServer, models.OutputTypeMockAPI, models.OutputTypeHTTP, models.OutputTypeKafka:
+		return true
+	}
+	return false
+}
+
+// 将数据逐批写入输出：produce 每生成一批调用一次 write。
+// 全部成功时提交输出并记录生成的文件和被拒绝的行数；出错或中途异常时中止输出，清理未完成的文件。
+func writeAll(writer Writer, result *models.TaskResult, produce func(write func(records []map[string]interface{}) error) error) error {
+	committed := false
+	defer func() {
+		if !committed {
+			writer.Abort()
+		}
+	}()
+
+	err := produce(func(records []map[string]interface{}) error {
+		if err := writer.WriteBatch(records); err != nil {
+			return fmt.Errorf("输出数据失败: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("输出数据失败: %v", err)
+	}
+	committed = true
+
+	if files, ok := writer.(interface{ Files() []string }); ok {
+		result.Files = files.Files()
+	}
+	if rejecter, ok := writer.(interface{ Rejected() int64 }); ok {
+		result.RejectedCount += rejecter.Rejected()
+	}
+	if kafka, ok := writer.(*KafkaWriter); ok {
+		result.PartitionCounts = kafka.PartitionCounts()
+	}
+	return nil
+}
+
+// 临时文件：在目标文件所在目录写入 <目标文件名>.<随机串>.tmp，提交时原子重命名为目标文件，
+// 同时写同一路径的多个任务互不覆盖临时文件
+type tempFile struct {
+	*os.File
+	path   string // 目标文件的完整路径
+	closed bool
+}
+
+// 创建目标文件对应的临时文件，path 为目标文件的完整路径
+func createTempFile(path string) (*tempFile, error) {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("创建文件失败: %v", err)
+	}
+	// CreateTemp 创建的文件权限为0600，与直接创建的输出文件保持一致
+	if err := file.Chmod(0644); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, fmt.Errorf("创建文件失败: %v", err)
+	}
+	return &tempFile{File: file, path: path}, nil
+}
+
+// 关闭临时文件，可重复调用
+func (f *tempFile) Close() error {
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+	return f.File.Close()
+}
+
+// 关闭并重命名为目标文件
+func (f *tempFile) commit() error {
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("写入文件失败: %v", err)
+	}
+	if err := os.Rename(f.Name(), f.path); err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("保存文件失败: %v", err)
+	}
+	return nil
+}
+
+// 关闭并删除临时文件
+func (f *tempFile) discard() {
+	f.Close()
+	os.Remove(f.Name())
+}
+
+// 按固定速率放行，超出速率时等待，可在多个协程间共用
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newRateLimiter(perSecond float64) *rateLimiter {
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+func (l *rateLimiter) wait() {
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+	time.Sleep(delay)
+}
@@ -0,0 +1,350 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"generateTestData/backend/config"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Excel单个工作表的最大行数（含表头）
+const xlsxMaxRows = excelize.TotalRows
+
+// 超过该长度的纯数字字符串按文本格式输出，避免Excel转换为科学计数法或丢失精度（如身份证号）
+const xlsxLongDigits = 11
+
+// Excel导出选项（存储在 Task.Configuration 中）
+type XLSXOptions struct {
+	SheetName   string   `json:"xlsxSheetName"`   // 单表任务的工作表名，默认使用表名
+	TextColumns []string `json:"xlsxTextColumns"` // 强制使用文本格式的列
+	SheetRows   int      `json:"xlsxSheetRows"`   // 每个工作表的最大数据行数，默认并最多为Excel上限（1048575）
+}
+
+// XLSXWriter 以流式方式写入工作表，整个任务期间保持打开。
+// 每张表一个工作表，超过行数上限时自动续写到新的工作表。
+type XLSXWriter struct {
+	file        *excelize.File
+	name        string // 相对于生成目录的文件名
+	filePath    string
+	options     XLSXOptions
+	headerStyle int
+	textStyle   int
+	timeStyle   int
+	maxRows     int // 每个工作表的最大行数（含表头）
+
+	stream     *excelize.StreamWriter
+	baseName   string
+	headers    []string
+	part       int  // 当前表的工作表序号，从1开始
+	row        int  // 当前工作表已写入的行数（含表头）
+	started    bool // 当前工作表是否已写入表头
+	sheetNames map[string]bool
+	closed     bool
+}
+
+// 创建Excel文件
+func (s *ExportService) NewXLSXWriter(fileName string, options *XLSXOptions) (*XLSXWriter, error) {
+	// 确保文件名有正确的后缀
+	fileName = ensureFileExtension(fileName, "xlsx")
+
+	file := excelize.NewFile()
+	w := &XLSXWriter{
+		file:       file,
+		name:       fileName,
+		filePath:   filepath.Join(config.AppConfig.GenerateDir, fileName),
+		sheetNames: make(map[string]bool),
+	}
+	if options != nil {
+		w.options = *options
+	}
+	w.maxRows = xlsxMaxRows
+	if w.options.SheetRows > 0 && w.options.SheetRows < xlsxMaxRows-1 {
+		w.maxRows = w.options.SheetRows + 1
+	}
+
+	var err error
+	if w.headerStyle, err = file.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true},
+		Fill:      excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"D9E1F2"}},
+		Border:    []excelize.Border{{Type: "bottom", Color: "808080", Style: 1}},
+		Alignment: &excelize.Alignment{Horizontal: "center"},
+	}); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("创建表头样式失败: %v", err)
+	}
+	// 内置格式49为文本格式 "@"
+	if w.textStyle, err = file.NewStyle(&excelize.Style{NumFmt: 49}); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("创建文本样式失败: %v", err)
+	}
+	// 内置格式22为 "yyyy-mm-dd hh:mm"
+	if w.timeStyle, err = file.NewStyle(&excelize.Style{NumFmt: 22}); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("创建时间样式失败: %v", err)
+	}
+	return w, nil
+}
+
+// 工作表在内存和excelize的临时文件中生成，Close 时才写入输出文件
+func (w *XLSXWriter) Open() error {
+	return nil
+}
+
+// 开始写入一张表，之后的数据写入以 name 命名的工作表
+func (w *XLSXWriter) StartSheet(name string, headers []string) error {
+	if err := w.flushSheet(); err != nil {
+		return err
+	}
+	if w.options.SheetName != "" && len(w.sheetNames) == 0 {
+		name = w.options.SheetName
+	}
+	w.baseName = name
+	w.headers = headers
+	w.part = 0
+	return w.newSheet()
+}
+
+// 写入一批记录，表头的列顺序决定单元格顺序
+func (w *XLSXWriter) WriteBatch(records []map[string]interface{}) error {
+	if w.stream == nil {
+		return fmt.Errorf("写入数据前必须先创建工作表")
+	}
+	if !w.started {
+		if err := w.writeHeader(records); err != nil {
+			return err
+		}
+	}
+
+	for _, record := range records {
+		if w.row >= w.maxRows {
+			if err := w.newSheet(); err != nil {
+				return err
+			}
+			if err := w.writeHeader(records); err != nil {
+				return err
+			}
+		}
+
+		values := make([]interface{}, len(w.headers))
+		for i, header := range w.headers {
+			values[i] = w.cellValue(record[header])
+		}
+		w.row++
+		cell, _ := excelize.CoordinatesToCellName(1, w.row)
+		if err := w.stream.SetRow(cell, values); err != nil {
+			return fmt.Errorf("写入Excel失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// 保存文件，可重复调用
+func (w *XLSXWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	defer w.file.Close()
+
+	if err := w.flushSheet(); err != nil {
+		return err
+	}
+	// 删除新建文件时自带的默认工作表
+	if len(w.sheetNames) > 0 && !w.sheetNames["Sheet1"] {
+		if err := w.file.DeleteSheet("Sheet1"); err != nil {
+			return fmt.Errorf("删除默认工作表失败: %v", err)
+		}
+	}
+	// 先写入临时文件再重命名，避免留下不完整的文件
+	temp, err := createTempFile(w.filePath)
+	if err != nil {
+		return err
+	}
+	if err := w.file.Write(temp); err != nil {
+		temp.discard()
+		return fmt.Errorf("保存Excel文件失败: %v", err)
+	}
+	return temp.commit()
+}
+
+// 放弃输出，释放excelize的临时文件
+func (w *XLSXWriter) Abort() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.file.Close()
+}
+
+// 生成的文件（相对于生成目录）
+func (w *XLSXWriter) Files() []string {
+	return []string{w.name}
+}
+
+// 创建当前表的下一个工作表
+func (w *XLSXWriter) newSheet() error {
+	if err := w.flushSheet(); err != nil {
+		return err
+	}
+	w.part++
+	name := xlsxSheetName(w.baseName, w.part)
+	for w.sheetNames[name] {
+		w.part++
+		name = xlsxSheetName(w.baseName, w.part)
+	}
+
+	index, err := w.file.NewSheet(name)
+	if err != nil {
+		return fmt.Errorf("创建工作表失败: %v", err)
+	}
+	if len(w.sheetNames) == 0 {
+		w.file.SetActiveSheet(index)
+	}
+	w.sheetNames[name] = true
+
+	stream, err := w.file.NewStreamWriter(name)
+	if err != nil {
+		return fmt.Errorf("创建工作表失败: %v", err)
+	}
+	w.stream = stream
+	w.row = 0
+	w.started = false
+	return nil
+}
+
+// 设置列格式并写入表头。流式写入要求列样式在数据之前设置，
+// 因此文本列根据工作表的第一批数据判断。
+func (w *XLSXWriter) writeHeader(records []map[string]interface{}) error {
+	textColumns := make(map[string]bool)
+	for _, column := range w.options.TextColumns {
+		textColumns[column] = true
+	}
+	for _, record := range records {
+		for _, header := range w.headers {
+			if s, ok := record[header].(string); ok && isLongDigits(s) {
+				textColumns[header] = true
+			}
+		}
+	}
+
+	for i, header := range w.headers {
+		width := float64(len(header) + 4)
+		if width < 12 {
+			width = 12
+		}
+		if textColumns[header] {
+			if width < 22 {
+				width = 22
+			}
+			if err := w.stream.SetColStyle(i+1, i+1, w.textStyle); err != nil {
+				return fmt.Errorf("设置列格式失败: %v", err)
+			}
+		}
+		if err := w.stream.SetColWidth(i+1, i+1, width); err != nil {
+			return fmt.Errorf("设置列宽失败: %v", err)
+		}
+	}
+	if err := w.stream.SetPanes(&excelize.Panes{
+		Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft",
+	}); err != nil {
+		return fmt.Errorf("冻结表头失败: %v", err)
+	}
+
+	headerCells := make([]interface{}, len(w.headers))
+	for i, header := range w.headers {
+		headerCells[i] = excelize.Cell{StyleID: w.headerStyle, Value: header}
+	}
+	w.row = 1
+	if err := w.stream.SetRow("A1", headerCells); err != nil {
+		return fmt.Errorf("写入表头失败: %v", err)
+	}
+	w.started = true
+	return nil
+}
+
+// 结束当前工作表
+func (w *XLSXWriter) flushSheet() error {
+	if w.stream == nil {
+		return nil
+	}
+	if !w.started {
+		if err := w.writeHeader(nil); err != nil {
+			return err
+		}
+	}
+	stream := w.stream
+	w.stream = nil
+	if err := stream.Flush(); err != nil {
+		return fmt.Errorf("写入工作表失败: %v", err)
+	}
+	return nil
+}
+
+// 转换为单元格值：数字、布尔、时间保持类型，长数字字符串使用文本格式
+func (w *XLSXWriter) cellValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		if isLongDigits(v) {
+			return excelize.Cell{StyleID: w.textStyle, Value: v}
+		}
+		return v
+	case []byte:
+		return string(v)
+	case time.Time:
+		return excelize.Cell{StyleID: w.timeStyle, Value: v}
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, bool:
+		return v
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// 是否为需要按文本保存的长数字串
+func isLongDigits(s string) bool {
+	if len(s) <= xlsxLongDigits && !(len(s) > 1 && s[0] == '0') {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		// 身份证号末位可能为X
+		if (c < '0' || c > '9') && !(i == len(s)-1 && (c == 'X' || c == 'x') && i > 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// 生成合法的工作表名：去掉非法字符，长度不超过31，续写的工作表加序号后缀
+func xlsxSheetName(name string, part int) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	if name == "" {
+		name = "Sheet"
+	}
+
+	suffix := ""
+	if part > 1 {
+		suffix = fmt.Sprintf(" (%d)", part)
+	}
+	runes := []rune(name)
+	if limit := excelize.MaxSheetNameLength - len([]rune(suffix)); len(runes) > limit {
+		runes = runes[:limit]
+	}
+	return string(runes) + suffix
+}
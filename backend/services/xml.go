@@ -0,0 +1,224 @@
+package services
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// XML导出选项（存储在 Task.Configuration 中）
+type XMLOptions struct {
+	RootElement   string            `json:"xmlRoot"`       // 根元素名，默认 records
+	RecordElement string            `json:"xmlRecord"`     // 每条记录的元素名，默认 record
+	Attributes    []string          `json:"xmlAttributes"` // 作为属性输出的字段路径（如 id、user.level），其余字段输出为子元素
+	Namespace     string            `json:"xmlNamespace"`  // 根元素的默认命名空间
+	Namespaces    map[string]string `json:"xmlNamespaces"` // 前缀 -> 命名空间，在根元素上声明，元素名可使用 前缀:名称
+}
+
+func (o *XMLOptions) rootElement() string {
+	if o == nil || o.RootElement == "" {
+		return "records"
+	}
+	return o.RootElement
+}
+
+func (o *XMLOptions) recordElement() string {
+	if o == nil || o.RecordElement == "" {
+		return "record"
+	}
+	return o.RecordElement
+}
+
+// 创建XML输出文件
+func (s *ExportService) NewXMLWriter(fileName string, options *XMLOptions) (*FileWriter, error) {
+	return newFileWriter(fileName, newXMLEncoder(options), nil)
+}
+
+// XML编码器，每个分片是一个带根元素的完整文档
+type xmlEncoder struct {
+	options    *XMLOptions
+	rootName   string
+	recordName string
+	attributes map[string]bool
+}
+
+func newXMLEncoder(options *XMLOptions) *xmlEncoder {
+	e := &xmlEncoder{
+		options:    options,
+		rootName:   xmlName(options.rootElement()),
+		recordName: xmlName(options.recordElement()),
+		attributes: make(map[string]bool),
+	}
+	if options != nil {
+		for _, path := range options.Attributes {
+			e.attributes[path] = true
+		}
+	}
+	return e
+}
+
+// 根元素结束标签
+func (e *xmlEncoder) closing() string {
+	return "</" + e.rootName + ">\n"
+}
+
+func (e *xmlEncoder) extension() string { return "xml" }
+
+func (e *xmlEncoder) begin(w io.Writer, part int) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("写入文件失败: %v", err)
+	}
+	if err := writeXMLTokens(w, "", xmlRootStart(e.rootName, e.options)); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func (e *xmlEncoder) encode(w io.Writer, records []map[string]interface{}) error {
+	for _, record := range records {
+		tokens := appendXMLElement(nil, e.recordName, "", record, e.attributes)
+		if err := writeXMLTokens(w, "  ", tokens...); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return fmt.Errorf("写入文件失败: %v", err)
+		}
+	}
+	return nil
+}
+
+func (e *xmlEncoder) end(w io.Writer) error {
+	_, err := io.WriteString(w, e.closing())
+	return err
+}
+
+// 根元素开始标签，包含命名空间声明
+func xmlRootStart(name string, options *XMLOptions) xml.StartElement {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	if options == nil {
+		return start
+	}
+	if options.Namespace != "" {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "xmlns"}, Value: options.Namespace})
+	}
+	prefixes := make([]string, 0, len(options.Namespaces))
+	for prefix := range options.Namespaces {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	for _, prefix := range prefixes {
+		start.Attr = append(start.Attr, xml.Attr{
+			Name:  xml.Name{Local: "xmlns:" + xmlName(prefix)},
+			Value: options.Namespaces[prefix],
+		})
+	}
+	return start
+}
+
+// 依次编码XML标记
+func writeXMLTokens(writer io.Writer, prefix string, tokens ...xml.Token) error {
+	encoder := xml.NewEncoder(writer)
+	encoder.Indent(prefix, "  ")
+	for _, token := range tokens {
+		if err := encoder.EncodeToken(token); err != nil {
+			return fmt.Errorf("写入XML失败: %v", err)
+		}
+	}
+	// 未闭合的开始标签会导致 Close 报错，这里只需刷新缓冲
+	if err := encoder.Flush(); err != nil {
+		return fmt.Errorf("写入XML失败: %v", err)
+	}
+	return nil
+}
+
+// 生成一个元素的标记：对象的字段按名称排序，数组展开为同名的重复元素，
+// path 为字段路径（数组元素与数组共用路径），在 attributes 中的标量字段输出为属性
+func appendXMLElement(tokens []xml.Token, name, path string, value interface{}, attributes map[string]bool) []xml.Token {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		var children []string
+		for _, key := range keys {
+			childPath := joinXMLPath(path, key)
+			if attributes[childPath] && isXMLScalar(v[key]) {
+				if v[key] != nil {
+					start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: xmlName(key)}, Value: toText(v[key])})
+				}
+				continue
+			}
+			children = append(children, key)
+		}
+
+		tokens = append(tokens, start)
+		for _, key := range children {
+			childPath := joinXMLPath(path, key)
+			if items, ok := v[key].([]interface{}); ok {
+				for _, item := range items {
+					tokens = appendXMLElement(tokens, xmlName(key), childPath, item, attributes)
+				}
+				continue
+			}
+			tokens = appendXMLElement(tokens, xmlName(key), childPath, v[key], attributes)
+		}
+	case []interface{}:
+		// 嵌套数组：元素以 item 命名
+		tokens = append(tokens, start)
+		for _, item := range v {
+			tokens = appendXMLElement(tokens, "item", path, item, attributes)
+		}
+	case nil:
+		tokens = append(tokens, start)
+	default:
+		tokens = append(tokens, start, xml.CharData(toText(v)))
+	}
+	return append(tokens, start.End())
+}
+
+func joinXMLPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func isXMLScalar(value interface{}) bool {
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		return false
+	default:
+		return true
+	}
+}
+
+// 将字段名转换为合法的XML名称，保留命名空间前缀的冒号
+func xmlName(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		valid := unicode.IsLetter(r) || r == '_' || r == ':' ||
+			(i > 0 && (unicode.IsDigit(r) || r == '-' || r == '.'))
+		if !valid {
+			if i == 0 && (unicode.IsDigit(r) || r == '-' || r == '.') {
+				b.WriteRune('_')
+				b.WriteRune(r)
+				continue
+			}
+			r = '_'
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() == 0 {
+		return "_"
+	}
+	return b.String()
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 创建YAML多文档流输出文件，每条记录一个文档，以 --- 分隔
+func (s *ExportService) NewYAMLWriter(fileName string) (*FileWriter, error) {
+	return newFileWriter(fileName, &yamlEncoder{}, nil)
+}
+
+// YAML编码器，每条记录一个文档
+type yamlEncoder struct{}
+
+func (e *yamlEncoder) extension() string { return "yaml" }
+
+func (e *yamlEncoder) begin(w io.Writer, part int) error { return nil }
+
+func (e *yamlEncoder) encode(w io.Writer, records []map[string]interface{}) error {
+	for i, record := range records {
+		// 每个文档都以 --- 开头，追加的批次无需关心前一个文档
+		if _, err := io.WriteString(w, "---\n"); err != nil {
+			return fmt.Errorf("写入文件失败: %v", err)
+		}
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(record); err != nil {
+			return fmt.Errorf("序列化YAML失败(第%d条): %v", i+1, err)
+		}
+		if err := encoder.Close(); err != nil {
+			return fmt.Errorf("序列化YAML失败: %v", err)
+		}
+	}
+	return nil
+}
+
+func (e *yamlEncoder) end(w io.Writer) error { return nil }
@@ -0,0 +1,143 @@
+package test
+
+import (
+	"fmt"
+	"generateTestData/backend/config"
+	"generateTestData/backend/models"
+	"generateTestData/backend/services"
+	"generateTestData/backend/utils"
+	"os"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestBulkLoadModes(t *testing.T) {
+	dbPath := "test_bulk_load.db"
+	targetPath := "test_bulk_load_target.db"
+	for _, p := range []string{dbPath, targetPath} {
+		os.Remove(p)
+		defer os.Remove(p)
+	}
+	config.AppConfig = &config.Config{DBPath: dbPath, GenerateDir: "."}
+	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	models.DB = db
+	if err := db.AutoMigrate(&models.DataSource{}, &models.Task{}, &models.TaskRun{}, &models.TaskRunLog{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	target, err := gorm.Open(sqlite.Open(targetPath), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open target: %v", err)
+	}
+	target.Exec("CREATE TABLE items (id INTEGER PRIMARY KEY, a TEXT, b INTEGER CHECK (b >= 0), c REAL, d TEXT)")
+	ds := models.DataSource{Name: "target", Type: "sqlite", Database: targetPath}
+	db.Create(&ds)
+
+	taskService := services.NewTaskService()
+	// 10000 rows of 5 columns; with a batch size of 10000 one INSERT would need 50000 placeholders
+	run := func(name, b, configuration string) (int64, int64) {
+		task := models.Task{
+			Name:         name,
+			Type:         models.TaskTypeDatabase,
+			DataSourceID: &ds.ID,
+			TableName:    "items",
+			Count:        10000,
+			FieldRules: fmt.Sprintf(`{"id":{"type":"sequence"},"a":{"type":"fixed","parameters":{"value":"x"}},`+
+				`"b":{"type":"custom","parameters":{"script":%q}},"c":{"type":"fixed","parameters":{"value":1.5}},"d":{"type":"fixed","parameters":{"value":"y"}}}`, b),
+			OutputType:    models.OutputTypeDatabase,
+			Configuration: configuration,
+		}
+		db.Create(&task)
+		if err := taskService.ExecuteTaskSync(task.ID, models.RunTriggerAPI); err != nil {
+			t.Fatalf("%s: execute failed: %v", name, err)
+		}
+		runs, _ := taskService.GetTaskRuns(task.ID)
+		var total int64
+		target.Raw("SELECT COUNT(*) FROM items").Scan(&total)
+		return total, runs[0].RejectedCount
+	}
+
+	// 1. Batch mode splits statements that would exceed the SQLite placeholder limit
+	if total, rejected := run("batch", "rowIndex", `{"loadMode":"batch","insertBatchSize":10000}`); total != 10000 || rejected != 0 {
+		t.Errorf("Batch: expected 10000 rows, got %d (%d rejected)", total, rejected)
+	}
+
+	// 2. Row mode inserts every row
+	if total, rejected := run("row", "rowIndex", `{"loadMode":"row","writeMode":"truncate"}`); total != 10000 || rejected != 0 {
+		t.Errorf("Row: expected 10000 rows, got %d (%d rejected)", total, rejected)
+	}
+
+	// 3. With errorPolicy skip, failing rows are rejected one by one in both modes
+	badRows := "rowIndex % 1000 == 0 ? -1 : rowIndex"
+	for _, mode := range []string{"batch", "row"} {
+		configuration := fmt.Sprintf(`{"loadMode":%q,"insertBatchSize":10000,"writeMode":"truncate","errorPolicy":"skip"}`, mode)
+		if total, rejected := run(mode+" skip", badRows, configuration); total != 9990 || rejected != 10 {
+			t.Errorf("%s: expected 9990 rows and 10 rejected, got %d and %d", mode, total, rejected)
+		}
+	}
+
+	// 4. Ignore and upsert statements are split the same way and handle the existing rows
+	if total, rejected := run("ignore", "rowIndex + 100000", `{"loadMode":"batch","insertBatchSize":10000,"writeMode":"ignore"}`); total != 10000 || rejected != 0 {
+		t.Errorf("Ignore: expected 10000 rows, got %d (%d rejected)", total, rejected)
+	}
+	// Only the 10 rows rejected above are new
+	var inserted int64
+	target.Raw("SELECT COUNT(*) FROM items WHERE b >= 100000").Scan(&inserted)
+	if inserted != 10 {
+		t.Errorf("Ignore: expected existing rows to be kept and 10 inserted, got %d new values", inserted)
+	}
+	if total, rejected := run("upsert", "rowIndex + 200000", `{"loadMode":"batch","insertBatchSize":10000,"writeMode":"upsert"}`); total != 10000 || rejected != 0 {
+		t.Errorf("Upsert: expected 10000 rows, got %d (%d rejected)", total, rejected)
+	}
+	var updated int64
+	target.Raw("SELECT COUNT(*) FROM items WHERE b = id - 1 + 200000").Scan(&updated)
+	if updated != 10000 {
+		t.Errorf("Upsert: expected every row to be updated, got %d", updated)
+	}
+
+	// 5. COPY and LOAD DATA cannot handle conflicts or reject single rows
+	for _, configuration := range []string{
+		`{"loadMode":"copy","writeMode":"upsert"}`,
+		`{"loadMode":"load_data","writeMode":"ignore"}`,
+		`{"loadMode":"copy","errorPolicy":"skip"}`,
+		`{"loadMode":"load_data","errorPolicy":"reject_file"}`,
+	} {
+		task := models.Task{Name: "bulk", Type: models.TaskTypeDatabase, DataSourceID: &ds.ID, TableName: "items", Count: 1, OutputType: models.OutputTypeDatabase, Configuration: configuration}
+		if err := taskService.ValidateTask(&task); err == nil {
+			t.Errorf("Expected %s to be rejected", configuration)
+		}
+	}
+
+	fmt.Println("TestBulkLoadModes Passed!")
+}
+
+func TestLoadDataField(t *testing.T) {
+	cases := []struct {
+		value interface{}
+		want  string
+	}{
+		{nil, `\N`},
+		{`\N`, `\\N`},
+		{"a\tb", `a\tb`},
+		{"line1\nline2\r", `line1\nline2\r`},
+		{`C:\path`, `C:\\path`},
+		{"nul\x00", `nul\0`},
+		{[]byte("x\ty"), `x\ty`},
+		{true, "1"},
+		{false, "0"},
+		{42, "42"},
+		{time.Date(2024, 3, 1, 8, 30, 0, 500000000, time.UTC), "2024-03-01 08:30:00.5"},
+	}
+	for _, c := range cases {
+		if got := utils.FormatLoadDataField(c.value); got != c.want {
+			t.Errorf("FormatLoadDataField(%q): expected %q, got %q", c.value, c.want, got)
+		}
+	}
+
+	fmt.Println("TestLoadDataField Passed!")
+}
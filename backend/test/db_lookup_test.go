@@ -33,7 +33,7 @@ func TestDBLookup(t *testing.T) {
 	models.DB = db
 
 	// Auto migrate
-	err = db.AutoMigrate(&models.DataSource{}, &models.Task{})
+	err = db.AutoMigrate(&models.DataSource{}, &models.Task{}, &models.TaskRun{}, &models.TaskRunLog{})
 	if err != nil {
 		t.Fatalf("Failed to migrate: %v", err)
 	}
@@ -99,7 +99,7 @@ func TestDBLookup(t *testing.T) {
 	// 5. Execute Task
 	taskService := services.NewTaskService()
 
-	err = taskService.ExecuteTask(task.ID)
+	_, err = taskService.ExecuteTask(task.ID, models.RunTriggerAPI)
 	if err != nil {
 		t.Fatalf("ExecuteTask failed: %v", err)
 	}
@@ -0,0 +1,187 @@
+package test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"generateTestData/backend/config"
+	"generateTestData/backend/models"
+	"generateTestData/backend/services"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hamba/avro/v2/ocf"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestAvroAndProtobufExport(t *testing.T) {
+	uploadDir := t.TempDir()
+	config.AppConfig = &config.Config{GenerateDir: ".", UploadDir: uploadDir}
+	exportService := services.NewExportService()
+
+	var schema map[string]interface{}
+	json.Unmarshal([]byte(`{"id": 1, "user": {"name": "", "level": ""}, "tags": [""], "created_at": ""}`), &schema)
+	recordSchema, err := services.RecordSchemaFromJSON(schema, map[string]models.FieldRule{"id": {Type: "sequence"}})
+	if err != nil {
+		t.Fatalf("Failed to build schema: %v", err)
+	}
+	batch := func(start int) []map[string]interface{} {
+		records := make([]map[string]interface{}, 2)
+		for i := range records {
+			records[i] = map[string]interface{}{
+				"id":         start + i,
+				"user":       map[string]interface{}{"name": fmt.Sprintf("user%d", start+i), "level": "GOLD"},
+				"tags":       []interface{}{"a", "b"},
+				"created_at": "2024-01-02 03:04:05",
+			}
+		}
+		return records
+	}
+
+	// 1. Avro with a schema generated from the task, appended across batches
+	avroFile := "test_encoded.avro"
+	defer os.Remove(avroFile)
+
+	options := &services.AvroOptions{Codec: "deflate", RecordName: "Event", Namespace: "test"}
+	avroSchema, err := exportService.AvroSchema(recordSchema, options)
+	if err != nil {
+		t.Fatalf("Failed to generate Avro schema: %v", err)
+	}
+	avroWriter, err := exportService.NewAvroWriter(avroFile, avroSchema, options)
+	if err != nil {
+		t.Fatalf("Failed to create Avro writer: %v", err)
+	}
+	if err := avroWriter.Open(); err != nil {
+		t.Fatalf("Failed to open Avro writer: %v", err)
+	}
+	for _, start := range []int{1, 3} {
+		if err := avroWriter.WriteBatch(batch(start)); err != nil {
+			t.Fatalf("Failed to export Avro: %v", err)
+		}
+	}
+	if err := avroWriter.Close(); err != nil {
+		t.Fatalf("Failed to close Avro writer: %v", err)
+	}
+
+	f, err := os.Open(avroFile)
+	if err != nil {
+		t.Fatalf("Failed to open Avro file: %v", err)
+	}
+	defer f.Close()
+	decoder, err := ocf.NewDecoder(f)
+	if err != nil {
+		t.Fatalf("Failed to read Avro file: %v", err)
+	}
+	var decoded []map[string]interface{}
+	for decoder.HasNext() {
+		var record map[string]interface{}
+		if err := decoder.Decode(&record); err != nil {
+			t.Fatalf("Failed to decode Avro record: %v", err)
+		}
+		decoded = append(decoded, record)
+	}
+	if len(decoded) != 4 {
+		t.Fatalf("Expected 4 Avro records, got %d", len(decoded))
+	}
+	if decoded[3]["id"] != int64(4) {
+		t.Errorf("Expected last id to be 4, got %v", decoded[3]["id"])
+	}
+
+	// 2. User-supplied Avro schema: unknown fields are rejected
+	userOptions := &services.AvroOptions{Schema: `{"type":"record","name":"Only","fields":[{"name":"id","type":"long"}]}`}
+	userSchema, err := exportService.AvroSchema(nil, userOptions)
+	if err != nil {
+		t.Fatalf("Failed to parse user schema: %v", err)
+	}
+	badWriter, err := exportService.NewAvroWriter("test_encoded_bad.avro", userSchema, userOptions)
+	if err != nil {
+		t.Fatalf("Failed to create Avro writer: %v", err)
+	}
+	if err := badWriter.Open(); err != nil {
+		t.Fatalf("Failed to open Avro writer: %v", err)
+	}
+	if err := badWriter.WriteBatch(batch(1)); err == nil {
+		t.Errorf("Expected error for fields missing from the Avro schema")
+	}
+	// Aborting removes the partial file
+	badWriter.Abort()
+	if temps, _ := filepath.Glob("test_encoded_bad.avro.*.tmp"); len(temps) != 0 {
+		t.Errorf("Expected partial Avro file to be removed")
+	}
+
+	// 3. Length-delimited Protobuf driven by an uploaded .proto file
+	proto := `syntax = "proto3";
+package demo;
+import "google/protobuf/timestamp.proto";
+
+enum Level { LEVEL_UNSPECIFIED = 0; GOLD = 1; }
+message User { string name = 1; Level level = 2; }
+message Event {
+  int64 id = 1;
+  User user = 2;
+  repeated string tags = 3;
+  google.protobuf.Timestamp created_at = 4;
+}`
+	if err := os.WriteFile(uploadDir+"/event.proto", []byte(proto), 0644); err != nil {
+		t.Fatalf("Failed to write proto: %v", err)
+	}
+	message, err := exportService.LoadProtoMessage(&services.ProtobufOptions{ProtoFile: "event.proto", MessageType: "demo.Event"})
+	if err != nil {
+		t.Fatalf("Failed to load proto: %v", err)
+	}
+
+	pbFile := "test_encoded.pb"
+	defer os.Remove(pbFile)
+	pbWriter, err := exportService.NewProtobufWriter(pbFile, message)
+	if err != nil {
+		t.Fatalf("Failed to create Protobuf writer: %v", err)
+	}
+	for _, start := range []int{1, 3} {
+		if err := pbWriter.WriteBatch(batch(start)); err != nil {
+			t.Fatalf("Failed to export Protobuf: %v", err)
+		}
+	}
+	if err := pbWriter.Close(); err != nil {
+		t.Fatalf("Failed to close Protobuf writer: %v", err)
+	}
+
+	pf, err := os.Open(pbFile)
+	if err != nil {
+		t.Fatalf("Failed to open Protobuf file: %v", err)
+	}
+	defer pf.Close()
+	reader := bufio.NewReader(pf)
+	var messages []*dynamicpb.Message
+	for {
+		msg := dynamicpb.NewMessage(message)
+		if err := protodelim.UnmarshalFrom(reader, msg); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Failed to decode Protobuf message: %v", err)
+		}
+		messages = append(messages, msg)
+	}
+	if len(messages) != 4 {
+		t.Fatalf("Expected 4 Protobuf messages, got %d", len(messages))
+	}
+
+	last := messages[3]
+	fields := message.Fields()
+	user := last.Get(fields.ByName("user")).Message()
+	createdAt := last.Get(fields.ByName("created_at")).Message()
+	seconds := createdAt.Get(createdAt.Descriptor().Fields().ByName("seconds")).Int()
+	if last.Get(fields.ByName("id")).Int() != 4 ||
+		user.Get(user.Descriptor().Fields().ByName("name")).String() != "user4" ||
+		user.Get(user.Descriptor().Fields().ByName("level")).Enum() != protoreflect.EnumNumber(1) ||
+		last.Get(fields.ByName("tags")).List().Len() != 2 ||
+		seconds != time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC).Unix() {
+		t.Errorf("Unexpected Protobuf message: %v", last)
+	}
+
+	fmt.Println("TestAvroAndProtobufExport Passed!")
+}
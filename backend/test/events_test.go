@@ -0,0 +1,147 @@
+package test
+
+import (
+	"fmt"
+	"generateTestData/backend/config"
+	"generateTestData/backend/models"
+	"generateTestData/backend/services"
+	"os"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestTaskEvents(t *testing.T) {
+	dbPath := "test_events.db"
+	outputDir := "test_events_output"
+	os.Remove(dbPath)
+	os.RemoveAll(outputDir)
+	defer os.Remove(dbPath)
+	defer os.RemoveAll(outputDir)
+	os.MkdirAll(outputDir, 0755)
+	config.AppConfig = &config.Config{DBPath: dbPath, GenerateDir: outputDir}
+	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	models.DB = db
+	if err := db.AutoMigrate(&models.DataSource{}, &models.Task{}, &models.TaskRun{}, &models.TaskRunLog{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	task := models.Task{Name: "events", Type: models.TaskTypeJSON, Count: 20000, JSONSchema: `{"id":1,"name":""}`, OutputType: models.OutputTypeJSONL, OutputPath: "events"}
+	other := models.Task{Name: "other", Type: models.TaskTypeJSON, Count: 10, JSONSchema: `{"id":1}`, OutputType: models.OutputTypeJSONL, OutputPath: "other"}
+	db.Create(&task)
+	db.Create(&other)
+
+	events, unsubscribe := services.SubscribeTaskEvents(task.ID)
+	defer unsubscribe()
+	all, unsubscribeAll := services.SubscribeTaskEvents(0)
+	defer unsubscribeAll()
+
+	taskService := services.NewTaskService()
+	if err := taskService.ExecuteTaskSync(task.ID, models.RunTriggerAPI); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if err := taskService.ExecuteTaskSync(other.ID, models.RunTriggerAPI); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	// 1. A run publishes queued, running, phases, throttled progress and a final result, in order
+	var received []services.TaskEvent
+	for len(events) > 0 {
+		received = append(received, <-events)
+	}
+	if len(received) < 5 {
+		t.Fatalf("Expected at least 5 events, got %+v", received)
+	}
+	if received[0].Type != services.TaskEventStatus || received[0].Status != models.TaskStatusQueued {
+		t.Errorf("Expected queued status first, got %+v", received[0])
+	}
+	if received[1].Type != services.TaskEventStatus || received[1].Status != models.TaskStatusRunning {
+		t.Errorf("Expected running status second, got %+v", received[1])
+	}
+	var phases []string
+	progressEvents := 0
+	var last services.TaskEvent
+	for _, event := range received {
+		if event.TaskID != task.ID {
+			t.Errorf("Received event of another task: %+v", event)
+		}
+		switch event.Type {
+		case services.TaskEventPhase:
+			phases = append(phases, event.Phase)
+		case services.TaskEventProgress:
+			progressEvents++
+			last = event
+		}
+	}
+	if fmt.Sprint(phases) != fmt.Sprint([]string{services.PhaseGenerating, services.PhaseWriting}) {
+		t.Errorf("Unexpected phases: %v", phases)
+	}
+	// 20 batches of 1000 rows are throttled, but the final 100% is always published
+	if progressEvents == 0 || progressEvents > 20 {
+		t.Errorf("Unexpected number of progress events: %d", progressEvents)
+	}
+	if last.Progress != 100 || last.Rows != task.Count || last.RowsPerSecond <= 0 {
+		t.Errorf("Unexpected final progress: %+v", last)
+	}
+	result := received[len(received)-1]
+	if result.Type != services.TaskEventResult || result.Status != models.TaskStatusCompleted || result.Result == nil || result.Result.GeneratedCount != task.Count {
+		t.Errorf("Expected completed result last, got %+v", result)
+	}
+
+	// 2. Progress is still persisted for polling clients
+	var saved models.Task
+	db.First(&saved, task.ID)
+	if saved.Progress != 100 {
+		t.Errorf("Expected progress 100, got %v", saved.Progress)
+	}
+
+	// 3. The global stream receives events of every task
+	seen := map[uint]bool{}
+	for len(all) > 0 {
+		seen[(<-all).TaskID] = true
+	}
+	if !seen[task.ID] || !seen[other.ID] {
+		t.Errorf("Expected events of both tasks on the global stream, got %v", seen)
+	}
+
+	// 4. Failures publish a result with the error
+	db.Model(&task).Update("json_schema", "{")
+	taskService.ExecuteTaskSync(task.ID, models.RunTriggerAPI)
+	var failed services.TaskEvent
+	for len(events) > 0 {
+		failed = <-events
+	}
+	if failed.Type != services.TaskEventResult || failed.Status != models.TaskStatusFailed || failed.Message == "" {
+		t.Errorf("Expected failed result, got %+v", failed)
+	}
+
+	// 5. A subscriber that falls behind loses progress events but every status and result is delivered
+	slow, unsubscribeSlow := services.SubscribeTaskEvents(other.ID)
+	defer unsubscribeSlow()
+	for i := 0; i < 60; i++ {
+		if err := taskService.ExecuteTaskSync(other.ID, models.RunTriggerAPI); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+	}
+	if len(slow) != cap(slow) {
+		t.Fatalf("Expected the slow subscriber's buffer to be full, got %d events", len(slow))
+	}
+	statuses, results := 0, 0
+	for len(slow) > 0 {
+		switch event := <-slow; event.Type {
+		case services.TaskEventStatus:
+			statuses++
+		case services.TaskEventResult:
+			results++
+		}
+	}
+	if statuses != 120 || results != 60 {
+		t.Errorf("Expected 120 status and 60 result events, got %d and %d", statuses, results)
+	}
+
+	fmt.Println("TestTaskEvents Passed!")
+}
@@ -0,0 +1,226 @@
+package test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"generateTestData/backend/config"
+	"generateTestData/backend/models"
+	"generateTestData/backend/services"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestCompressedSplitOutput(t *testing.T) {
+	config.AppConfig = &config.Config{GenerateDir: "."}
+	exportService := services.NewExportService()
+
+	records := make([]map[string]interface{}, 7)
+	for i := range records {
+		records[i] = map[string]interface{}{"id": i + 1, "name": fmt.Sprintf("user%d", i+1)}
+	}
+
+	type manifest struct {
+		Format      string              `json:"format"`
+		Compression string              `json:"compression"`
+		Rows        int64               `json:"rows"`
+		Parts       []services.FilePart `json:"parts"`
+	}
+	readManifest := func(name string) manifest {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("Failed to read manifest: %v", err)
+		}
+		var m manifest
+		if err := json.Unmarshal(data, &m); err != nil {
+			t.Fatalf("Invalid manifest: %v", err)
+		}
+		return m
+	}
+	checkPart := func(part services.FilePart) []byte {
+		data, err := os.ReadFile(part.File)
+		if err != nil {
+			t.Fatalf("Failed to read part %s: %v", part.File, err)
+		}
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != part.SHA256 || int64(len(data)) != part.Bytes {
+			t.Errorf("Checksum or size mismatch for %s", part.File)
+		}
+		return data
+	}
+
+	// 1. CSV split every 3 rows with gzip: each part is a complete CSV with its own header
+	csvTask := &models.Task{
+		OutputType:    models.OutputTypeCSV,
+		OutputPath:    "test_split.csv",
+		Configuration: `{"compression":"gzip","splitRows":3,"manifest":true}`,
+	}
+	writer, err := exportService.NewFileWriter(csvTask, nil, []string{"id", "name"})
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	// Write in uneven batches so parts are cut across batch boundaries
+	for _, batch := range [][]map[string]interface{}{records[:2], records[2:6], records[6:]} {
+		if err := writer.WriteBatch(batch); err != nil {
+			t.Fatalf("Failed to write batch: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+	for _, file := range writer.Files() {
+		defer os.Remove(file)
+	}
+
+	expectedFiles := []string{"test_split-0001.csv.gz", "test_split-0002.csv.gz", "test_split-0003.csv.gz", "test_split.manifest.json"}
+	if fmt.Sprint(writer.Files()) != fmt.Sprint(expectedFiles) {
+		t.Fatalf("Unexpected files: %v", writer.Files())
+	}
+	m := readManifest("test_split.manifest.json")
+	if m.Format != "csv" || m.Compression != "gzip" || m.Rows != 7 || len(m.Parts) != 3 {
+		t.Fatalf("Unexpected manifest: %+v", m)
+	}
+	id := 1
+	for i, part := range m.Parts {
+		reader, err := gzip.NewReader(bytes.NewReader(checkPart(part)))
+		if err != nil {
+			t.Fatalf("Invalid gzip part %s: %v", part.File, err)
+		}
+		data, _ := io.ReadAll(reader)
+		rows, err := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF")))).ReadAll()
+		if err != nil {
+			t.Fatalf("Invalid CSV part %s: %v", part.File, err)
+		}
+		if rows[0][0] != "id" || rows[0][1] != "name" {
+			t.Errorf("Part %d is missing its header: %v", i+1, rows[0])
+		}
+		if int64(len(rows)-1) != part.Rows {
+			t.Errorf("Part %d has %d rows, manifest says %d", i+1, len(rows)-1, part.Rows)
+		}
+		for _, row := range rows[1:] {
+			if row[0] != fmt.Sprint(id) {
+				t.Errorf("Expected id %d, got %s", id, row[0])
+			}
+			id++
+		}
+	}
+
+	// 2. JSON split by size with zstd: every part is a valid JSON array
+	jsonTask := &models.Task{
+		OutputType:    models.OutputTypeJSON,
+		OutputPath:    "test_split",
+		Configuration: `{"compression":"zstd","splitBytes":1}`,
+	}
+	writer, err = exportService.NewFileWriter(jsonTask, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	if err := writer.WriteBatch(records[:3]); err != nil {
+		t.Fatalf("Failed to write batch: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+	for _, file := range writer.Files() {
+		defer os.Remove(file)
+	}
+	if len(writer.Files()) != 1 || writer.Files()[0] != "test_split-0001.json.zst" {
+		t.Fatalf("Unexpected files: %v", writer.Files())
+	}
+	decoder, _ := zstd.NewReader(nil)
+	defer decoder.Close()
+	data, _ := os.ReadFile(writer.Files()[0])
+	data, err = decoder.DecodeAll(data, nil)
+	if err != nil {
+		t.Fatalf("Invalid zstd part: %v", err)
+	}
+	var decoded []map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil || len(decoded) != 3 {
+		t.Errorf("Invalid JSON part (%v): %s", err, data)
+	}
+
+	// 3. Unknown compression is rejected
+	badTask := &models.Task{OutputType: models.OutputTypeJSON, OutputPath: "test_bad.json", Configuration: `{"compression":"lz4"}`}
+	if _, err := exportService.NewFileWriter(badTask, nil, nil); err == nil {
+		t.Errorf("Expected error for unsupported compression")
+	}
+
+	// 4. Aborting a split writer leaves no parts or temporary files behind
+	abortTask := &models.Task{OutputType: models.OutputTypeCSV, OutputPath: "test_abort.csv", Configuration: `{"splitRows":2}`}
+	writer, err = exportService.NewFileWriter(abortTask, nil, []string{"id", "name"})
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	if err := writer.WriteBatch(records); err != nil {
+		t.Fatalf("Failed to write batch: %v", err)
+	}
+	writer.Abort()
+	temps, _ := filepath.Glob("test_abort-*.tmp")
+	for _, name := range append([]string{"test_abort-0001.csv"}, temps...) {
+		if _, err := os.Stat(name); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed after abort", name)
+			os.Remove(name)
+		}
+	}
+
+	// 5. A rerun with fewer parts removes the parts left over from the earlier run
+	write := func(configuration string) (*services.FileWriter, error) {
+		task := &models.Task{OutputType: models.OutputTypeCSV, OutputPath: "test_rerun.csv", Configuration: configuration}
+		writer, err := exportService.NewFileWriter(task, nil, []string{"id", "name"})
+		if err != nil {
+			t.Fatalf("Failed to create writer: %v", err)
+		}
+		if err := writer.WriteBatch(records); err != nil {
+			t.Fatalf("Failed to write batch: %v", err)
+		}
+		return writer, writer.Close()
+	}
+	defer func() {
+		leftovers, _ := filepath.Glob("test_rerun*")
+		for _, name := range leftovers {
+			os.RemoveAll(name)
+		}
+	}()
+	if _, err := write(`{"splitRows":2,"manifest":true}`); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+	if _, err := write(`{"splitRows":3,"manifest":true}`); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+	parts, _ := filepath.Glob("test_rerun-*.csv")
+	if fmt.Sprint(parts) != "[test_rerun-0001.csv test_rerun-0002.csv test_rerun-0003.csv]" {
+		t.Errorf("Expected only the parts of the latest run, got %v", parts)
+	}
+	if m := readManifest("test_rerun.manifest.json"); m.Rows != 7 || len(m.Parts) != 3 {
+		t.Errorf("Unexpected manifest after rerun: %+v", m)
+	}
+
+	// 6. A commit failing partway keeps the published parts, drops the temporary files and writes no manifest
+	os.Remove("test_rerun-0003.csv")
+	os.Mkdir("test_rerun-0003.csv", 0755)
+	os.WriteFile(filepath.Join("test_rerun-0003.csv", "keep"), nil, 0644)
+	if _, err := write(`{"splitRows":3,"manifest":true}`); err == nil {
+		t.Fatalf("Expected the commit to fail")
+	}
+	for _, name := range []string{"test_rerun-0001.csv", "test_rerun-0002.csv"} {
+		if _, err := os.Stat(name); err != nil {
+			t.Errorf("Expected %s to survive a failed commit: %v", name, err)
+		}
+	}
+	if temps, _ := filepath.Glob("test_rerun*.tmp"); len(temps) > 0 {
+		t.Errorf("Expected no temporary files after a failed commit, got %v", temps)
+	}
+	if _, err := os.Stat("test_rerun.manifest.json"); !os.IsNotExist(err) {
+		t.Errorf("Expected no manifest after a failed commit")
+	}
+
+	fmt.Println("TestCompressedSplitOutput Passed!")
+}
@@ -0,0 +1,113 @@
+package test
+
+import (
+	"fmt"
+	"generateTestData/backend/config"
+	"generateTestData/backend/gen"
+	"generateTestData/backend/models"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenLibrary(t *testing.T) {
+	// The library must not depend on the platform database or configuration
+	savedDB, savedConfig := models.DB, config.AppConfig
+	models.DB, config.AppConfig = nil, nil
+	defer func() { models.DB, config.AppConfig = savedDB, savedConfig }()
+
+	schema, err := gen.ParseSchema(`{"id":"","user":{"email":"","tags":[""]},"score":1.5,"createdAt":"","note":""}`)
+	if err != nil {
+		t.Fatalf("ParseSchema failed: %v", err)
+	}
+	rules := map[string]gen.Rule{
+		"id":          {Type: "uuid"},
+		"user.email":  {Type: "regex", Parameters: map[string]interface{}{"pattern": "[a-z]{6}@test\\.com"}},
+		"user.tags":   {Parameters: map[string]interface{}{"length": float64(2)}},
+		"user.tags[]": {Type: "enum", Parameters: map[string]interface{}{"values": "a,b,c"}},
+		"createdAt":   {Type: "random", Parameters: map[string]interface{}{"format": "2006-01-02"}},
+		"note":        {Type: "custom", Parameters: map[string]interface{}{"script": "randomInt(1, 100) + '-' + Math.floor(Math.random() * 1000) + '-' + rowIndex"}},
+	}
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	// 1. Same seed, same rows
+	first, err := gen.New(schema, rules, gen.Options{Seed: 42, Now: now}).Generate(20)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	second, _ := gen.New(schema, rules, gen.Options{Seed: 42, Now: now}).Generate(20)
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("Expected identical rows for the same seed:\n%v\n%v", first[0], second[0])
+	}
+	other, _ := gen.New(schema, rules, gen.Options{Seed: 7, Now: now}).Generate(20)
+	if reflect.DeepEqual(first, other) {
+		t.Errorf("Expected different rows for a different seed")
+	}
+	user := first[0]["user"].(map[string]interface{})
+	if !strings.HasSuffix(user["email"].(string), "@test.com") || len(user["tags"].([]interface{})) != 2 {
+		t.Errorf("Unexpected nested record: %v", first[0])
+	}
+	if created := first[0]["createdAt"].(string); created < "2020-01-01" || created > "2025-06-01" {
+		t.Errorf("Expected random date before the fixed time, got %s", created)
+	}
+	if !strings.HasSuffix(first[3]["note"].(string), "-3") {
+		t.Errorf("Expected rowIndex in custom script, got %v", first[3]["note"])
+	}
+	if _, ok := rules["createdAt"].Parameters["fieldName"]; ok {
+		t.Errorf("Caller's rules must not be modified")
+	}
+
+	// 2. Columns with a lookup provider; the iterator can stop early
+	columns := gen.Columns(
+		gen.Column{Name: "id", Type: "int"},
+		gen.Column{Name: "user_id", Type: "int"},
+	)
+	lookup := gen.StaticLookup{"users.id": {101, 102, 103}}
+	generator := gen.New(columns, map[string]gen.Rule{
+		"id":      {Type: "sequence", Parameters: map[string]interface{}{"start": 10}},
+		"user_id": {Type: "db_lookup", Parameters: map[string]interface{}{"tableName": "users", "columnName": "id", "dataSourceId": float64(3)}},
+	}, gen.Options{Seed: 1, Lookup: lookup})
+	count := 0
+	for row, err := range generator.Rows(100) {
+		if err != nil {
+			t.Fatalf("Row failed: %v", err)
+		}
+		if row["id"] != fmt.Sprint(10+count) {
+			t.Errorf("Unexpected sequence value: %v", row["id"])
+		}
+		if v := row["user_id"]; v != 101 && v != 102 && v != 103 {
+			t.Errorf("Unexpected lookup value: %v", v)
+		}
+		count++
+		if count == 5 {
+			break
+		}
+	}
+	if row, _ := generator.Next(); row["id"] != "15" {
+		t.Errorf("Expected generator to continue after break, got %v", row["id"])
+	}
+	if _, err := gen.New(columns, map[string]gen.Rule{
+		"user_id": {Type: "db_lookup", Parameters: map[string]interface{}{"tableName": "users", "columnName": "id"}},
+	}, gen.Options{}).Next(); err == nil {
+		t.Errorf("Expected db_lookup without provider to fail")
+	}
+
+	// 3. A shared unique store spans generators and reports exhaustion
+	store := gen.NewUniqueStore()
+	statusRules := map[string]gen.Rule{"status": {Type: "enum", Parameters: map[string]interface{}{"values": "a,b,c"}}}
+	statusSchema := gen.Columns(gen.Column{Name: "status", Type: "varchar"})
+	options := gen.Options{Unique: store, UniqueFields: []string{"status"}}
+	if _, err := gen.New(statusSchema, statusRules, options).Generate(2); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	next := gen.New(statusSchema, statusRules, options)
+	if _, err := next.Next(); err != nil {
+		t.Fatalf("Expected the last unused value, got %v", err)
+	}
+	if _, err := next.Next(); err == nil || !strings.Contains(err.Error(), "status") {
+		t.Errorf("Expected exhaustion error, got %v", err)
+	}
+
+	fmt.Println("TestGenLibrary Passed!")
+}
@@ -0,0 +1,210 @@
+package test
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"generateTestData/backend/config"
+	"generateTestData/backend/services"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHTTPSink(t *testing.T) {
+	config.AppConfig = &config.Config{GenerateDir: "."}
+	exportService := services.NewExportService()
+
+	type request struct {
+		method  string
+		path    string
+		headers http.Header
+		body    []byte
+	}
+	var mu sync.Mutex
+	var requests []request
+	failures := 0 // number of upcoming requests to answer with 503
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/reject" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("invalid payload"))
+			return
+		}
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		requests = append(requests, request{r.Method, r.URL.Path, r.Header.Clone(), body})
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	records := make([]map[string]interface{}, 5)
+	for i := range records {
+		records[i] = map[string]interface{}{"id": i + 1, "name": fmt.Sprintf("user%d", i+1)}
+	}
+	send := func(options *services.HTTPOptions) (*services.HTTPWriter, error) {
+		mu.Lock()
+		requests = nil
+		mu.Unlock()
+		writer, err := exportService.NewHTTPWriter(options)
+		if err != nil {
+			t.Fatalf("Failed to create HTTP writer: %v", err)
+		}
+		if err := writer.Open(); err != nil {
+			t.Fatalf("Failed to open HTTP writer: %v", err)
+		}
+		if err := writer.WriteBatch(records); err != nil {
+			writer.Abort()
+			return writer, err
+		}
+		return writer, writer.Close()
+	}
+	retries := 3
+
+	// 1. Batch mode with HMAC signing, retried after a 503
+	failures = 1
+	if _, err := send(&services.HTTPOptions{
+		URL:            server.URL + "/batch",
+		BatchSize:      2,
+		Headers:        map[string]string{"X-Count": "{{.count}}"},
+		Auth:           "hmac",
+		HMACSecret:     "secret",
+		MaxRetries:     &retries,
+		RetryBackoffMs: 10,
+	}); err != nil {
+		t.Fatalf("Batch send failed: %v", err)
+	}
+	if len(requests) != 3 {
+		t.Fatalf("Expected 3 batch requests, got %d", len(requests))
+	}
+	var received []map[string]interface{}
+	for _, req := range requests {
+		var batch []map[string]interface{}
+		if err := json.Unmarshal(req.body, &batch); err != nil {
+			t.Fatalf("Invalid batch body: %s", req.body)
+		}
+		if req.headers.Get("X-Count") != fmt.Sprint(len(batch)) {
+			t.Errorf("Expected X-Count %d, got %s", len(batch), req.headers.Get("X-Count"))
+		}
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write(req.body)
+		if req.headers.Get("X-Signature") != "sha256="+hex.EncodeToString(mac.Sum(nil)) {
+			t.Errorf("Invalid signature: %s", req.headers.Get("X-Signature"))
+		}
+		received = append(received, batch...)
+	}
+	if len(received) != 5 {
+		t.Errorf("Expected 5 records across batches, got %d", len(received))
+	}
+
+	// 2. Per-record mode with templated URL, method and body, sent concurrently with basic auth
+	if _, err := send(&services.HTTPOptions{
+		URL:         server.URL + "/users/{{.id}}",
+		Method:      "put",
+		Mode:        "record",
+		Body:        `name={{.name}}`,
+		Headers:     map[string]string{"Content-Type": "text/plain"},
+		Concurrency: 3,
+		Auth:        "basic",
+		Username:    "admin",
+		Password:    "pass",
+	}); err != nil {
+		t.Fatalf("Record send failed: %v", err)
+	}
+	if len(requests) != 5 {
+		t.Fatalf("Expected 5 record requests, got %d", len(requests))
+	}
+	seen := map[string]bool{}
+	for _, req := range requests {
+		if req.method != http.MethodPut || req.headers.Get("Content-Type") != "text/plain" {
+			t.Errorf("Unexpected request: %s %v", req.method, req.headers)
+		}
+		r := http.Request{Header: req.headers}
+		if user, pass, ok := r.BasicAuth(); !ok || user != "admin" || pass != "pass" {
+			t.Errorf("Missing basic auth")
+		}
+		seen[req.path+" "+string(req.body)] = true
+	}
+	for _, record := range records {
+		if key := fmt.Sprintf("/users/%v name=%v", record["id"], record["name"]); !seen[key] {
+			t.Errorf("Missing request %s", key)
+		}
+	}
+
+	// 3. Rejected requests go to the dead-letter file instead of failing the task
+	writer, err := send(&services.HTTPOptions{URL: server.URL + "/reject", BatchSize: 2, DeadLetterFile: "test_dead_letter"})
+	if err != nil {
+		t.Fatalf("Expected dead-lettered send to succeed: %v", err)
+	}
+	defer os.Remove("test_dead_letter.jsonl")
+	if writer.Rejected() != 5 || len(writer.Files()) != 1 || writer.Files()[0] != "test_dead_letter.jsonl" {
+		t.Errorf("Unexpected rejected count %d or files %v", writer.Rejected(), writer.Files())
+	}
+	file, err := os.Open("test_dead_letter.jsonl")
+	if err != nil {
+		t.Fatalf("Failed to open dead-letter file: %v", err)
+	}
+	defer file.Close()
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry struct {
+			Status int    `json:"status"`
+			Error  string `json:"error"`
+			Body   string `json:"body"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || entry.Status != 400 || entry.Error != "invalid payload" || entry.Body == "" {
+			t.Errorf("Unexpected dead-letter entry: %s", scanner.Text())
+		}
+		lines++
+	}
+	if lines != 3 {
+		t.Errorf("Expected 3 dead-letter entries, got %d", lines)
+	}
+
+	// 4. Without a dead-letter file a client error fails the batch, and exhausted retries too
+	if _, err := send(&services.HTTPOptions{URL: server.URL + "/reject"}); err == nil {
+		t.Errorf("Expected error for rejected request")
+	}
+	noRetries := 0
+	failures = 1
+	if _, err := send(&services.HTTPOptions{URL: server.URL + "/batch", MaxRetries: &noRetries}); err == nil {
+		t.Errorf("Expected error when retries are exhausted")
+	}
+	failures = 0
+
+	// 5. Rate limit spaces requests out
+	start := time.Now()
+	if _, err := send(&services.HTTPOptions{URL: server.URL + "/batch", Mode: "record", Concurrency: 5, RateLimit: 20}); err != nil {
+		t.Fatalf("Rate limited send failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 190*time.Millisecond {
+		t.Errorf("Expected rate limit to slow down requests, took %v", elapsed)
+	}
+
+	// 6. Invalid configurations are rejected
+	for _, options := range []*services.HTTPOptions{
+		{},
+		{URL: server.URL, Method: "TRACE"},
+		{URL: server.URL, Auth: "bearer"},
+		{URL: server.URL, Body: "{{.name"},
+	} {
+		if _, err := exportService.NewHTTPWriter(options); err == nil {
+			t.Errorf("Expected error for options %+v", options)
+		}
+	}
+
+	fmt.Println("TestHTTPSink Passed!")
+}
@@ -0,0 +1,85 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"generateTestData/backend/config"
+	"generateTestData/backend/models"
+	"generateTestData/backend/services"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestJSONLinesOutput(t *testing.T) {
+	config.AppConfig = &config.Config{GenerateDir: "."}
+	exportService := services.NewExportService()
+
+	batch := func(start int) []map[string]interface{} {
+		records := make([]map[string]interface{}, 2)
+		for i := range records {
+			records[i] = map[string]interface{}{"id": start + i, "tags": []interface{}{"a", "b"}}
+		}
+		return records
+	}
+	writeAll := func(task *models.Task) string {
+		writer, err := exportService.NewFileWriter(task, nil, nil)
+		if err != nil {
+			t.Fatalf("Failed to create writer: %v", err)
+		}
+		for _, start := range []int{1, 3} {
+			if err := writer.WriteBatch(batch(start)); err != nil {
+				t.Fatalf("Failed to write batch: %v", err)
+			}
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("Failed to close writer: %v", err)
+		}
+		data, err := os.ReadFile(writer.Files()[0])
+		if err != nil {
+			t.Fatalf("Failed to read output: %v", err)
+		}
+		os.Remove(writer.Files()[0])
+		return string(data)
+	}
+
+	// 1. JSON Lines: one object per line, every line terminated, across batches
+	content := writeAll(&models.Task{OutputType: models.OutputTypeJSONL, OutputPath: "test_output"})
+	expected := `{"id":1,"tags":["a","b"]}` + "\n" + `{"id":2,"tags":["a","b"]}` + "\n" +
+		`{"id":3,"tags":["a","b"]}` + "\n" + `{"id":4,"tags":["a","b"]}` + "\n"
+	if content != expected {
+		t.Errorf("Unexpected JSON Lines output:\n%s", content)
+	}
+
+	// 2. Compact JSON array: no indentation, still valid JSON
+	content = writeAll(&models.Task{OutputType: models.OutputTypeJSON, OutputPath: "test_output", Configuration: `{"pretty":false}`})
+	expected = "[\n" + `{"id":1,"tags":["a","b"]},` + "\n" + `{"id":2,"tags":["a","b"]},` + "\n" +
+		`{"id":3,"tags":["a","b"]},` + "\n" + `{"id":4,"tags":["a","b"]}` + "\n]\n"
+	if content != expected {
+		t.Errorf("Unexpected compact JSON output:\n%s", content)
+	}
+
+	// 3. Pretty JSON array is the default
+	content = writeAll(&models.Task{OutputType: models.OutputTypeJSON, OutputPath: "test_output"})
+	var decoded []map[string]interface{}
+	if err := json.Unmarshal([]byte(content), &decoded); err != nil || len(decoded) != 4 {
+		t.Errorf("Invalid pretty JSON output (%v):\n%s", err, content)
+	}
+	if !strings.Contains(content, "\n    \"id\": 1,") {
+		t.Errorf("Expected indented JSON output:\n%s", content)
+	}
+
+	// 4. TXT output keeps the same line framing across batches
+	content = writeAll(&models.Task{OutputType: models.OutputTypeTXT, OutputPath: "test_output"})
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	if len(lines) != 4 || !strings.HasSuffix(content, "\n") {
+		t.Errorf("Unexpected TXT output:\n%s", content)
+	}
+	for _, line := range lines {
+		if !json.Valid([]byte(line)) {
+			t.Errorf("Invalid JSON line: %s", line)
+		}
+	}
+
+	fmt.Println("TestJSONLinesOutput Passed!")
+}
@@ -0,0 +1,162 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"generateTestData/backend/config"
+	"generateTestData/backend/models"
+	"generateTestData/backend/services"
+	"testing"
+	"time"
+
+	"github.com/hamba/avro/v2"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+func TestKafkaOutput(t *testing.T) {
+	config.AppConfig = &config.Config{GenerateDir: "."}
+	exportService := services.NewExportService()
+
+	// In-process stand-in for a Kafka cluster
+	cluster, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(3, "orders", "orders_avro"))
+	if err != nil {
+		t.Fatalf("Failed to start fake cluster: %v", err)
+	}
+	defer cluster.Close()
+	brokers := cluster.ListenAddrs()
+
+	records := make([]map[string]interface{}, 10)
+	for i := range records {
+		records[i] = map[string]interface{}{"id": int64(i + 1), "user": fmt.Sprintf("user%d", i%3)}
+	}
+
+	produce := func(options *services.KafkaOptions, schema avro.Schema, batches ...[]map[string]interface{}) *services.KafkaWriter {
+		writer, err := exportService.NewKafkaWriter(options, schema, nil)
+		if err != nil {
+			t.Fatalf("Failed to create Kafka writer: %v", err)
+		}
+		if err := writer.Open(); err != nil {
+			t.Fatalf("Failed to open Kafka writer: %v", err)
+		}
+		for _, batch := range batches {
+			if err := writer.WriteBatch(batch); err != nil {
+				t.Fatalf("Failed to produce batch: %v", err)
+			}
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("Failed to close Kafka writer: %v", err)
+		}
+		return writer
+	}
+	consume := func(topic string, n int) []*kgo.Record {
+		client, err := kgo.NewClient(kgo.SeedBrokers(brokers...), kgo.ConsumeTopics(topic), kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()))
+		if err != nil {
+			t.Fatalf("Failed to create consumer: %v", err)
+		}
+		defer client.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		var consumed []*kgo.Record
+		for len(consumed) < n {
+			fetches := client.PollFetches(ctx)
+			if ctx.Err() != nil {
+				t.Fatalf("Timed out after consuming %d of %d messages", len(consumed), n)
+			}
+			consumed = append(consumed, fetches.Records()...)
+		}
+		return consumed
+	}
+
+	// 1. JSON messages keyed by user, with templated headers, produced in two batches
+	writer := produce(&services.KafkaOptions{
+		Brokers:  brokers,
+		Topic:    "orders",
+		KeyField: "user",
+		Headers:  map[string]string{"source": "generateTestData", "order-id": "{{.id}}"},
+	}, nil, records[:4], records[4:])
+
+	var total int64
+	for _, count := range writer.PartitionCounts() {
+		total += count
+	}
+	if total != 10 {
+		t.Errorf("Expected 10 acknowledged messages, got %v", writer.PartitionCounts())
+	}
+
+	keyPartitions := map[string]int32{}
+	for _, message := range consume("orders", 10) {
+		var value map[string]interface{}
+		if err := json.Unmarshal(message.Value, &value); err != nil {
+			t.Fatalf("Invalid JSON message: %s", message.Value)
+		}
+		if string(message.Key) != value["user"] {
+			t.Errorf("Expected key %v, got %s", value["user"], message.Key)
+		}
+		// The hash partitioner sends the same key to the same partition
+		if partition, ok := keyPartitions[string(message.Key)]; ok && partition != message.Partition {
+			t.Errorf("Key %s was spread over partitions %d and %d", message.Key, partition, message.Partition)
+		}
+		keyPartitions[string(message.Key)] = message.Partition
+
+		headers := map[string]string{}
+		for _, header := range message.Headers {
+			headers[header.Key] = string(header.Value)
+		}
+		if headers["source"] != "generateTestData" || headers["order-id"] != fmt.Sprint(value["id"]) {
+			t.Errorf("Unexpected headers: %v", headers)
+		}
+	}
+
+	// 2. Avro messages with manual partitioning
+	schema := avro.MustParse(`{"type":"record","name":"Order","fields":[{"name":"id","type":"long"},{"name":"user","type":"string"},{"name":"partition","type":"int"}]}`)
+	writer = produce(&services.KafkaOptions{
+		Brokers:        brokers,
+		Topic:          "orders_avro",
+		Format:         "avro",
+		Partitioner:    "manual",
+		PartitionField: "partition",
+		Acks:           "leader",
+	}, schema, []map[string]interface{}{
+		{"id": int64(1), "user": "a", "partition": 2},
+		{"id": int64(2), "user": "b", "partition": 2},
+		{"id": int64(3), "user": "c", "partition": 0},
+	})
+	if counts := writer.PartitionCounts(); counts[2] != 2 || counts[0] != 1 {
+		t.Errorf("Unexpected partition counts: %v", counts)
+	}
+	for _, message := range consume("orders_avro", 3) {
+		var order struct {
+			ID   int64  `avro:"id"`
+			User string `avro:"user"`
+		}
+		if err := avro.Unmarshal(schema, message.Value, &order); err != nil {
+			t.Errorf("Invalid Avro message: %v", err)
+		}
+	}
+
+	// 3. Rate limit spaces messages out
+	start := time.Now()
+	produce(&services.KafkaOptions{Brokers: brokers, Topic: "orders", RateLimit: 50}, nil, records[:6])
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("Expected rate limit to slow down producing, took %v", elapsed)
+	}
+
+	// 4. Invalid configurations are rejected when the task is created
+	taskService := services.NewTaskService()
+	for _, configuration := range []string{
+		`{"kafkaTopic":"orders"}`,
+		`{"kafkaBrokers":["localhost:9092"]}`,
+		`{"kafkaBrokers":["localhost:9092"],"kafkaTopic":"orders","kafkaAcks":"some"}`,
+		`{"kafkaBrokers":["localhost:9092"],"kafkaTopic":"orders","kafkaPartitioner":"manual"}`,
+		`{"kafkaBrokers":["localhost:9092"],"kafkaTopic":"orders","kafkaFormat":"template"}`,
+	} {
+		task := &models.Task{Name: "kafka", Type: models.TaskTypeJSON, Count: 1, JSONSchema: `{"id":1}`, OutputType: models.OutputTypeKafka, Configuration: configuration}
+		if err := taskService.CreateTask(task); err == nil {
+			t.Errorf("Expected error for configuration %s", configuration)
+		}
+	}
+
+	fmt.Println("TestKafkaOutput Passed!")
+}
@@ -0,0 +1,112 @@
+package test
+
+import (
+	"fmt"
+	"generateTestData/backend/models"
+	"generateTestData/backend/services"
+	"math"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestMaskingRules(t *testing.T) {
+	masker := services.NewMaskingService("test-salt")
+
+	mask := func(value interface{}, rule models.FieldRule, record map[string]interface{}) interface{} {
+		masked, err := masker.MaskValue(value, rule, record)
+		if err != nil {
+			t.Fatalf("MaskValue(%v, %s) failed: %v", value, rule.Type, err)
+		}
+		return masked
+	}
+
+	// 1. Partial mask keeps prefix and suffix
+	partial := models.FieldRule{Type: "partial", Parameters: map[string]interface{}{"keepPrefix": float64(3), "keepSuffix": float64(4)}}
+	if got := mask("13812341234", partial, nil); got != "138****1234" {
+		t.Errorf("Expected 138****1234, got %v", got)
+	}
+	negative := models.FieldRule{Type: "partial", Parameters: map[string]interface{}{"keepPrefix": float64(-1), "keepSuffix": float64(-3)}}
+	if got := mask("13812341234", negative, nil); got != "***********" {
+		t.Errorf("Expected negative lengths to keep nothing, got %v", got)
+	}
+
+	// 2. The same input maps to the same output, so joins across tables still work
+	rules := map[string]models.FieldRule{
+		"id":    {Type: "tokenize"},
+		"email": {Type: "hash", Parameters: map[string]interface{}{"length": float64(16)}},
+		"name":  {Type: "faker", Parameters: map[string]interface{}{"kind": "chinese_name"}},
+	}
+	customer, err := masker.MaskRecord(map[string]interface{}{"id": int64(100234), "email": "a@b.com", "name": "张三"}, rules)
+	if err != nil {
+		t.Fatalf("MaskRecord failed: %v", err)
+	}
+	order, err := services.NewMaskingService("test-salt").MaskRecord(map[string]interface{}{"id": int64(100234), "email": "a@b.com", "name": "张三"}, rules)
+	if err != nil {
+		t.Fatalf("MaskRecord failed: %v", err)
+	}
+	for column := range rules {
+		if customer[column] != order[column] {
+			t.Errorf("Column %s: expected deterministic masking, got %v and %v", column, customer[column], order[column])
+		}
+	}
+	if customer["id"] == int64(100234) {
+		t.Errorf("Expected id to be tokenized")
+	}
+	if _, ok := customer["id"].(int64); !ok {
+		t.Errorf("Expected tokenized id to stay int64, got %T", customer["id"])
+	}
+
+	// 3. Tokenization preserves the format of the input
+	token := mask("AB-1234-xy", models.FieldRule{Type: "tokenize"}, nil).(string)
+	if !regexp.MustCompile(`^[A-Z]{2}-[0-9]{4}-[a-z]{2}$`).MatchString(token) {
+		t.Errorf("Expected format-preserving token, got %s", token)
+	}
+
+	// 4. Integers near the type limits never fail; tokens out of range fall back to strings
+	seen := map[string]bool{}
+	for i := 0; i < 50; i++ {
+		for _, value := range []interface{}{int64(math.MaxInt64 - i), int64(math.MinInt64 + i), uint64(math.MaxUint64 - uint64(i)), uint64(math.MaxInt64 + 1 + uint64(i))} {
+			masked := mask(value, models.FieldRule{Type: "tokenize"}, nil)
+			switch masked.(type) {
+			case int64:
+				if _, ok := value.(int64); !ok {
+					t.Errorf("Expected unsigned %v not to be tokenized as int64", value)
+				}
+			case uint64:
+				if _, ok := value.(uint64); !ok {
+					t.Errorf("Expected signed %v not to be tokenized as uint64", value)
+				}
+			case string:
+			default:
+				t.Errorf("Unexpected token type %T for %v", masked, value)
+			}
+			token := fmt.Sprint(masked)
+			if len(token) != len(fmt.Sprint(value)) {
+				t.Errorf("Expected token of %v to keep its length, got %s", value, token)
+			}
+			if seen[token] {
+				t.Errorf("Duplicate token %s", token)
+			}
+			seen[token] = true
+		}
+	}
+
+	// 5. Date shifting keyed on another column keeps intervals between dates
+	shift := models.FieldRule{Type: "date_shift", Parameters: map[string]interface{}{"maxDays": float64(60), "keyColumn": "patient_id"}}
+	record := map[string]interface{}{"patient_id": 7}
+	admitted := mask("2024-03-01", shift, record).(string)
+	discharged := mask("2024-03-11", shift, record).(string)
+	a, _ := time.Parse("2006-01-02", admitted)
+	d, _ := time.Parse("2006-01-02", discharged)
+	if d.Sub(a) != 10*24*time.Hour {
+		t.Errorf("Expected shifted dates to stay 10 days apart, got %s and %s", admitted, discharged)
+	}
+
+	// 6. Null rule
+	if got := mask("secret", models.FieldRule{Type: "null"}, nil); got != nil {
+		t.Errorf("Expected nil, got %v", got)
+	}
+
+	fmt.Println("TestMaskingRules Passed!")
+}
@@ -0,0 +1,238 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"generateTestData/backend/config"
+	"generateTestData/backend/controllers"
+	"generateTestData/backend/models"
+	"generateTestData/backend/services"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestMockAPI(t *testing.T) {
+	dbPath := "test_mock_api.db"
+	os.Remove(dbPath)
+	defer os.Remove(dbPath)
+	config.AppConfig = &config.Config{DBPath: dbPath, GenerateDir: "."}
+	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	models.DB = db
+	if err := db.AutoMigrate(&models.Task{}, &models.TaskRun{}, &models.TaskRunLog{}, &models.MockCollection{}, &models.MockRecord{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	// Same routes as main.go
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	mockController := controllers.NewMockController()
+	mock := router.Group("/mock/:name", mockController.Simulate)
+	mock.GET("", mockController.List)
+	mock.POST("", mockController.Create)
+	mock.GET("/:id", mockController.Get)
+	mock.PUT("/:id", mockController.Update)
+	mock.PATCH("/:id", mockController.Update)
+	mock.DELETE("/:id", mockController.Delete)
+
+	call := func(method, path, body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return recorder
+	}
+	list := func(path string) ([]map[string]interface{}, string) {
+		recorder := call("GET", path, "")
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("GET %s returned %d: %s", path, recorder.Code, recorder.Body)
+		}
+		var records []map[string]interface{}
+		json.Unmarshal(recorder.Body.Bytes(), &records)
+		return records, recorder.Header().Get("X-Total-Count")
+	}
+	publish := func(options *services.MockAPIOptions, records []map[string]interface{}) {
+		writer, err := services.NewExportService().NewMockAPIWriter(options, 1)
+		if err != nil {
+			t.Fatalf("Failed to create mock writer: %v", err)
+		}
+		if err := writer.Open(); err != nil {
+			t.Fatalf("Failed to open mock writer: %v", err)
+		}
+		if err := writer.WriteBatch(records); err != nil {
+			t.Fatalf("Failed to write records: %v", err)
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("Failed to publish collection: %v", err)
+		}
+	}
+
+	users := make([]map[string]interface{}, 30)
+	for i := range users {
+		city := "Beijing"
+		if i%2 == 1 {
+			city = "Shanghai"
+		}
+		users[i] = map[string]interface{}{
+			"id":      i + 1,
+			"name":    fmt.Sprintf("user%02d", i+1),
+			"age":     (i % 5) * 10,
+			"city":    city,
+			"profile": map[string]interface{}{"level": i % 3},
+		}
+	}
+	publish(&services.MockAPIOptions{Name: "users"}, users)
+
+	// 1. Pagination
+	records, total := list("/mock/users?_page=2&_limit=10")
+	if total != "30" || len(records) != 10 || fmt.Sprint(records[0]["id"]) != "11" {
+		t.Errorf("Unexpected page: total=%s len=%d first=%v", total, len(records), records[0]["id"])
+	}
+
+	// 2. Filtering and multi-field sorting
+	records, total = list("/mock/users?city=Beijing&age_gte=20&_sort=age,id&_order=desc,asc")
+	if total != "9" {
+		t.Errorf("Expected 9 filtered records, got %s", total)
+	}
+	for i, record := range records {
+		if record["city"] != "Beijing" || record["age"].(float64) < 20 {
+			t.Errorf("Record does not match filter: %v", record)
+		}
+		if i > 0 {
+			prev := records[i-1]
+			if prev["age"].(float64) < record["age"].(float64) ||
+				(prev["age"] == record["age"] && prev["id"].(float64) > record["id"].(float64)) {
+				t.Errorf("Records not sorted: %v before %v", prev, record)
+			}
+		}
+	}
+	if _, total = list("/mock/users?profile.level=2"); total != "10" {
+		t.Errorf("Expected 10 records for nested filter, got %s", total)
+	}
+	if _, total = list("/mock/users?name_like=USER0"); total != "9" {
+		t.Errorf("Expected 9 records for like filter, got %s", total)
+	}
+	if _, total = list("/mock/users?city=Beijing&city=Shanghai&age_ne=0"); total != "24" {
+		t.Errorf("Expected 24 records for multi-value filter, got %s", total)
+	}
+
+	// 3. Get by id and not found
+	if recorder := call("GET", "/mock/users/5", ""); recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), `"user05"`) {
+		t.Errorf("Unexpected GET by id: %d %s", recorder.Code, recorder.Body)
+	}
+	if recorder := call("GET", "/mock/users/999", ""); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for missing record, got %d", recorder.Code)
+	}
+	if recorder := call("GET", "/mock/nope", ""); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for missing collection, got %d", recorder.Code)
+	}
+
+	// 4. Create, replace, merge and delete
+	if recorder := call("POST", "/mock/users", `{"name":"new"}`); recorder.Code != http.StatusCreated || !strings.Contains(recorder.Body.String(), `"id":31`) {
+		t.Errorf("Unexpected POST: %d %s", recorder.Code, recorder.Body)
+	}
+	if recorder := call("POST", "/mock/users", `{"id":5}`); recorder.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for duplicate id, got %d", recorder.Code)
+	}
+	if recorder := call("PUT", "/mock/users/31", `{"name":"replaced"}`); recorder.Code != http.StatusOK || recorder.Body.String() != `{"id":31,"name":"replaced"}` {
+		t.Errorf("Unexpected PUT: %d %s", recorder.Code, recorder.Body)
+	}
+	if recorder := call("PATCH", "/mock/users/5", `{"age":99}`); recorder.Code != http.StatusOK ||
+		!strings.Contains(recorder.Body.String(), `"age":99`) || !strings.Contains(recorder.Body.String(), `"name":"user05"`) {
+		t.Errorf("Unexpected PATCH: %d %s", recorder.Code, recorder.Body)
+	}
+	if recorder := call("DELETE", "/mock/users/6", ""); recorder.Code != http.StatusNoContent {
+		t.Errorf("Unexpected DELETE: %d", recorder.Code)
+	}
+	if recorder := call("GET", "/mock/users/6", ""); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected deleted record to be gone, got %d", recorder.Code)
+	}
+
+	// 5. SQLite storage is written through; memory storage is not persisted
+	var count int64
+	db.Model(&models.MockRecord{}).Where("collection = ?", "users").Count(&count)
+	if count != 30 {
+		t.Errorf("Expected 30 persisted records, got %d", count)
+	}
+	var row models.MockRecord
+	db.Where("collection = ? AND record_id = ?", "users", "31").First(&row)
+	if row.Data != `{"id":31,"name":"replaced"}` {
+		t.Errorf("Unexpected persisted record: %s", row.Data)
+	}
+	publish(&services.MockAPIOptions{Name: "temp", Storage: "memory"}, users[:3])
+	db.Model(&models.MockRecord{}).Where("collection = ?", "temp").Count(&count)
+	if _, total = list("/mock/temp"); total != "3" || count != 0 {
+		t.Errorf("Expected 3 in-memory records and none persisted, got %s and %d", total, count)
+	}
+
+	// 6. Aborted publishing leaves the existing collection untouched; append mode keeps it
+	writer, _ := services.NewExportService().NewMockAPIWriter(&services.MockAPIOptions{Name: "users"}, 1)
+	writer.Open()
+	writer.WriteBatch(users[:1])
+	writer.Abort()
+	if _, total = list("/mock/users"); total != "30" {
+		t.Errorf("Expected aborted publish to keep 30 records, got %s", total)
+	}
+	publish(&services.MockAPIOptions{Name: "users", Append: true}, []map[string]interface{}{{"id": 100}})
+	if _, total = list("/mock/users"); total != "31" {
+		t.Errorf("Expected appended collection to have 31 records, got %s", total)
+	}
+
+	// 7. Latency and error injection
+	publish(&services.MockAPIOptions{Name: "flaky", LatencyMs: 30, ErrorRate: 1, ErrorStatus: 503}, users[:1])
+	start := time.Now()
+	if recorder := call("GET", "/mock/flaky", ""); recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected injected 503, got %d", recorder.Code)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("Expected injected latency, took %v", elapsed)
+	}
+
+	// 8. A task publishes its generated data
+	task := models.Task{
+		Name:          "Mock API Test",
+		Type:          models.TaskTypeJSON,
+		Count:         25,
+		JSONSchema:    `{"name": "test"}`,
+		OutputType:    models.OutputTypeMockAPI,
+		Configuration: `{"mockName":"generated"}`,
+	}
+	taskService := services.NewTaskService()
+	if err := taskService.CreateTask(&task); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+	if _, err := taskService.ExecuteTask(task.ID, models.RunTriggerAPI); err != nil {
+		t.Fatalf("ExecuteTask failed: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var current models.Task
+		db.First(&current, task.ID)
+		if current.Status == models.TaskStatusCompleted {
+			break
+		}
+		if current.Status == models.TaskStatusFailed {
+			t.Fatalf("Task failed: %s", current.ErrorMsg)
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Task timeout")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if _, total = list("/mock/generated"); total != "25" {
+		t.Errorf("Expected 25 generated records, got %s", total)
+	}
+	if err := taskService.CreateTask(&models.Task{Name: "bad", Type: models.TaskTypeJSON, Count: 1, JSONSchema: `{}`, OutputType: models.OutputTypeMockAPI, Configuration: `{"mockName":"a/b"}`}); err == nil {
+		t.Errorf("Expected error for invalid collection name")
+	}
+
+	fmt.Println("TestMockAPI Passed!")
+}
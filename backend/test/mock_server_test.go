@@ -68,7 +68,7 @@ func TestPushToMockServer(t *testing.T) {
 	}
 	models.DB = db
 
-	err = db.AutoMigrate(&models.Task{})
+	err = db.AutoMigrate(&models.Task{}, &models.TaskRun{}, &models.TaskRunLog{})
 	if err != nil {
 		t.Fatalf("Failed to migrate: %v", err)
 	}
@@ -99,7 +99,7 @@ func TestPushToMockServer(t *testing.T) {
 
 	// 4. Execute Task
 	taskService := services.NewTaskService()
-	err = taskService.ExecuteTask(task.ID)
+	_, err = taskService.ExecuteTask(task.ID, models.RunTriggerAPI)
 	if err != nil {
 		t.Fatalf("ExecuteTask failed: %v", err)
 	}
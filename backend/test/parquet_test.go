@@ -0,0 +1,152 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"generateTestData/backend/config"
+	"generateTestData/backend/models"
+	"generateTestData/backend/services"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func TestParquetExport(t *testing.T) {
+	config.AppConfig = &config.Config{GenerateDir: "."}
+	exportService := services.NewExportService()
+
+	// 1. Table schema: typed columns, written across several batches and row groups
+	tableFile := "test_parquet_table.parquet"
+	defer os.Remove(tableFile)
+
+	tableInfo := &models.TableInfo{
+		TableName: "users",
+		Columns: []models.ColumnInfo{
+			{Name: "id", Type: "bigint"},
+			{Name: "age", Type: "int"},
+			{Name: "score", Type: "decimal"},
+			{Name: "active", Type: "tinyint(1)"},
+			{Name: "birthday", Type: "date"},
+			{Name: "created_at", Type: "datetime"},
+			{Name: "name", Type: "varchar"},
+		},
+	}
+	writer, err := exportService.NewParquetWriter(tableFile, services.RecordSchemaFromTable(tableInfo),
+		&services.ParquetOptions{Compression: "zstd", RowGroupSize: 4})
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	if err := writer.Open(); err != nil {
+		t.Fatalf("Failed to open writer: %v", err)
+	}
+	createdAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	for batch := 0; batch < 3; batch++ {
+		records := make([]map[string]interface{}, 3)
+		for i := range records {
+			id := batch*3 + i + 1
+			records[i] = map[string]interface{}{
+				"id": id, "age": "30", "score": 88.5, "active": int64(1),
+				"birthday": "1990-05-17", "created_at": createdAt, "name": nil,
+			}
+		}
+		if err := writer.WriteBatch(records); err != nil {
+			t.Fatalf("Failed to write batch: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+
+	rows, file := readParquet(t, tableFile)
+	if len(rows) != 9 {
+		t.Fatalf("Expected 9 rows, got %d", len(rows))
+	}
+	if n := len(file.RowGroups()); n != 3 {
+		t.Errorf("Expected 3 row groups, got %d", n)
+	}
+	first := rows[0]
+	if first["id"] != int64(1) || first["age"] != int32(30) || first["score"] != 88.5 || first["active"] != true || first["name"] != nil {
+		t.Errorf("Unexpected typed values: %v", first)
+	}
+	// DATE is stored as days since the Unix epoch
+	if first["birthday"] != int32(time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC).Unix()/86400) {
+		t.Errorf("Unexpected date value: %v", first["birthday"])
+	}
+
+	// 2. JSON schema: nested structs and lists
+	jsonFile := "test_parquet_json.parquet"
+	defer os.Remove(jsonFile)
+
+	var schema map[string]interface{}
+	json.Unmarshal([]byte(`{"id": 1, "user": {"name": "", "vip": true}, "tags": [""], "items": [{"sku": "", "qty": 0}]}`), &schema)
+	rules := map[string]models.FieldRule{"id": {Type: "sequence"}}
+	parquetSchema, err := services.RecordSchemaFromJSON(schema, rules)
+	if err != nil {
+		t.Fatalf("Failed to build schema: %v", err)
+	}
+	writer, err = exportService.NewParquetWriter(jsonFile, parquetSchema, &services.ParquetOptions{Compression: "gzip"})
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	if err := writer.Open(); err != nil {
+		t.Fatalf("Failed to open writer: %v", err)
+	}
+	err = writer.WriteBatch([]map[string]interface{}{{
+		"id":    1,
+		"user":  map[string]interface{}{"name": "alice", "vip": true},
+		"tags":  []interface{}{"a", "b"},
+		"items": []interface{}{map[string]interface{}{"sku": "S1", "qty": 2.0}},
+	}})
+	if err != nil {
+		t.Fatalf("Failed to write JSON batch: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+
+	rows, file = readParquet(t, jsonFile)
+	if len(rows) != 1 {
+		t.Fatalf("Expected 1 row, got %d", len(rows))
+	}
+	if id, ok := file.Schema().Lookup("id"); !ok || id.Node.Type().Kind() != parquet.Int64 {
+		t.Errorf("Expected id to be stored as INT64")
+	}
+	user, _ := rows[0]["user"].(map[string]interface{})
+	tags, _ := rows[0]["tags"].([]interface{})
+	items, _ := rows[0]["items"].([]interface{})
+	if user["name"] != "alice" || len(tags) != 2 || len(items) != 1 {
+		t.Errorf("Unexpected nested values: %v", rows[0])
+	}
+
+	// 3. Unsupported compression is rejected
+	if _, err := exportService.NewParquetWriter("test_parquet_bad.parquet", parquetSchema, &services.ParquetOptions{Compression: "lz5"}); err == nil {
+		t.Errorf("Expected error for unsupported compression")
+	}
+
+	fmt.Println("TestParquetExport Passed!")
+}
+
+func readParquet(t *testing.T, fileName string) ([]map[string]interface{}, *parquet.File) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", fileName, err)
+	}
+	t.Cleanup(func() { f.Close() })
+	stat, _ := f.Stat()
+	file, err := parquet.OpenFile(f, stat.Size())
+	if err != nil {
+		t.Fatalf("Failed to read parquet file: %v", err)
+	}
+
+	reader := parquet.NewGenericReader[map[string]interface{}](f, file.Schema())
+	rows := make([]map[string]interface{}, file.NumRows())
+	for i := range rows {
+		rows[i] = map[string]interface{}{}
+	}
+	if n, err := reader.Read(rows); n != len(rows) {
+		t.Fatalf("Failed to read rows: read %d, %v", n, err)
+	}
+	return rows, file
+}
@@ -0,0 +1,197 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"generateTestData/backend/config"
+	"generateTestData/backend/models"
+	"generateTestData/backend/services"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestTaskQueue(t *testing.T) {
+	dbPath := "test_queue.db"
+	targetPath := "test_queue_target.db"
+	maskSourcePath := "test_queue_mask_source.db"
+	outputDir := "test_queue_output"
+	for _, p := range []string{dbPath, targetPath, maskSourcePath, outputDir} {
+		os.RemoveAll(p)
+		defer os.RemoveAll(p)
+	}
+	os.MkdirAll(outputDir, 0755)
+	config.AppConfig = &config.Config{DBPath: dbPath, GenerateDir: outputDir, WorkerPoolSize: 1}
+	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	models.DB = db
+	if err := db.AutoMigrate(&models.DataSource{}, &models.Task{}, &models.TaskRun{}, &models.TaskRunLog{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	taskService := services.NewTaskService()
+	newTask := func(name string, priority int) models.Task {
+		task := models.Task{Name: name, Type: models.TaskTypeJSON, Count: 200, JSONSchema: `{"id":1}`, OutputType: models.OutputTypeJSONL, OutputPath: name, Priority: priority}
+		db.Create(&task)
+		return task
+	}
+	waitIdle := func() []models.TaskRun {
+		var runs []models.TaskRun
+		deadline := time.Now().Add(10 * time.Second)
+		for time.Now().Before(deadline) {
+			var active int64
+			db.Model(&models.TaskRun{}).Where("finished_at IS NULL").Count(&active)
+			if active == 0 {
+				break
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		db.Order("id").Find(&runs)
+		return runs
+	}
+
+	// 1. Concurrent requests enqueue a task only once while it is queued or running
+	single := newTask("single", 0)
+	db.Model(&single).Update("count", 200000)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	start := make(chan struct{})
+	accepted := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, err := taskService.ExecuteTask(single.ID, models.RunTriggerAPI)
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				accepted++
+			} else if !errors.Is(err, services.ErrTaskRunning) {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+	if accepted != 1 {
+		t.Errorf("Expected exactly one accepted request, got %d", accepted)
+	}
+	if runs := waitIdle(); len(runs) != 1 || runs[0].Status != models.TaskStatusCompleted {
+		t.Fatalf("Expected one completed run, got %+v", runs)
+	}
+
+	// 2. Editing a queued task does not reset its status
+	db.Model(&single).Update("status", models.TaskStatusQueued)
+	edited := single
+	edited.Status = models.TaskStatusPending
+	edited.Count = 300
+	if err := taskService.UpdateTask(&edited); err != nil {
+		t.Fatalf("UpdateTask failed: %v", err)
+	}
+	var saved models.Task
+	db.First(&saved, single.ID)
+	if saved.Status != models.TaskStatusQueued || saved.Count != 300 {
+		t.Errorf("Expected status to be kept and count updated, got %s %d", saved.Status, saved.Count)
+	}
+	db.Model(&single).Update("status", models.TaskStatusCompleted)
+
+	// 3. Queued runs survive a restart and start by priority; interrupted runs fail
+	queue := []models.Task{newTask("low-1", 0), newTask("high", 5), newTask("low-2", 0)}
+	for _, task := range queue {
+		run := models.TaskRun{TaskID: task.ID, Trigger: models.RunTriggerAPI, Status: models.TaskStatusQueued, Priority: task.Priority, Seed: 1, QueuedAt: time.Now()}
+		run.SetSnapshot(&task)
+		db.Create(&run)
+		db.Model(&task).Update("status", models.TaskStatusQueued)
+	}
+	interrupted := newTask("interrupted", 0)
+	db.Model(&interrupted).Update("status", models.TaskStatusRunning)
+	now := time.Now()
+	db.Create(&models.TaskRun{TaskID: interrupted.ID, Status: models.TaskStatusRunning, QueuedAt: now, StartedAt: &now})
+	if err := services.StartTaskQueue(); err != nil {
+		t.Fatalf("StartTaskQueue failed: %v", err)
+	}
+	started := map[string]time.Time{}
+	for _, run := range waitIdle()[1:] {
+		var task models.Task
+		db.First(&task, run.TaskID)
+		if task.Name == "interrupted" {
+			if run.Status != models.TaskStatusFailed || task.Status != models.TaskStatusFailed {
+				t.Errorf("Expected interrupted run to fail, got %s / %s", run.Status, task.Status)
+			}
+			continue
+		}
+		if run.Status != models.TaskStatusCompleted || run.StartedAt == nil {
+			t.Fatalf("Expected %s to complete, got %+v", task.Name, run)
+		}
+		started[task.Name] = *run.StartedAt
+	}
+	if !started["high"].Before(started["low-1"]) || !started["low-1"].Before(started["low-2"]) {
+		t.Errorf("Unexpected start order: %v", started)
+	}
+
+	// 4. Tasks on the same data source respect its concurrency cap
+	config.AppConfig.WorkerPoolSize = 3
+	target, _ := gorm.Open(sqlite.Open(targetPath), &gorm.Config{})
+	target.Exec("CREATE TABLE events (id INTEGER, name TEXT)")
+	source := models.DataSource{Name: "target", Type: "sqlite", Database: targetPath, MaxConcurrency: 1}
+	db.Create(&source)
+	var capped []uint
+	for _, name := range []string{"load-1", "load-2"} {
+		task := models.Task{Name: name, Type: models.TaskTypeDatabase, DataSourceID: &source.ID, TableName: "events", Count: 2000, OutputType: models.OutputTypeDatabase}
+		db.Create(&task)
+		run, err := taskService.ExecuteTask(task.ID, models.RunTriggerAPI)
+		if err != nil {
+			t.Fatalf("ExecuteTask failed: %v", err)
+		}
+		capped = append(capped, run.ID)
+	}
+	waitIdle()
+	var first, second models.TaskRun
+	db.First(&first, capped[0])
+	db.First(&second, capped[1])
+	if first.Status != models.TaskStatusCompleted || second.Status != models.TaskStatusCompleted {
+		t.Fatalf("Expected both loads to complete: %s %s / %s %s", first.Status, first.ErrorMsg, second.Status, second.ErrorMsg)
+	}
+	if second.StartedAt.Before(*first.FinishedAt) {
+		t.Errorf("Expected runs on the same data source not to overlap")
+	}
+
+	// 5. A mask task writing to the capped data source counts against its cap as well
+	maskSource, _ := gorm.Open(sqlite.Open(maskSourcePath), &gorm.Config{})
+	maskSource.Exec("CREATE TABLE customers (id INTEGER, name TEXT)")
+	maskSource.Exec("INSERT INTO customers VALUES (1, 'a'), (2, 'b')")
+	target.Exec("CREATE TABLE customers (id INTEGER, name TEXT)")
+	other := models.DataSource{Name: "mask source", Type: "sqlite", Database: maskSourcePath}
+	db.Create(&other)
+	load := models.Task{Name: "load-3", Type: models.TaskTypeDatabase, DataSourceID: &source.ID, TableName: "events", Count: 2000, OutputType: models.OutputTypeDatabase}
+	mask := models.Task{Name: "mask", Type: models.TaskTypeMask, DataSourceID: &other.ID, TableName: "customers", OutputType: models.OutputTypeDatabase,
+		FieldRules: `{"name":{"type":"null"}}`, Configuration: fmt.Sprintf(`{"salt":"s","targetDataSourceId":%d}`, source.ID)}
+	db.Create(&load)
+	db.Create(&mask)
+	loadRun, err := taskService.ExecuteTask(load.ID, models.RunTriggerAPI)
+	if err != nil {
+		t.Fatalf("ExecuteTask failed: %v", err)
+	}
+	maskRun, err := taskService.ExecuteTask(mask.ID, models.RunTriggerAPI)
+	if err != nil {
+		t.Fatalf("ExecuteTask failed: %v", err)
+	}
+	waitIdle()
+	db.First(loadRun, loadRun.ID)
+	db.First(maskRun, maskRun.ID)
+	if loadRun.Status != models.TaskStatusCompleted || maskRun.Status != models.TaskStatusCompleted {
+		t.Fatalf("Expected load and mask to complete: %s %s / %s %s", loadRun.Status, loadRun.ErrorMsg, maskRun.Status, maskRun.ErrorMsg)
+	}
+	if maskRun.StartedAt.Before(*loadRun.FinishedAt) {
+		t.Errorf("Expected the mask run to wait for the load on its target data source")
+	}
+
+	fmt.Println("TestTaskQueue Passed!")
+}
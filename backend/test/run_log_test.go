@@ -0,0 +1,162 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"generateTestData/backend/config"
+	"generateTestData/backend/models"
+	"generateTestData/backend/services"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestRunLogs(t *testing.T) {
+	dbPath := "test_run_log.db"
+	targetPath := "test_run_log_target.db"
+	outputDir := "test_run_log_output"
+	for _, p := range []string{dbPath, targetPath, outputDir} {
+		os.RemoveAll(p)
+		defer os.RemoveAll(p)
+	}
+	os.MkdirAll(outputDir, 0755)
+	config.AppConfig = &config.Config{DBPath: dbPath, GenerateDir: outputDir, LogRetentionDays: 7}
+	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	models.DB = db
+	if err := db.AutoMigrate(&models.DataSource{}, &models.Task{}, &models.TaskSchedule{}, &models.TaskRun{}, &models.TaskRunLog{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	taskService := services.NewTaskService()
+	execute := func(task *models.Task) (*models.TaskRun, error) {
+		err := taskService.ExecuteTaskSync(task.ID, models.RunTriggerAPI)
+		runs, _ := taskService.GetTaskRuns(task.ID)
+		return &runs[0], err
+	}
+	logs := func(run *models.TaskRun, filter services.RunLogFilter) []models.TaskRunLog {
+		entries, err := taskService.GetRunLogs(run.TaskID, run.ID, &filter)
+		if err != nil {
+			t.Fatalf("GetRunLogs failed: %v", err)
+		}
+		return entries
+	}
+
+	// 1. A successful run logs the config summary, phases, batch timings and the result
+	task := models.Task{Name: "events", Type: models.TaskTypeJSON, Count: 2500, JSONSchema: `{"id":1,"score":1}`, OutputType: models.OutputTypeJSONL, OutputPath: "events"}
+	db.Create(&task)
+	run, err := execute(&task)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	all := logs(run, services.RunLogFilter{})
+	if len(all) < 4 || all[0].Message != "开始执行" || !strings.HasPrefix(all[len(all)-1].Message, "执行完成，耗时: ") {
+		t.Fatalf("Unexpected logs: %+v", all)
+	}
+	var summary map[string]interface{}
+	json.Unmarshal([]byte(all[0].Data), &summary)
+	if summary["seed"] != float64(run.Seed) || summary["count"] != float64(2500) || summary["outputType"] != string(models.OutputTypeJSONL) {
+		t.Errorf("Unexpected config summary: %s", all[0].Data)
+	}
+	if batches := logs(run, services.RunLogFilter{Keyword: "批次完成"}); len(batches) != 3 || batches[0].Level != models.LogLevelDebug {
+		t.Errorf("Expected 3 batch entries, got %+v", batches)
+	}
+	if info := logs(run, services.RunLogFilter{Level: models.LogLevelInfo}); len(info) == 0 || len(info) >= len(all) {
+		t.Errorf("Expected level filter to drop debug entries, got %d of %d", len(info), len(all))
+	}
+	if _, err := taskService.GetRunLogs(task.ID, run.ID, &services.RunLogFilter{Level: "verbose"}); err == nil {
+		t.Errorf("Expected invalid level to be rejected")
+	}
+
+	// 2. Script errors name the field, rule and row
+	db.Model(&task).Update("field_rules", `{"score":{"type":"custom","parameters":{"script":"rowIndex < 3 ? 1 : undefinedValue"}}}`)
+	run, err = execute(&task)
+	if err == nil {
+		t.Fatalf("Expected the script to fail")
+	}
+	failures := logs(run, services.RunLogFilter{Level: models.LogLevelError})
+	if len(failures) != 1 || failures[0].Field != "score" || !strings.Contains(failures[0].Data, `"rule":"custom"`) || !strings.Contains(failures[0].Data, `"row":3`) {
+		t.Errorf("Unexpected error entries: %+v", failures)
+	}
+	if byField := logs(run, services.RunLogFilter{Field: "score"}); len(byField) != 1 {
+		t.Errorf("Expected field filter to match the script error, got %+v", byField)
+	}
+
+	// 3. Rejected rows are logged with their values, up to a limit
+	target, _ := gorm.Open(sqlite.Open(targetPath), &gorm.Config{})
+	target.Exec("CREATE TABLE items (id INTEGER, score INTEGER CHECK (score < 50))")
+	source := models.DataSource{Name: "target", Type: "sqlite", Database: targetPath}
+	db.Create(&source)
+	rejecting := models.Task{
+		Name:          "rejecting",
+		Type:          models.TaskTypeDatabase,
+		DataSourceID:  &source.ID,
+		TableName:     "items",
+		Count:         150,
+		FieldRules:    `{"id":{"type":"sequence"},"score":{"type":"fixed","parameters":{"value":80}}}`,
+		OutputType:    models.OutputTypeDatabase,
+		Configuration: `{"errorPolicy":"skip"}`,
+	}
+	db.Create(&rejecting)
+	run, err = execute(&rejecting)
+	if err != nil || run.RejectedCount != 150 {
+		t.Fatalf("Expected all rows to be rejected: %v %+v", err, run)
+	}
+	rejected := logs(run, services.RunLogFilter{Keyword: "记录被拒绝"})
+	if len(rejected) != 100 || !strings.Contains(rejected[0].Data, `"score":80`) || rejected[0].Level != models.LogLevelWarn {
+		t.Errorf("Expected 100 rejected rows with values, got %d: %+v", len(rejected), rejected[0])
+	}
+	if capped := logs(run, services.RunLogFilter{Keyword: "共 150 行被拒绝"}); len(capped) != 1 {
+		t.Errorf("Expected a summary of rejected rows beyond the limit")
+	}
+
+	// 4. HTTP retries are logged with the response status
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	pushing := models.Task{Name: "pushing", Type: models.TaskTypeJSON, Count: 10, JSONSchema: `{"id":1}`, OutputType: models.OutputTypeHTTP,
+		Configuration: fmt.Sprintf(`{"httpUrl":%q,"httpRetryBackoffMs":1}`, server.URL)}
+	db.Create(&pushing)
+	run, err = execute(&pushing)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	retries := logs(run, services.RunLogFilter{Level: models.LogLevelWarn})
+	if len(retries) != 1 || !strings.Contains(retries[0].Message, "第 1 次重试") || !strings.Contains(retries[0].Data, `"status":503`) {
+		t.Errorf("Unexpected retry entries: %+v", retries)
+	}
+
+	// 5. Logs older than the retention period are pruned
+	db.Model(&models.TaskRunLog{}).Where("run_id = ?", run.ID).Update("created_at", time.Now().AddDate(0, 0, -8))
+	if pruned, err := services.PruneRunLogs(); err != nil || pruned == 0 {
+		t.Errorf("Expected old logs to be pruned: %d %v", pruned, err)
+	}
+	if remaining := logs(run, services.RunLogFilter{}); len(remaining) != 0 {
+		t.Errorf("Expected pruned run to have no logs, got %d", len(remaining))
+	}
+
+	// 6. Deleting the task removes its logs
+	taskService.DeleteTask(task.ID)
+	var count int64
+	db.Model(&models.TaskRunLog{}).Where("task_id = ?", task.ID).Count(&count)
+	if count != 0 {
+		t.Errorf("Expected logs to be deleted with the task, got %d", count)
+	}
+
+	fmt.Println("TestRunLogs Passed!")
+}
@@ -0,0 +1,170 @@
+package test
+
+import (
+	"fmt"
+	"generateTestData/backend/config"
+	"generateTestData/backend/models"
+	"generateTestData/backend/services"
+	"os"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestTaskSchedule(t *testing.T) {
+	dbPath := "test_schedule.db"
+	outputDir := "test_schedule_output"
+	os.Remove(dbPath)
+	os.RemoveAll(outputDir)
+	defer os.Remove(dbPath)
+	defer os.RemoveAll(outputDir)
+	os.MkdirAll(outputDir, 0755)
+	config.AppConfig = &config.Config{DBPath: dbPath, GenerateDir: outputDir}
+	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	models.DB = db
+	if err := db.AutoMigrate(&models.DataSource{}, &models.Task{}, &models.TaskSchedule{}, &models.TaskRun{}, &models.TaskRunLog{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	newTask := func(name string) models.Task {
+		task := models.Task{Name: name, Type: models.TaskTypeJSON, Count: 5, JSONSchema: `{"id":1}`, OutputType: models.OutputTypeJSONL, OutputPath: name}
+		db.Create(&task)
+		return task
+	}
+	runCount := func(id uint) int64 {
+		var schedule models.TaskSchedule
+		db.First(&schedule, id)
+		return schedule.RunCount
+	}
+	waitFor := func(id uint, count int64) {
+		deadline := time.Now().Add(10 * time.Second)
+		for runCount(id) < count && time.Now().Before(deadline) {
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+
+	recurring := newTask("recurring")
+	scheduler := services.NewSchedulerService()
+
+	// 1. Invalid expressions, time zones, policies and tasks are rejected
+	for _, invalid := range []models.TaskSchedule{
+		{TaskID: recurring.ID, Cron: "61 * * * *"},
+		{TaskID: recurring.ID, Cron: "@every 1m", TimeZone: "Mars/Olympus"},
+		{TaskID: recurring.ID, Cron: "@recurring", OverlapPolicy: "queue"},
+		{TaskID: 999, Cron: "@recurring"},
+	} {
+		if err := scheduler.Create(&invalid); err == nil {
+			t.Errorf("Expected invalid schedule to be rejected: %+v", invalid)
+		}
+	}
+
+	// 2. An enabled schedule runs the task and records the result
+	schedule := models.TaskSchedule{TaskID: recurring.ID, Cron: "@every 1s", TimeZone: "Asia/Shanghai", Enabled: true}
+	if err := scheduler.Create(&schedule); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if schedule.OverlapPolicy != models.OverlapSkip || schedule.CatchUpPolicy != models.CatchUpNone || schedule.NextRunAt == nil {
+		t.Errorf("Expected default policies and next run time: %+v", schedule)
+	}
+	if err := scheduler.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	waitFor(schedule.ID, 1)
+	var saved models.TaskSchedule
+	db.First(&saved, schedule.ID)
+	if saved.RunCount < 1 || saved.LastRunAt == nil || saved.LastError != "" {
+		t.Errorf("Expected a successful scheduled run: %+v", saved)
+	}
+	var task models.Task
+	db.First(&task, recurring.ID)
+	if task.Status != models.TaskStatusCompleted {
+		t.Errorf("Expected task to be completed, got %s (%s)", task.Status, task.ErrorMsg)
+	}
+
+	// 3. Disabling a schedule stops further runs
+	schedule.Enabled = false
+	if err := scheduler.Update(&schedule); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	before := runCount(schedule.ID)
+	time.Sleep(1500 * time.Millisecond)
+	if after := runCount(schedule.ID); after != before {
+		t.Errorf("Expected no runs after disabling, got %d -> %d", before, after)
+	}
+	scheduler.Stop()
+
+	// 4. Runs missed while the service was down are caught up according to the policy
+	missedSince := time.Now().Add(-150 * time.Minute)
+	catchUp := map[string]*models.TaskSchedule{}
+	for _, policy := range []string{models.CatchUpNone, models.CatchUpOnce, models.CatchUpAll} {
+		task := newTask("catch-up-" + policy)
+		catchUp[policy] = &models.TaskSchedule{TaskID: task.ID, Cron: "@every 1h", Enabled: true, OverlapPolicy: models.OverlapSkip, CatchUpPolicy: policy, NextRunAt: &missedSince}
+		db.Create(catchUp[policy])
+	}
+	scheduler = services.NewSchedulerService()
+	if err := scheduler.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	waitFor(catchUp[models.CatchUpAll].ID, 3)
+	waitFor(catchUp[models.CatchUpOnce].ID, 1)
+	scheduler.Stop()
+	for policy, expected := range map[string]int64{models.CatchUpNone: 0, models.CatchUpOnce: 1, models.CatchUpAll: 3} {
+		var saved models.TaskSchedule
+		db.First(&saved, catchUp[policy].ID)
+		if saved.RunCount != expected {
+			t.Errorf("Expected %d catch-up runs for %s, got %d", expected, policy, saved.RunCount)
+		}
+		if saved.NextRunAt == nil || !saved.NextRunAt.After(time.Now()) {
+			t.Errorf("Expected next run time to move forward for %s: %v", policy, saved.NextRunAt)
+		}
+	}
+
+	// 5. Deleting removes the schedule
+	if err := scheduler.Delete(schedule.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	schedules, _ := scheduler.List(recurring.ID)
+	if len(schedules) != 0 {
+		t.Errorf("Expected schedule to be deleted, got %d", len(schedules))
+	}
+	if all, _ := scheduler.List(0); len(all) != 3 {
+		t.Errorf("Expected other schedules to remain, got %d", len(all))
+	}
+
+	// 6. Deleting a task removes its schedules, and schedules left behind by deleted tasks stop firing
+	deleted := newTask("deleted")
+	schedule = models.TaskSchedule{TaskID: deleted.ID, Cron: "@every 1s", Enabled: true}
+	scheduler = services.NewSchedulerService()
+	if err := scheduler.Create(&schedule); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	orphan := models.TaskSchedule{TaskID: 999, Cron: "@every 1s", Enabled: true}
+	db.Create(&orphan)
+	if err := scheduler.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if err := services.NewTaskService().DeleteTask(deleted.ID); err != nil {
+		t.Fatalf("DeleteTask failed: %v", err)
+	}
+	time.Sleep(1500 * time.Millisecond)
+	scheduler.Stop()
+	if remaining, _ := scheduler.List(deleted.ID); len(remaining) != 0 {
+		t.Errorf("Expected the task's schedules to be deleted, got %d", len(remaining))
+	}
+	if remaining, _ := scheduler.List(999); len(remaining) != 0 {
+		t.Errorf("Expected the orphaned schedule to be deleted, got %d", len(remaining))
+	}
+	var runs int64
+	db.Model(&models.TaskRun{}).Where("task_id = ?", deleted.ID).Count(&runs)
+	if runs != 0 {
+		t.Errorf("Expected no runs of the deleted task, got %d", runs)
+	}
+
+	fmt.Println("TestTaskSchedule Passed!")
+}
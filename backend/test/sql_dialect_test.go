@@ -0,0 +1,125 @@
+package test
+
+import (
+	"database/sql"
+	"fmt"
+	"generateTestData/backend/config"
+	"generateTestData/backend/models"
+	"generateTestData/backend/services"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestSQLDialectExport(t *testing.T) {
+	config.AppConfig = &config.Config{GenerateDir: "."}
+	exportService := services.NewExportService()
+
+	tableInfo := &models.TableInfo{
+		TableName: "users",
+		Columns: []models.ColumnInfo{
+			{Name: "id", Type: "int", IsPrimaryKey: true},
+			{Name: "name", Type: "varchar", MaxLength: 50, Nullable: true},
+			{Name: "active", Type: "boolean", Nullable: true},
+			{Name: "avatar", Type: "blob", Nullable: true},
+			{Name: "created_at", Type: "datetime", Nullable: true},
+		},
+	}
+	createdAt := time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC)
+	records := []map[string]interface{}{
+		{"id": 1, "name": `O'Brien \ co`, "active": true, "avatar": []byte{0xde, 0xad}, "created_at": createdAt},
+		{"id": 2, "name": nil, "active": false, "avatar": nil, "created_at": nil},
+	}
+	options := &services.SQLOptions{CreateTable: true, Transaction: true, DisableForeignKeys: true}
+
+	export := func(dialect string) string {
+		fileName := "test_dialect_" + dialect + ".sql"
+		defer os.Remove(fileName)
+
+		opts := *options
+		opts.Dialect = dialect
+		writer, err := exportService.NewSQLScriptWriter(fileName, &opts)
+		if err != nil {
+			t.Fatalf("[%s] NewSQLScriptWriter failed: %v", dialect, err)
+		}
+		if err := writer.Open(); err != nil {
+			t.Fatalf("[%s] Open failed: %v", dialect, err)
+		}
+		if err := writer.StartTable(tableInfo); err != nil {
+			t.Fatalf("[%s] StartTable failed: %v", dialect, err)
+		}
+		if err := writer.WriteBatch(records); err != nil {
+			t.Fatalf("[%s] WriteBatch failed: %v", dialect, err)
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("[%s] Close failed: %v", dialect, err)
+		}
+		content, err := os.ReadFile(fileName)
+		if err != nil {
+			t.Fatalf("[%s] Failed to read output: %v", dialect, err)
+		}
+		return string(content)
+	}
+
+	// 1. Dialect-specific quoting and literals
+	expected := map[string][]string{
+		"mysql": {
+			"SET FOREIGN_KEY_CHECKS=0;", "START TRANSACTION;", "CREATE TABLE IF NOT EXISTS `users`",
+			"INSERT INTO `users` (`id`, `name`, `active`, `avatar`, `created_at`) VALUES",
+			`(1, 'O\'Brien \\ co', TRUE, X'dead', '2024-05-01 08:30:00')`, "COMMIT;", "SET FOREIGN_KEY_CHECKS=1;",
+		},
+		"postgresql": {
+			"BEGIN;", `"active" BOOLEAN`,
+			`(1, 'O''Brien \ co', TRUE, '\xdead'::bytea, TIMESTAMP '2024-05-01 08:30:00')`,
+			"SET session_replication_role = DEFAULT;",
+		},
+		"sqlserver": {
+			"[users]", "NVARCHAR(50)", `(1, N'O''Brien \ co', 1, 0xdead, '2024-05-01 08:30:00')`,
+			"ALTER TABLE [users] NOCHECK CONSTRAINT ALL;", "ALTER TABLE [users] WITH CHECK CHECK CONSTRAINT ALL;",
+		},
+		"oracle": {
+			"INSERT ALL", `INTO "users"`, "HEXTORAW('dead')", "VARCHAR2(50)", "SELECT 1 FROM DUAL;",
+		},
+	}
+	for dialect, fragments := range expected {
+		content := export(dialect)
+		for _, fragment := range fragments {
+			if !strings.Contains(content, fragment) {
+				t.Errorf("[%s] Expected output to contain %q, got:\n%s", dialect, fragment, content)
+			}
+		}
+	}
+
+	// 2. SQLite output can be replayed into an empty database
+	dbPath := "test_dialect_replay.db"
+	os.Remove(dbPath)
+	defer os.Remove(dbPath)
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(export("sqlite")); err != nil {
+		t.Fatalf("Failed to replay SQLite output: %v", err)
+	}
+
+	var name string
+	var avatar []byte
+	if err := db.QueryRow(`SELECT name, avatar FROM users WHERE id = 1`).Scan(&name, &avatar); err != nil {
+		t.Fatalf("Failed to query replayed data: %v", err)
+	}
+	if name != `O'Brien \ co` || string(avatar) != "\xde\xad" {
+		t.Errorf("Unexpected replayed row: name=%q avatar=%x", name, avatar)
+	}
+
+	// 3. Unknown dialects are rejected
+	if _, err := exportService.NewSQLScriptWriter("test_dialect_bad.sql", &services.SQLOptions{Dialect: "db2"}); err == nil {
+		t.Errorf("Expected error for unsupported dialect")
+	}
+
+	fmt.Println("TestSQLDialectExport Passed!")
+}
@@ -0,0 +1,134 @@
+package test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"generateTestData/backend/config"
+	"generateTestData/backend/controllers"
+	"generateTestData/backend/models"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestGenerateStream(t *testing.T) {
+	dbPath := "test_stream.db"
+	os.Remove(dbPath)
+	defer os.Remove(dbPath)
+	config.AppConfig = &config.Config{DBPath: dbPath, GenerateDir: "."}
+	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	models.DB = db
+	if err := db.AutoMigrate(&models.DataSource{}, &models.Task{}, &models.TaskTemplate{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	generateController := controllers.NewGenerateController()
+	finished := make(chan struct{}, 10)
+	router.POST("/api/generate/stream", func(ctx *gin.Context) {
+		generateController.Stream(ctx)
+		finished <- struct{}{}
+	})
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	post := func(ctx context.Context, body string) *http.Response {
+		req, _ := http.NewRequestWithContext(ctx, "POST", server.URL+"/api/generate/stream", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		return resp
+	}
+	read := func(body string) (*http.Response, string) {
+		resp := post(context.Background(), body)
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		<-finished
+		return resp, string(data)
+	}
+
+	// 1. NDJSON from an inline JSON task, streamed in several chunks
+	resp, body := read(`{"task":{"type":"json","jsonSchema":"{\"name\":\"x\",\"tags\":[\"a\"]}"},"count":2500}`)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("Unexpected response: %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if len(resp.TransferEncoding) == 0 || resp.TransferEncoding[0] != "chunked" {
+		t.Errorf("Expected chunked transfer encoding, got %v", resp.TransferEncoding)
+	}
+	lines := strings.Split(strings.TrimSuffix(body, "\n"), "\n")
+	if len(lines) != 2500 {
+		t.Errorf("Expected 2500 lines, got %d", len(lines))
+	}
+	for _, line := range lines[:10] {
+		if !json.Valid([]byte(line)) {
+			t.Errorf("Invalid JSON line: %s", line)
+		}
+	}
+	if resp.Trailer.Get("X-Generated-Count") != "2500" || resp.Trailer.Get("X-Generate-Error") != "" {
+		t.Errorf("Unexpected trailers: %v", resp.Trailer)
+	}
+
+	// 2. CSV and SQL from a saved template
+	template := models.TaskTemplate{
+		Name:       "users",
+		Type:       models.TaskTypeCSV,
+		JSONSchema: `[{"name":"id","type":"int"},{"name":"name","type":"varchar"}]`,
+		FieldRules: `{"id":{"type":"sequence","value":1}}`,
+	}
+	db.Create(&template)
+	_, body = read(fmt.Sprintf(`{"templateId":%d,"count":3,"format":"csv"}`, template.ID))
+	rows := strings.Split(strings.TrimSpace(strings.TrimPrefix(body, "\xEF\xBB\xBF")), "\n")
+	if len(rows) != 4 || rows[0] != "id,name" {
+		t.Errorf("Unexpected CSV stream:\n%s", body)
+	}
+	resp, body = read(fmt.Sprintf(`{"templateId":%d,"count":3,"format":"sql","task":{"configuration":"{\"sqlDialect\":\"postgresql\"}"}}`, template.ID))
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/sql") || strings.Count(body, "INSERT INTO") == 0 {
+		t.Errorf("Unexpected SQL stream:\n%s", body)
+	}
+
+	// 3. Configuration errors are reported before streaming starts
+	for _, request := range []string{
+		`{"task":{"type":"json","jsonSchema":"{}"}}`,
+		`{"task":{"type":"json","jsonSchema":"{}"},"count":1,"format":"sql"}`,
+		`{"task":{"type":"json","jsonSchema":"{}"},"count":1,"format":"xml"}`,
+		`{"templateId":999,"count":1}`,
+	} {
+		resp, body := read(request)
+		if resp.StatusCode != http.StatusBadRequest || !strings.Contains(body, "error") {
+			t.Errorf("Expected 400 for %s, got %d %s", request, resp.StatusCode, body)
+		}
+	}
+
+	// 4. Generation stops when the client disconnects
+	ctx, cancel := context.WithCancel(context.Background())
+	resp = post(ctx, `{"task":{"type":"json","jsonSchema":"{\"name\":\"x\"}"},"count":100000000}`)
+	reader := bufio.NewReader(resp.Body)
+	if _, err := reader.ReadString('\n'); err != nil {
+		t.Fatalf("Failed to read first line: %v", err)
+	}
+	cancel()
+	resp.Body.Close()
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Generation did not stop after the client disconnected")
+	}
+
+	fmt.Println("TestGenerateStream Passed!")
+}
@@ -0,0 +1,251 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"generateTestData/backend/config"
+	"generateTestData/backend/models"
+	"generateTestData/backend/services"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestSubsetReferentialIntegrity(t *testing.T) {
+	// Setup
+	dbPath := "test_subset.db"
+	sourcePath := "test_subset_source.db"
+	targetPath := "test_subset_target.db"
+	for _, p := range []string{dbPath, sourcePath, targetPath} {
+		os.Remove(p)
+	}
+
+	config.AppConfig = &config.Config{
+		DBPath:      dbPath,
+		GenerateDir: ".",
+	}
+
+	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	models.DB = db
+	if err := db.AutoMigrate(&models.DataSource{}, &models.Task{}, &models.TaskRun{}, &models.TaskRunLog{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	// 1. Source and target share a schema with a self-referencing cycle (employees.manager_id)
+	schema := []string{
+		"CREATE TABLE employees (id INTEGER PRIMARY KEY, name TEXT, manager_id INTEGER REFERENCES employees(id))",
+		"CREATE TABLE customers (id INTEGER PRIMARY KEY, name TEXT, account_manager_id INTEGER REFERENCES employees(id))",
+		"CREATE TABLE products (id INTEGER PRIMARY KEY, title TEXT)",
+		"CREATE TABLE orders (id INTEGER PRIMARY KEY, customer_id INTEGER NOT NULL REFERENCES customers(id))",
+		"CREATE TABLE order_items (id INTEGER PRIMARY KEY, order_id INTEGER NOT NULL REFERENCES orders(id), product_id INTEGER NOT NULL REFERENCES products(id))",
+	}
+	source, err := gorm.Open(sqlite.Open(sourcePath), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open source: %v", err)
+	}
+	target, err := gorm.Open(sqlite.Open(targetPath), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open target: %v", err)
+	}
+	for _, stmt := range schema {
+		if err := source.Exec(stmt).Error; err != nil {
+			t.Fatalf("Failed to create source schema: %v", err)
+		}
+		if err := target.Exec(stmt).Error; err != nil {
+			t.Fatalf("Failed to create target schema: %v", err)
+		}
+	}
+
+	fixtures := []string{
+		"INSERT INTO employees VALUES (1, 'boss', NULL), (2, 'alice', 1), (3, 'bob', 1)",
+		"INSERT INTO customers VALUES (1, 'acme', 2), (2, 'globex', 3)",
+		"INSERT INTO products VALUES (1, 'pen'), (2, 'ink'), (3, 'paper')",
+		"INSERT INTO orders VALUES (1, 1), (2, 1), (3, 2)",
+		"INSERT INTO order_items VALUES (1, 1, 1), (2, 2, 2), (3, 3, 3)",
+	}
+	for _, stmt := range fixtures {
+		if err := source.Exec(stmt).Error; err != nil {
+			t.Fatalf("Failed to insert fixtures: %v", err)
+		}
+	}
+
+	sourceDS := models.DataSource{Name: "source", Type: "sqlite", Database: sourcePath}
+	targetDS := models.DataSource{Name: "target", Type: "sqlite", Database: targetPath}
+	db.Create(&sourceDS)
+	db.Create(&targetDS)
+
+	// 2. Subset rooted at customer 1
+	subsetConfig, _ := json.Marshal(map[string]interface{}{
+		"filter":             "id = 1",
+		"targetDataSourceId": targetDS.ID,
+	})
+	task := models.Task{
+		Name:          "Subset Test",
+		Type:          models.TaskTypeSubset,
+		DataSourceID:  &sourceDS.ID,
+		TableName:     "customers",
+		OutputType:    models.OutputTypeDatabase,
+		Configuration: string(subsetConfig),
+	}
+	taskService := services.NewTaskService()
+	if err := taskService.CreateTask(&task); err != nil {
+		t.Fatalf("CreateTask failed: %v", err)
+	}
+	if _, err := taskService.ExecuteTask(task.ID, models.RunTriggerAPI); err != nil {
+		t.Fatalf("ExecuteTask failed: %v", err)
+	}
+
+	// Wait for task completion
+	var finished models.Task
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if time.Now().After(deadline) {
+			t.Fatalf("Task timeout")
+		}
+		db.First(&finished, task.ID)
+		if finished.Status == models.TaskStatusFailed {
+			t.Fatalf("Task failed: %s", finished.ErrorMsg)
+		}
+		if finished.Status == models.TaskStatusCompleted {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	// 3. Verify per-table counts
+	var result models.TaskResult
+	if err := json.Unmarshal([]byte(finished.Result), &result); err != nil {
+		t.Fatalf("Failed to parse task result: %v", err)
+	}
+	expected := map[string]int64{
+		"customers":   1,
+		"orders":      2,
+		"order_items": 2,
+		"products":    2,
+		"employees":   2, // alice and her manager
+	}
+	for table, count := range expected {
+		if result.TableCounts[table] != count {
+			t.Errorf("Table %s: expected %d rows, got %d", table, count, result.TableCounts[table])
+		}
+	}
+
+	// 4. Verify the target contains the rows and the deferred self reference was restored
+	var managerID *int
+	target.Raw("SELECT manager_id FROM employees WHERE id = 2").Scan(&managerID)
+	if managerID == nil || *managerID != 1 {
+		t.Errorf("Expected alice's manager_id to be restored to 1, got %v", managerID)
+	}
+	var orphanItems int64
+	target.Raw("SELECT COUNT(*) FROM order_items WHERE order_id NOT IN (SELECT id FROM orders) OR product_id NOT IN (SELECT id FROM products)").Scan(&orphanItems)
+	if orphanItems != 0 {
+		t.Errorf("Expected no dangling references, got %d", orphanItems)
+	}
+
+	// Cleanup
+	for _, p := range []string{dbPath, sourcePath, targetPath} {
+		os.Remove(p)
+	}
+	fmt.Println("TestSubsetReferentialIntegrity Passed!")
+}
+
+func TestSubsetNonNullableCycle(t *testing.T) {
+	dbPath := "test_subset_cycle.db"
+	sourcePath := "test_subset_cycle_source.db"
+	targetPath := "test_subset_cycle_target.db"
+	for _, p := range []string{dbPath, sourcePath, targetPath} {
+		os.Remove(p)
+		defer os.Remove(p)
+	}
+	config.AppConfig = &config.Config{DBPath: dbPath, GenerateDir: "."}
+	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	models.DB = db
+	if err := db.AutoMigrate(&models.DataSource{}, &models.Task{}, &models.TaskRun{}, &models.TaskRunLog{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	// departments <-> people through NOT NULL columns; teams <-> members where teams.lead_id is nullable
+	schema := []string{
+		"CREATE TABLE departments (id INTEGER PRIMARY KEY, head_id INTEGER NOT NULL REFERENCES people(id))",
+		"CREATE TABLE people (id INTEGER PRIMARY KEY, department_id INTEGER NOT NULL REFERENCES departments(id))",
+		"CREATE TABLE teams (id INTEGER PRIMARY KEY, lead_id INTEGER REFERENCES members(id))",
+		"CREATE TABLE members (id INTEGER PRIMARY KEY, team_id INTEGER NOT NULL REFERENCES teams(id))",
+	}
+	fixtures := []string{
+		"INSERT INTO departments VALUES (1, 1)",
+		"INSERT INTO people VALUES (1, 1), (2, 1)",
+		"INSERT INTO teams VALUES (1, 1)",
+		"INSERT INTO members VALUES (1, 1), (2, 1)",
+	}
+	source, _ := gorm.Open(sqlite.Open(sourcePath), &gorm.Config{})
+	target, _ := gorm.Open(sqlite.Open(targetPath), &gorm.Config{})
+	for _, stmt := range schema {
+		source.Exec(stmt)
+		target.Exec(stmt)
+	}
+	for _, stmt := range fixtures {
+		if err := source.Exec(stmt).Error; err != nil {
+			t.Fatalf("Failed to insert fixtures: %v", err)
+		}
+	}
+	sourceDS := models.DataSource{Name: "source", Type: "sqlite", Database: sourcePath}
+	targetDS := models.DataSource{Name: "target", Type: "sqlite", Database: targetPath}
+	db.Create(&sourceDS)
+	db.Create(&targetDS)
+
+	taskService := services.NewTaskService()
+	run := func(table string) error {
+		task := models.Task{
+			Name:          table,
+			Type:          models.TaskTypeSubset,
+			DataSourceID:  &sourceDS.ID,
+			TableName:     table,
+			OutputType:    models.OutputTypeDatabase,
+			Configuration: fmt.Sprintf(`{"filter":"id = 1","targetDataSourceId":%d}`, targetDS.ID),
+		}
+		db.Create(&task)
+		return taskService.ExecuteTaskSync(task.ID, models.RunTriggerAPI)
+	}
+
+	// 1. A cycle through NOT NULL columns fails up front, naming the tables and columns
+	err = run("departments")
+	if err == nil {
+		t.Fatalf("Expected the non-nullable cycle to be rejected")
+	}
+	for _, part := range []string{"departments(head_id) -> people", "people(department_id) -> departments"} {
+		if !strings.Contains(err.Error(), part) {
+			t.Errorf("Expected error to mention %q, got %v", part, err)
+		}
+	}
+	var written int64
+	target.Raw("SELECT COUNT(*) FROM people").Scan(&written)
+	if written != 0 {
+		t.Errorf("Expected nothing to be written, got %d people", written)
+	}
+
+	// 2. The cycle is broken at the table whose reference is nullable, and the reference is restored
+	if err := run("teams"); err != nil {
+		t.Fatalf("Expected the nullable cycle to be written: %v", err)
+	}
+	var leadID *int
+	target.Raw("SELECT lead_id FROM teams WHERE id = 1").Scan(&leadID)
+	if leadID == nil || *leadID != 1 {
+		t.Errorf("Expected lead_id to be restored to 1, got %v", leadID)
+	}
+	target.Raw("SELECT COUNT(*) FROM members").Scan(&written)
+	if written != 2 {
+		t.Errorf("Expected 2 members, got %d", written)
+	}
+
+	fmt.Println("TestSubsetNonNullableCycle Passed!")
+}
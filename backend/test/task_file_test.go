@@ -0,0 +1,123 @@
+package test
+
+import (
+	"fmt"
+	"generateTestData/backend/config"
+	"generateTestData/backend/models"
+	"generateTestData/backend/services"
+	"os"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestTaskFileRun(t *testing.T) {
+	taskPath := "test_task_file.yaml"
+	targetPath := "test_task_file_target.db"
+	outputPath := "test_task_file.csv"
+	for _, p := range []string{taskPath, targetPath, outputPath} {
+		os.Remove(p)
+		defer os.Remove(p)
+	}
+
+	// Same setup as the CLI: in-memory platform database, no data.db
+	config.AppConfig = &config.Config{GenerateDir: "."}
+	models.InitMemoryDB()
+	defer func() { models.DB = nil }()
+
+	// 1. YAML structures and JSON strings are both accepted
+	os.WriteFile(taskPath, []byte(`
+name: users
+type: csv
+count: 1200
+jsonSchema:
+  - {name: id, type: int}
+  - {name: name, type: varchar}
+fieldRules: '{"id":{"type":"sequence","value":1}}'
+uniqueFields: [id]
+outputType: csv
+outputPath: `+outputPath+`
+configuration:
+  compression: none
+`), 0644)
+	taskFile, err := services.LoadTaskFile(taskPath)
+	if err != nil {
+		t.Fatalf("Failed to load task file: %v", err)
+	}
+	task, err := taskFile.Task()
+	if err != nil {
+		t.Fatalf("Failed to build task: %v", err)
+	}
+	if task.JSONSchema != `[{"name":"id","type":"int"},{"name":"name","type":"varchar"}]` || task.UniqueFields != `["id"]` || task.Configuration != `{"compression":"none"}` {
+		t.Errorf("Unexpected task fields: %s %s %s", task.JSONSchema, task.UniqueFields, task.Configuration)
+	}
+
+	// 2. RunTask reports progress through the callback and does not touch the task table
+	var progress []float64
+	result, err := services.NewTaskService().RunTask(task, func(p float64) {
+		progress = append(progress, p)
+	})
+	if err != nil {
+		t.Fatalf("RunTask failed: %v", err)
+	}
+	if result.GeneratedCount != 1200 || len(progress) == 0 || progress[len(progress)-1] != 100 {
+		t.Errorf("Unexpected result: %d rows, progress %v", result.GeneratedCount, progress)
+	}
+	data, _ := os.ReadFile(outputPath)
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 1201 {
+		t.Errorf("Expected 1201 lines, got %d", len(lines))
+	}
+	var count int64
+	models.DB.Model(&models.Task{}).Count(&count)
+	if count != 0 {
+		t.Errorf("Expected no saved tasks, got %d", count)
+	}
+
+	// 3. Data sources referenced by ID are registered from the file
+	target, _ := gorm.Open(sqlite.Open(targetPath), &gorm.Config{})
+	target.Exec("CREATE TABLE items (id INTEGER PRIMARY KEY, title TEXT)")
+	os.WriteFile(taskPath, []byte(`{
+  "name": "items",
+  "type": "database",
+  "dataSourceId": 7,
+  "dataSources": [{"id": 7, "name": "target", "type": "sqlite", "database": "`+targetPath+`"}],
+  "tableName": "items",
+  "count": 50,
+  "outputType": "database"
+}`), 0644)
+	taskFile, _ = services.LoadTaskFile(taskPath)
+	task, err = taskFile.Task()
+	if err != nil {
+		t.Fatalf("Failed to build database task: %v", err)
+	}
+	if task.DataSource == nil || task.DataSource.Database != targetPath {
+		t.Fatalf("Data source not resolved: %+v", task.DataSource)
+	}
+	if _, err := services.NewTaskService().RunTask(task, nil); err != nil {
+		t.Fatalf("RunTask failed: %v", err)
+	}
+	target.Raw("SELECT COUNT(*) FROM items").Scan(&count)
+	if count != 50 {
+		t.Errorf("Expected 50 inserted rows, got %d", count)
+	}
+	if sqlDB, err := target.DB(); err == nil {
+		sqlDB.Close()
+	}
+
+	// 4. Invalid definitions fail before anything runs
+	os.WriteFile(taskPath, []byte("name: bad\ntype: json\ncount: 0\njsonSchema: {a: 1}\n"), 0644)
+	taskFile, _ = services.LoadTaskFile(taskPath)
+	task, _ = taskFile.Task()
+	if err := services.NewTaskService().ValidateTask(task); err == nil {
+		t.Errorf("Expected validation error for count 0")
+	}
+	os.WriteFile(taskPath, []byte("dataSourceId: 99\n"), 0644)
+	taskFile, _ = services.LoadTaskFile(taskPath)
+	if _, err := taskFile.Task(); err == nil {
+		t.Errorf("Expected error for unknown data source")
+	}
+
+	fmt.Println("TestTaskFileRun Passed!")
+}
@@ -0,0 +1,131 @@
+package test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"generateTestData/backend/config"
+	"generateTestData/backend/models"
+	"generateTestData/backend/services"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestTaskRunHistory(t *testing.T) {
+	dbPath := "test_task_run.db"
+	outputDir := "test_task_run_output"
+	os.Remove(dbPath)
+	os.RemoveAll(outputDir)
+	defer os.Remove(dbPath)
+	defer os.RemoveAll(outputDir)
+	os.MkdirAll(outputDir, 0755)
+	config.AppConfig = &config.Config{DBPath: dbPath, GenerateDir: outputDir}
+	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	models.DB = db
+	if err := db.AutoMigrate(&models.DataSource{}, &models.Task{}, &models.TaskSchedule{}, &models.TaskRun{}, &models.TaskRunLog{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	task := models.Task{
+		Name:       "events",
+		Type:       models.TaskTypeJSON,
+		Count:      50,
+		JSONSchema: `{"id":"","score":1}`,
+		FieldRules: `{"id":{"type":"regex","parameters":{"pattern":"[a-z]{12}"}}}`,
+		OutputType: models.OutputTypeJSONL,
+		OutputPath: "events",
+	}
+	db.Create(&task)
+	taskService := services.NewTaskService()
+	waitRun := func(id uint) models.TaskRun {
+		var run models.TaskRun
+		deadline := time.Now().Add(10 * time.Second)
+		for time.Now().Before(deadline) {
+			db.First(&run, id)
+			if run.FinishedAt != nil {
+				break
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		return run
+	}
+
+	// 1. Each execution is recorded with its trigger, counts, files and config snapshot
+	if err := taskService.ExecuteTaskSync(task.ID, models.RunTriggerManual); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	runs, _ := taskService.GetTaskRuns(task.ID)
+	if len(runs) != 1 {
+		t.Fatalf("Expected one run, got %d", len(runs))
+	}
+	first := runs[0]
+	if first.Trigger != models.RunTriggerManual || first.Status != models.TaskStatusCompleted || first.GeneratedCount != 50 || first.FinishedAt == nil {
+		t.Errorf("Unexpected run: %+v", first)
+	}
+	snapshot, err := first.GetSnapshot()
+	if err != nil || snapshot.Count != 50 || snapshot.FieldRules != task.FieldRules || snapshot.Status != "" {
+		t.Errorf("Unexpected snapshot %s: %v", first.Snapshot, err)
+	}
+	files, _ := first.GetFiles()
+	if len(files) != 1 {
+		t.Fatalf("Expected one output file, got %v", files)
+	}
+	data, _ := os.ReadFile(filepath.Join(outputDir, files[0].Name))
+	sum := sha256.Sum256(data)
+	if files[0].Size != int64(len(data)) || files[0].SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("Unexpected file info: %+v", files[0])
+	}
+
+	// 2. Runs are kept after the task changes; re-running uses the old config and seed
+	db.Model(&task).Update("count", 80)
+	if err := taskService.ExecuteTaskSync(task.ID, models.RunTriggerAPI); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	rerun, err := taskService.RerunTask(task.ID, first.ID, models.RunTriggerManual)
+	if err != nil {
+		t.Fatalf("Rerun failed: %v", err)
+	}
+	replayed := waitRun(rerun.ID)
+	replayedFiles, _ := replayed.GetFiles()
+	if replayed.RerunOf == nil || *replayed.RerunOf != first.ID || replayed.Seed != first.Seed || replayed.GeneratedCount != 50 {
+		t.Errorf("Unexpected rerun: %+v", replayed)
+	}
+	if len(replayedFiles) != 1 || replayedFiles[0].SHA256 != files[0].SHA256 {
+		t.Errorf("Expected rerun to reproduce the same output: %v vs %v", replayedFiles, files)
+	}
+	var current models.Task
+	db.First(&current, task.ID)
+	if current.Count != 80 {
+		t.Errorf("Rerun must not change the task config, got count %d", current.Count)
+	}
+
+	// 3. Failures are recorded with their error
+	db.Model(&task).Update("json_schema", "{")
+	if err := taskService.ExecuteTaskSync(task.ID, models.RunTriggerSchedule); err == nil {
+		t.Errorf("Expected execution to fail")
+	}
+	runs, _ = taskService.GetTaskRuns(task.ID)
+	if len(runs) != 4 || runs[0].Status != models.TaskStatusFailed || runs[0].ErrorMsg == "" || runs[0].Trigger != models.RunTriggerSchedule {
+		t.Errorf("Expected newest failed run first, got %+v", runs[0])
+	}
+	if _, err := taskService.GetTaskRun(task.ID+1, first.ID); err == nil {
+		t.Errorf("Expected run lookup to be scoped to the task")
+	}
+
+	// 4. Deleting the task removes its history
+	taskService.DeleteTask(task.ID)
+	runs, _ = taskService.GetTaskRuns(task.ID)
+	if len(runs) != 0 {
+		t.Errorf("Expected runs to be deleted, got %d", len(runs))
+	}
+
+	fmt.Println("TestTaskRunHistory Passed!")
+}
@@ -0,0 +1,108 @@
+package test
+
+import (
+	"fmt"
+	"generateTestData/backend/config"
+	"generateTestData/backend/services"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTemplateExport(t *testing.T) {
+	config.AppConfig = &config.Config{GenerateDir: "."}
+	exportService := services.NewExportService()
+
+	batch := func(start int) []map[string]interface{} {
+		records := make([]map[string]interface{}, 2)
+		for i := range records {
+			records[i] = map[string]interface{}{
+				"id":         start + i,
+				"name":       fmt.Sprintf("用户%d|O'Neil", start+i),
+				"amount":     12.5,
+				"created_at": time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+				"tags":       []interface{}{"a", "b"},
+				"note":       nil,
+			}
+		}
+		return records
+	}
+
+	// 1. Fixed-width file with header, rows and footer, written across batches
+	fixedFile := "test_template.dat"
+	defer os.Remove(fixedFile)
+
+	writer, err := exportService.NewTemplateWriter(fixedFile, &services.TemplateOptions{
+		Header: "HDR{{\"USERS\" | fixed 8}}\n",
+		Row:    `{{rowNumber | zpad 4}}{{.name | fixed 6}}{{.amount | number 2 | lpad 8}}{{.created_at | date "20060102"}}{{.note | default "-"}}`,
+		Footer: "TRL{{.Rows | zpad 6}}\n",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	defer writer.Close()
+	for _, start := range []int{1, 3} {
+		if err := writer.WriteBatch(batch(start)); err != nil {
+			t.Fatalf("Failed to write batch: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+
+	data, err := os.ReadFile(fixedFile)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	expected := []string{
+		"HDRUSERS   ",
+		"0001用户1|O'   12.5020240102-",
+		"0002用户2|O'   12.5020240102-",
+		"0003用户3|O'   12.5020240102-",
+		"0004用户4|O'   12.5020240102-",
+		"TRL000004",
+		"",
+	}
+	if string(data) != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected fixed-width output:\n%s", data)
+	}
+
+	// 2. Escaping helpers and a custom line ending
+	escapeFile := "test_template"
+	defer os.Remove(escapeFile + ".sql")
+	writer, err = exportService.NewTemplateWriter(escapeFile, &services.TemplateOptions{
+		Row:        `CALL add_user({{.id}}, '{{sqlEscape .name}}', {{json .tags}}); -- {{hl7Escape .name}} {{join "," .tags}}`,
+		LineEnding: "\r\n",
+		Extension:  "sql",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	if err := writer.WriteBatch(batch(1)[:1]); err != nil {
+		t.Fatalf("Failed to write batch: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+	data, _ = os.ReadFile(escapeFile + ".sql")
+	if string(data) != `CALL add_user(1, '用户1|O''Neil', ["a","b"]); -- 用户1\F\O'Neil a,b`+"\r\n" {
+		t.Errorf("Unexpected escaped output: %q", data)
+	}
+
+	// 3. Invalid templates and unknown fields are rejected
+	if _, err := exportService.NewTemplateWriter("test_template_bad.txt", &services.TemplateOptions{Row: "{{.id"}); err == nil {
+		t.Errorf("Expected error for invalid template")
+	}
+	writer, err = exportService.NewTemplateWriter("test_template_missing.txt", &services.TemplateOptions{Row: "{{.missing}}"})
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	defer os.Remove("test_template_missing.txt")
+	if err := writer.WriteBatch(batch(1)); err == nil {
+		t.Errorf("Expected error for unknown field")
+	}
+	writer.Close()
+
+	fmt.Println("TestTemplateExport Passed!")
+}
@@ -0,0 +1,165 @@
+package test
+
+import (
+	"fmt"
+	"generateTestData/backend/config"
+	"generateTestData/backend/models"
+	"generateTestData/backend/services"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestWorkspaceExportApply(t *testing.T) {
+	dbPath := "test_workspace.db"
+	dir := "test_workspace"
+	os.Remove(dbPath)
+	os.RemoveAll(dir)
+	defer os.Remove(dbPath)
+	defer os.RemoveAll(dir)
+	config.AppConfig = &config.Config{DBPath: dbPath, GenerateDir: "."}
+	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	models.DB = db
+	if err := db.AutoMigrate(&models.DataSource{}, &models.Task{}, &models.TaskTemplate{}, &models.RuleLibrary{}, &models.TaskRun{}, &models.TaskRunLog{}, &models.TaskSchedule{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	source := models.DataSource{Name: "orders-db", Type: "sqlite", Database: "orders.db"}
+	db.Create(&source)
+	orders := models.Task{
+		Name:         "orders",
+		Type:         models.TaskTypeDatabase,
+		DataSourceID: &source.ID,
+		TableName:    "orders",
+		Count:        100,
+		FieldRules:   fmt.Sprintf(`{"user_id":{"type":"db_lookup","parameters":{"dataSourceId":%d,"tableName":"users","columnName":"id"}}}`, source.ID),
+		OutputType:   models.OutputTypeDatabase,
+		Status:       models.TaskStatusCompleted,
+	}
+	db.Create(&orders)
+	db.Create(&models.Task{Name: "events", Type: models.TaskTypeJSON, Count: 10, JSONSchema: `{"id":1,"tags":["a"]}`, OutputType: models.OutputTypeJSONL, OutputPath: "events.jsonl"})
+	db.Create(&models.TaskTemplate{Name: "用户模板", Type: models.TaskTypeCSV, JSONSchema: `[{"name":"id","type":"int"}]`})
+	db.Create(&models.RuleLibrary{Name: "contacts", FieldRules: `{"email":{"type":"regex","value":"[a-z]{5}@test\\.com"}}`})
+
+	workspace := services.NewWorkspaceService()
+
+	// 1. Export writes one YAML file per resource with data sources referenced by name
+	if err := workspace.Export(dir); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "tasks", "orders.yaml"))
+	if err != nil {
+		t.Fatalf("Missing task file: %v", err)
+	}
+	text := string(data)
+	if !strings.Contains(text, "dataSource: orders-db") || strings.Contains(text, "dataSourceId") || strings.Contains(text, "status") {
+		t.Errorf("Unexpected task file:\n%s", text)
+	}
+	for _, file := range []string{"tasks/events.yaml", "templates/用户模板.yaml", "rules/contacts.yaml"} {
+		if _, err := os.Stat(filepath.Join(dir, file)); err != nil {
+			t.Errorf("Missing exported file %s", file)
+		}
+	}
+
+	// 2. Applying an unchanged export is a no-op
+	changes, err := workspace.Apply(dir, false)
+	if err != nil || len(changes) != 0 {
+		t.Fatalf("Expected no changes, got %v %v", changes, err)
+	}
+
+	// 3. Dry-run reports create, update and delete without touching the database
+	os.WriteFile(filepath.Join(dir, "tasks", "orders.yaml"), []byte(strings.Replace(text, "count: 100", "count: 500", 1)), 0644)
+	os.WriteFile(filepath.Join(dir, "rules", "names.yml"), []byte("name: names\nfieldRules:\n  name: {type: enum, value: [a, b]}\n"), 0644)
+	os.Remove(filepath.Join(dir, "templates", "用户模板.yaml"))
+	changes, err = workspace.Apply(dir, true)
+	if err != nil {
+		t.Fatalf("Dry-run failed: %v", err)
+	}
+	summary := make([]string, len(changes))
+	for i, change := range changes {
+		summary[i] = change.Action + " " + change.Kind + "/" + change.Name
+	}
+	if strings.Join(summary, ";") != "update tasks/orders;delete templates/用户模板;create rules/names" {
+		t.Errorf("Unexpected plan: %v", summary)
+	}
+	if !strings.Contains(changes[0].Diff, "- count: 100\n+ count: 500") {
+		t.Errorf("Unexpected diff:\n%s", changes[0].Diff)
+	}
+	var count int64
+	db.Model(&models.TaskTemplate{}).Count(&count)
+	if count != 1 {
+		t.Errorf("Dry-run must not delete the template")
+	}
+
+	// 4. Apply makes the database match the files and keeps task identity and status
+	if _, err := workspace.Apply(dir, false); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	var updated models.Task
+	db.First(&updated, orders.ID)
+	if updated.Count != 500 || updated.Status != models.TaskStatusCompleted || !strings.Contains(updated.FieldRules, fmt.Sprintf(`"dataSourceId":%d`, source.ID)) {
+		t.Errorf("Unexpected updated task: %+v", updated)
+	}
+	db.Model(&models.TaskTemplate{}).Count(&count)
+	if count != 0 {
+		t.Errorf("Expected template to be deleted, got %d", count)
+	}
+	var library models.RuleLibrary
+	if err := db.Where("name = ?", "names").First(&library).Error; err != nil || library.FieldRules != `{"name":{"type":"enum","value":["a","b"]}}` {
+		t.Errorf("Unexpected rule library: %+v %v", library, err)
+	}
+	if changes, _ := workspace.Apply(dir, false); len(changes) != 0 {
+		t.Errorf("Expected second apply to be a no-op, got %d changes", len(changes))
+	}
+
+	// 5. Invalid files fail the whole apply
+	os.WriteFile(filepath.Join(dir, "tasks", "broken.yaml"), []byte("name: broken\ntype: database\ndataSource: missing\ntableName: t\ncount: 1\n"), 0644)
+	if _, err := workspace.Apply(dir, false); err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("Expected unknown data source error, got %v", err)
+	}
+	os.WriteFile(filepath.Join(dir, "tasks", "broken.yaml"), []byte("name: broken\ntype: json\ncont: 1\n"), 0644)
+	if _, err := workspace.Apply(dir, false); err == nil {
+		t.Errorf("Expected unknown field error")
+	}
+	db.Model(&models.Task{}).Count(&count)
+	if count != 2 {
+		t.Errorf("Expected failed apply to change nothing, got %d tasks", count)
+	}
+
+	// 6. Removing the file of a queued task is refused, even in dry-run
+	os.Remove(filepath.Join(dir, "tasks", "broken.yaml"))
+	os.Remove(filepath.Join(dir, "tasks", "events.yaml"))
+	var events models.Task
+	db.Where("name = ?", "events").First(&events)
+	db.Model(&events).Update("status", models.TaskStatusQueued)
+	for _, dryRun := range []bool{true, false} {
+		if _, err := workspace.Apply(dir, dryRun); err == nil || !strings.Contains(err.Error(), "events") {
+			t.Errorf("Expected deleting a queued task to be refused (dryRun=%v), got %v", dryRun, err)
+		}
+	}
+
+	// 7. Deleting a finished task removes its runs, logs and schedules
+	db.Model(&events).Update("status", models.TaskStatusCompleted)
+	run := models.TaskRun{TaskID: events.ID, Status: models.TaskStatusCompleted}
+	db.Create(&run)
+	db.Create(&models.TaskRunLog{TaskID: events.ID, RunID: run.ID, Level: models.LogLevelInfo, Message: "done"})
+	db.Create(&models.TaskSchedule{TaskID: events.ID, Cron: "@daily"})
+	if _, err := workspace.Apply(dir, false); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	for _, model := range []interface{}{&models.TaskRun{}, &models.TaskRunLog{}, &models.TaskSchedule{}} {
+		db.Model(model).Where("task_id = ?", events.ID).Count(&count)
+		if count != 0 {
+			t.Errorf("Expected %T rows of the deleted task to be removed, got %d", model, count)
+		}
+	}
+
+	fmt.Println("TestWorkspaceExportApply Passed!")
+}
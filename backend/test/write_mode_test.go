@@ -0,0 +1,221 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"generateTestData/backend/config"
+	"generateTestData/backend/models"
+	"generateTestData/backend/services"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestUpsertWithRejectFile(t *testing.T) {
+	// Setup
+	dbPath := "test_write_mode.db"
+	targetPath := "test_write_mode_target.db"
+	rejectFile := "test_write_mode_rejects.jsonl"
+	for _, p := range []string{dbPath, targetPath, rejectFile} {
+		os.Remove(p)
+	}
+
+	config.AppConfig = &config.Config{
+		DBPath:      dbPath,
+		GenerateDir: ".",
+	}
+
+	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	models.DB = db
+	if err := db.AutoMigrate(&models.DataSource{}, &models.Task{}, &models.TaskRun{}, &models.TaskRunLog{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	// 1. Target table already contains rows 1-3
+	target, err := gorm.Open(sqlite.Open(targetPath), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open target: %v", err)
+	}
+	target.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL, age INTEGER CHECK (age >= 0))")
+	target.Exec("INSERT INTO users VALUES (1, 'old', 1), (2, 'old', 2), (3, 'old', 3)")
+
+	ds := models.DataSource{Name: "target", Type: "sqlite", Database: targetPath}
+	db.Create(&ds)
+
+	// 2. Re-run rows 1-5 as upsert; row 5 violates the CHECK constraint
+	fieldRules, _ := json.Marshal(map[string]interface{}{
+		"id":   map[string]interface{}{"type": "sequence", "parameters": map[string]interface{}{"start": 1, "step": 1}},
+		"name": map[string]interface{}{"type": "fixed", "parameters": map[string]interface{}{"value": "new"}},
+		"age":  map[string]interface{}{"type": "custom", "parameters": map[string]interface{}{"script": "rowIndex == 4 ? -1 : 20"}},
+	})
+	writeConfig, _ := json.Marshal(map[string]interface{}{
+		"writeMode":   "upsert",
+		"errorPolicy": "reject_file",
+		"rejectFile":  rejectFile,
+	})
+	task := models.Task{
+		Name:          "Upsert Test",
+		Type:          models.TaskTypeDatabase,
+		DataSourceID:  &ds.ID,
+		TableName:     "users",
+		FieldRules:    string(fieldRules),
+		Count:         5,
+		OutputType:    models.OutputTypeDatabase,
+		Configuration: string(writeConfig),
+	}
+	taskService := services.NewTaskService()
+	if err := taskService.CreateTask(&task); err != nil {
+		t.Fatalf("CreateTask failed: %v", err)
+	}
+	if _, err := taskService.ExecuteTask(task.ID, models.RunTriggerAPI); err != nil {
+		t.Fatalf("ExecuteTask failed: %v", err)
+	}
+
+	// Wait for task completion
+	var finished models.Task
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if time.Now().After(deadline) {
+			t.Fatalf("Task timeout")
+		}
+		db.First(&finished, task.ID)
+		if finished.Status == models.TaskStatusFailed {
+			t.Fatalf("Task failed: %s", finished.ErrorMsg)
+		}
+		if finished.Status == models.TaskStatusCompleted {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	// 3. Existing rows are updated, the new row inserted and the bad row rejected
+	var result models.TaskResult
+	json.Unmarshal([]byte(finished.Result), &result)
+	if result.RejectedCount != 1 {
+		t.Errorf("Expected 1 rejected row, got %d", result.RejectedCount)
+	}
+
+	var total, updated int64
+	target.Raw("SELECT COUNT(*) FROM users").Scan(&total)
+	target.Raw("SELECT COUNT(*) FROM users WHERE name = 'new'").Scan(&updated)
+	if total != 4 || updated != 4 {
+		t.Errorf("Expected 4 rows all named 'new', got %d rows with %d updated", total, updated)
+	}
+
+	content, err := os.ReadFile(rejectFile)
+	if err != nil {
+		t.Fatalf("Failed to read reject file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], `"table":"users"`) {
+		t.Errorf("Expected one rejected record for users, got %q", string(content))
+	}
+
+	// Cleanup
+	for _, p := range []string{dbPath, targetPath, rejectFile} {
+		os.Remove(p)
+	}
+	fmt.Println("TestUpsertWithRejectFile Passed!")
+}
+
+func TestDatabaseRunTransaction(t *testing.T) {
+	dbPath := "test_run_tx.db"
+	targetPath := "test_run_tx_target.db"
+	rejectFile := "test_run_tx_rejects.jsonl"
+	for _, p := range []string{dbPath, targetPath, rejectFile} {
+		os.Remove(p)
+		defer os.Remove(p)
+	}
+	config.AppConfig = &config.Config{DBPath: dbPath, GenerateDir: "."}
+	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	models.DB = db
+	if err := db.AutoMigrate(&models.DataSource{}, &models.Task{}, &models.TaskRun{}, &models.TaskRunLog{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	target, err := gorm.Open(sqlite.Open(targetPath), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open target: %v", err)
+	}
+	target.Exec("CREATE TABLE events (id INTEGER, name TEXT)")
+	target.Exec("CREATE TABLE checked (id INTEGER, name TEXT CHECK (name <> 'bad'))")
+	ds := models.DataSource{Name: "target", Type: "sqlite", Database: targetPath}
+	db.Create(&ds)
+
+	taskService := services.NewTaskService()
+	// 25000 rows are written in batches of 10000; the script fails in the second batch
+	runTable := func(name, tableName, script, configuration string) error {
+		task := models.Task{
+			Name:          name,
+			Type:          models.TaskTypeDatabase,
+			DataSourceID:  &ds.ID,
+			TableName:     tableName,
+			Count:         25000,
+			FieldRules:    `{"id":{"type":"sequence"},"name":{"type":"custom","parameters":{"script":"` + script + `"}}}`,
+			OutputType:    models.OutputTypeDatabase,
+			Configuration: configuration,
+		}
+		db.Create(&task)
+		return taskService.ExecuteTaskSync(task.ID, models.RunTriggerAPI)
+	}
+	run := func(name, configuration string) error {
+		return runTable(name, "events", "rowIndex == 15000 ? undefinedValue : 'ok'", configuration)
+	}
+	count := func() int64 {
+		var total int64
+		target.Raw("SELECT COUNT(*) FROM events").Scan(&total)
+		return total
+	}
+
+	// 1. A failed run rolls back every batch written so far
+	if err := run("single transaction", ""); err == nil {
+		t.Fatalf("Expected the run to fail")
+	}
+	if total := count(); total != 0 {
+		t.Errorf("Expected the target table to be empty after a failed run, got %d rows", total)
+	}
+
+	// 2. commitEachBatch keeps the batches committed before the failure
+	if err := run("each batch", `{"commitEachBatch":true}`); err == nil {
+		t.Fatalf("Expected the run to fail")
+	}
+	if total := count(); total != 10000 {
+		t.Errorf("Expected the first batch to be kept, got %d rows", total)
+	}
+
+	// 3. Clearing the table in truncate mode is rolled back together with the failed load
+	if err := run("truncate", `{"writeMode":"truncate"}`); err == nil {
+		t.Fatalf("Expected the run to fail")
+	}
+	if total := count(); total != 10000 {
+		t.Errorf("Expected existing rows to survive a failed truncate run, got %d rows", total)
+	}
+
+	// 4. Rows rejected in a rolled-back run are not written to the reject file
+	script := "rowIndex == 5 ? 'bad' : rowIndex == 15000 ? undefinedValue : 'ok'"
+	rejectConfig := `{"errorPolicy":"reject_file","rejectFile":"` + rejectFile + `"`
+	if err := runTable("rejects rolled back", "checked", script, rejectConfig+`}`); err == nil {
+		t.Fatalf("Expected the run to fail")
+	}
+	if _, err := os.Stat(rejectFile); !os.IsNotExist(err) {
+		t.Errorf("Expected no reject file after a rolled-back run, got %v", err)
+	}
+	if err := runTable("rejects committed", "checked", script, rejectConfig+`,"commitEachBatch":true}`); err == nil {
+		t.Fatalf("Expected the run to fail")
+	}
+	content, err := os.ReadFile(rejectFile)
+	if err != nil || len(strings.Split(strings.TrimSpace(string(content)), "\n")) != 1 {
+		t.Errorf("Expected the committed batch to record 1 rejected row, got %q %v", content, err)
+	}
+
+	fmt.Println("TestDatabaseRunTransaction Passed!")
+}
@@ -0,0 +1,111 @@
+package test
+
+import (
+	"fmt"
+	"generateTestData/backend/config"
+	"generateTestData/backend/services"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestXLSXExport(t *testing.T) {
+	config.AppConfig = &config.Config{GenerateDir: "."}
+	exportService := services.NewExportService()
+
+	fileName := "test_export.xlsx"
+	defer os.Remove(fileName)
+
+	// 1. Two tables with at most 3 data rows per sheet
+	writer, err := exportService.NewXLSXWriter(fileName, &services.XLSXOptions{SheetRows: 3, TextColumns: []string{"code"}})
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	defer writer.Close()
+
+	if err := writer.StartSheet("users", []string{"id", "id_card", "score", "code", "active"}); err != nil {
+		t.Fatalf("Failed to start sheet: %v", err)
+	}
+	for batch := 0; batch < 2; batch++ {
+		records := make([]map[string]interface{}, 2)
+		for i := range records {
+			records[i] = map[string]interface{}{
+				"id":      batch*2 + i + 1,
+				"id_card": "11010519491231002X",
+				"score":   88.5,
+				"code":    "42",
+				"active":  true,
+			}
+		}
+		if err := writer.WriteBatch(records); err != nil {
+			t.Fatalf("Failed to write batch: %v", err)
+		}
+	}
+
+	if err := writer.StartSheet("orders:2024", []string{"order_no", "amount"}); err != nil {
+		t.Fatalf("Failed to start sheet: %v", err)
+	}
+	if err := writer.WriteBatch([]map[string]interface{}{{"order_no": "0012345", "amount": int64(100)}}); err != nil {
+		t.Fatalf("Failed to write batch: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+
+	file, err := excelize.OpenFile(fileName)
+	if err != nil {
+		t.Fatalf("Failed to open xlsx: %v", err)
+	}
+	defer file.Close()
+
+	// 4 user rows roll over into a second sheet; invalid characters are replaced
+	expectedSheets := []string{"users", "users (2)", "orders_2024"}
+	if sheets := file.GetSheetList(); !reflect.DeepEqual(sheets, expectedSheets) {
+		t.Fatalf("Expected sheets %v, got %v", expectedSheets, sheets)
+	}
+	rows, _ := file.GetRows("users")
+	if len(rows) != 4 || rows[0][0] != "id" {
+		t.Errorf("Expected header and 3 data rows on the first sheet, got %v", rows)
+	}
+	rows, _ = file.GetRows("users (2)")
+	if len(rows) != 2 || rows[1][0] != "4" {
+		t.Errorf("Expected header and the 4th row on the second sheet, got %v", rows)
+	}
+
+	// 2. Typed cells: numbers and booleans stay typed, long digit strings are text
+	cellType := func(sheet, cell string) excelize.CellType {
+		cellType, err := file.GetCellType(sheet, cell)
+		if err != nil {
+			t.Fatalf("Failed to read cell %s!%s: %v", sheet, cell, err)
+		}
+		return cellType
+	}
+	if cellType("users", "A2") != excelize.CellTypeUnset || cellType("users", "C2") != excelize.CellTypeUnset {
+		t.Errorf("Expected numeric cells for id and score")
+	}
+	if cellType("users", "E2") != excelize.CellTypeBool {
+		t.Errorf("Expected boolean cell for active")
+	}
+	if value, _ := file.GetCellValue("users", "B2"); value != "11010519491231002X" || cellType("users", "B2") == excelize.CellTypeUnset {
+		t.Errorf("Expected ID card number stored as text, got %q", value)
+	}
+	if value, _ := file.GetCellValue("orders_2024", "A2"); value != "0012345" {
+		t.Errorf("Expected leading zeros to be kept, got %q", value)
+	}
+
+	// Text columns use the "@" number format
+	style, _ := file.GetCellStyle("users", "D2")
+	if format, err := file.GetStyle(style); err != nil || format.NumFmt != 49 {
+		t.Errorf("Expected text format on configured text column")
+	}
+
+	// 3. Header is styled
+	style, _ = file.GetCellStyle("users", "A1")
+	if header, err := file.GetStyle(style); err != nil || header.Font == nil || !header.Font.Bold {
+		t.Errorf("Expected bold header style")
+	}
+
+	fmt.Println("TestXLSXExport Passed!")
+}
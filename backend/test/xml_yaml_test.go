@@ -0,0 +1,127 @@
+package test
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"generateTestData/backend/config"
+	"generateTestData/backend/services"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestXMLAndYAMLExport(t *testing.T) {
+	config.AppConfig = &config.Config{GenerateDir: "."}
+	exportService := services.NewExportService()
+
+	batch := func(start int) []map[string]interface{} {
+		records := make([]map[string]interface{}, 2)
+		for i := range records {
+			records[i] = map[string]interface{}{
+				"id":   start + i,
+				"user": map[string]interface{}{"name": fmt.Sprintf("user%d", start+i), "level": "GOLD"},
+				"tags": []interface{}{"a", "b"},
+				"note": nil,
+			}
+		}
+		return records
+	}
+
+	// 1. XML with custom element names, attributes and namespaces, appended across batches
+	xmlFile := "test_export.xml"
+	defer os.Remove(xmlFile)
+
+	options := &services.XMLOptions{
+		RootElement:   "soap:Users",
+		RecordElement: "User",
+		Attributes:    []string{"id", "user.level"},
+		Namespace:     "http://example.com/users",
+		Namespaces:    map[string]string{"soap": "http://schemas.xmlsoap.org/soap/envelope/"},
+	}
+	xmlWriter, err := exportService.NewXMLWriter(xmlFile, options)
+	if err != nil {
+		t.Fatalf("Failed to create XML writer: %v", err)
+	}
+	for _, start := range []int{1, 3} {
+		if err := xmlWriter.WriteBatch(batch(start)); err != nil {
+			t.Fatalf("Failed to export XML: %v", err)
+		}
+	}
+	if err := xmlWriter.Close(); err != nil {
+		t.Fatalf("Failed to close XML writer: %v", err)
+	}
+
+	data, err := os.ReadFile(xmlFile)
+	if err != nil {
+		t.Fatalf("Failed to read XML file: %v", err)
+	}
+	content := string(data)
+	if !strings.HasPrefix(content, `<?xml version="1.0" encoding="UTF-8"?>`) ||
+		!strings.Contains(content, `<soap:Users xmlns="http://example.com/users" xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">`) ||
+		!strings.HasSuffix(content, "</soap:Users>\n") {
+		t.Errorf("Unexpected XML document:\n%s", content)
+	}
+	if !strings.Contains(content, `<User id="4">`) || !strings.Contains(content, `<user level="GOLD">`) {
+		t.Errorf("Expected id and user.level as attributes:\n%s", content)
+	}
+
+	var users struct {
+		Users []struct {
+			ID   int      `xml:"id,attr"`
+			Name string   `xml:"user>name"`
+			Tags []string `xml:"tags"`
+		} `xml:"User"`
+	}
+	if err := xml.Unmarshal(data, &users); err != nil {
+		t.Fatalf("Failed to parse XML: %v", err)
+	}
+	if len(users.Users) != 4 || users.Users[3].ID != 4 || users.Users[3].Name != "user4" || len(users.Users[3].Tags) != 2 {
+		t.Errorf("Unexpected XML records: %+v", users.Users)
+	}
+
+	// 2. YAML multi-document stream, appended across batches
+	yamlFile := "test_export.yaml"
+	defer os.Remove(yamlFile)
+	yamlWriter, err := exportService.NewYAMLWriter(yamlFile)
+	if err != nil {
+		t.Fatalf("Failed to create YAML writer: %v", err)
+	}
+	for _, start := range []int{1, 3} {
+		if err := yamlWriter.WriteBatch(batch(start)); err != nil {
+			t.Fatalf("Failed to export YAML: %v", err)
+		}
+	}
+	if err := yamlWriter.Close(); err != nil {
+		t.Fatalf("Failed to close YAML writer: %v", err)
+	}
+
+	f, err := os.Open(yamlFile)
+	if err != nil {
+		t.Fatalf("Failed to open YAML file: %v", err)
+	}
+	defer f.Close()
+	decoder := yaml.NewDecoder(f)
+	var documents []map[string]interface{}
+	for {
+		var document map[string]interface{}
+		if err := decoder.Decode(&document); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			t.Fatalf("Failed to decode YAML document: %v", err)
+		}
+		documents = append(documents, document)
+	}
+	if len(documents) != 4 {
+		t.Fatalf("Expected 4 YAML documents, got %d", len(documents))
+	}
+	user, _ := documents[3]["user"].(map[string]interface{})
+	if documents[3]["id"] != 4 || user["name"] != "user4" || documents[3]["note"] != nil {
+		t.Errorf("Unexpected YAML document: %v", documents[3])
+	}
+
+	fmt.Println("TestXMLAndYAMLExport Passed!")
+}
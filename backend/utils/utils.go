@@ -154,4 +154,27 @@ func FormatTime(t time.Time, layout string) string {
 		layout = "2006-01-02 15:04:05"
 	}
 	return t.Format(layout)
+}
+
+// 格式化 MySQL LOAD DATA 的字段值：NULL 写为 \N，反斜杠、制表符、换行等按 ESCAPED BY '\\' 转义
+func FormatLoadDataField(value interface{}) string {
+	var str string
+	switch v := value.(type) {
+	case nil:
+		return `\N`
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case time.Time:
+		str = v.Format("2006-01-02 15:04:05.999999")
+	case []byte:
+		str = string(v)
+	default:
+		str = fmt.Sprintf("%v", v)
+	}
+
+	replacer := strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`, "\x00", `\0`)
+	return replacer.Replace(str)
 }
\ No newline at end of file
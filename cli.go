@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"generateTestData/backend/config"
+	"generateTestData/backend/models"
+	"generateTestData/backend/services"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const cliUsage = `用法:
+  generateTestData                          启动Web服务
+  generateTestData run <任务文件> [选项]      执行任务
+  generateTestData validate <任务文件>        校验任务配置
+  generateTestData preview <任务文件> [选项]  生成少量数据输出到标准输出
+  generateTestData export <目录>              导出任务、模板和规则库到工作区目录
+  generateTestData apply <目录> [--dry-run]   按工作区目录新增、更新或删除任务、模板和规则库
+
+run 选项:
+  --count N        覆盖生成数量
+  --out PATH       覆盖输出文件，未指定 outputType 时按扩展名推断
+  --type TYPE      覆盖输出类型
+
+preview 选项:
+  --count N        生成数量，默认5
+  --format FORMAT  jsonl（默认）, csv, sql
+
+apply 选项:
+  --dry-run        只显示变更，不修改数据库
+
+任务文件为YAML或JSON，字段与创建任务接口一致。run、validate、preview 使用内存数据库，
+export、apply 读写 data.db。
+`
+
+// 按扩展名推断的文件输出类型
+var outputTypesByExtension = map[string]models.OutputType{
+	".csv":     models.OutputTypeCSV,
+	".json":    models.OutputTypeJSON,
+	".jsonl":   models.OutputTypeJSONL,
+	".ndjson":  models.OutputTypeJSONL,
+	".sql":     models.OutputTypeSQL,
+	".txt":     models.OutputTypeTXT,
+	".parquet": models.OutputTypeParquet,
+	".avro":    models.OutputTypeAvro,
+	".xlsx":    models.OutputTypeXLSX,
+	".xml":     models.OutputTypeXML,
+	".yaml":    models.OutputTypeYAML,
+	".yml":     models.OutputTypeYAML,
+}
+
+// 命令行模式入口，返回进程退出码：0 成功，1 执行失败，2 用法错误
+func runCLI(args []string, stdout, stderr io.Writer) int {
+	var run func(file string) error
+	initDB := models.InitMemoryDB
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { fmt.Fprint(stderr, cliUsage) }
+
+	switch args[0] {
+	case "run":
+		count := fs.Int64("count", 0, "")
+		out := fs.String("out", "", "")
+		outputType := fs.String("type", "", "")
+		run = func(file string) error {
+			return cliRun(file, *count, *out, models.OutputType(*outputType), stderr)
+		}
+	case "validate":
+		run = func(file string) error {
+			return cliValidate(file, stderr)
+		}
+	case "preview":
+		count := fs.Int64("count", 5, "")
+		format := fs.String("format", "jsonl", "")
+		run = func(file string) error {
+			return cliPreview(file, *count, *format, stdout)
+		}
+	case "export":
+		initDB = models.InitDB
+		run = func(dir string) error {
+			if err := services.NewWorkspaceService().Export(dir); err != nil {
+				return err
+			}
+			fmt.Fprintf(stderr, "已导出到: %s\n", dir)
+			return nil
+		}
+	case "apply":
+		dryRun := fs.Bool("dry-run", false, "")
+		initDB = models.InitDB
+		run = func(dir string) error {
+			return cliApply(dir, *dryRun, stdout, stderr)
+		}
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, cliUsage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "未知命令: %s\n\n%s", args[0], cliUsage)
+		return 2
+	}
+
+	// 任务文件（或目录）前后都可以写选项
+	var file string
+	rest := args[1:]
+	for {
+		if err := fs.Parse(rest); err != nil {
+			return 2
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		if file != "" {
+			fmt.Fprintf(stderr, "多余的参数: %s\n", fs.Arg(0))
+			return 2
+		}
+		file = fs.Arg(0)
+		rest = fs.Args()[1:]
+	}
+	if file == "" {
+		fmt.Fprintf(stderr, "缺少任务文件或目录\n\n%s", cliUsage)
+		return 2
+	}
+
+	// 不启动Web服务
+	config.AppConfig = &config.Config{GenerateDir: "."}
+	initDB()
+
+	if err := run(file); err != nil {
+		fmt.Fprintf(stderr, "错误: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// 读取任务文件并转换为任务
+func loadCLITask(file string) (*models.Task, error) {
+	taskFile, err := services.LoadTaskFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return taskFile.Task()
+}
+
+// 执行任务，进度和结果输出到标准错误
+func cliRun(file string, count int64, out string, outputType models.OutputType, stderr io.Writer) error {
+	task, err := loadCLITask(file)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		task.Count = count
+	}
+	if outputType != "" {
+		task.OutputType = outputType
+	}
+	if out != "" {
+		task.OutputPath = out
+		if outputType == "" {
+			if inferred, ok := outputTypesByExtension[strings.ToLower(filepath.Ext(out))]; ok {
+				task.OutputType = inferred
+			}
+		}
+	}
+
+	taskService := services.NewTaskService()
+	if err := taskService.ValidateTask(task); err != nil {
+		return err
+	}
+
+	// 输出文件的目录作为生成目录，文件名相对于生成目录
+	if task.OutputPath != "" && !strings.Contains(task.OutputPath, "://") {
+		dir := filepath.Dir(task.OutputPath)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("创建输出目录失败: %v", err)
+		}
+		config.AppConfig.GenerateDir = dir
+		task.OutputPath = filepath.Base(task.OutputPath)
+	}
+
+	last := -1.0
+	result, err := taskService.RunTask(task, func(progress float64) {
+		if progress != last {
+			last = progress
+			fmt.Fprintf(stderr, "进度: %.0f%%\n", progress)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stderr, "完成: 共 %d 行，耗时 %v，%.0f 行/秒\n", result.GeneratedCount, result.Duration, result.RowsPerSecond)
+	if result.RejectedCount > 0 {
+		fmt.Fprintf(stderr, "跳过: %d 行\n", result.RejectedCount)
+	}
+	for _, name := range result.Files {
+		fmt.Fprintf(stderr, "文件: %s\n", filepath.Join(config.AppConfig.GenerateDir, name))
+	}
+	return nil
+}
+
+// 校验任务配置和字段规则
+func cliValidate(file string, stderr io.Writer) error {
+	task, err := loadCLITask(file)
+	if err != nil {
+		return err
+	}
+	if err := services.NewTaskService().ValidateTask(task); err != nil {
+		return err
+	}
+	if _, err := task.GetFieldRules(); err != nil {
+		return fmt.Errorf("解析字段规则失败: %v", err)
+	}
+	if _, err := task.GetUniqueFields(); err != nil {
+		return fmt.Errorf("解析唯一字段失败: %v", err)
+	}
+	fmt.Fprintf(stderr, "任务配置有效: %s\n", task.Name)
+	return nil
+}
+
+// 生成少量数据输出到标准输出，不写文件
+func cliPreview(file string, count int64, format string, stdout io.Writer) error {
+	task, err := loadCLITask(file)
+	if err != nil {
+		return err
+	}
+	stream, err := services.NewTaskService().NewGenerationStream(&services.StreamRequest{
+		Task:   *task,
+		Count:  count,
+		Format: format,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = stream.Run(context.Background(), stdout, nil)
+	return err
+}
+
+// 按工作区目录同步数据库，变更输出到标准输出
+func cliApply(dir string, dryRun bool, stdout, stderr io.Writer) error {
+	changes, err := services.NewWorkspaceService().Apply(dir, dryRun)
+	if err != nil {
+		return err
+	}
+	actions := map[string]string{"create": "新增", "update": "更新", "delete": "删除"}
+	for _, change := range changes {
+		fmt.Fprintf(stdout, "%s %s/%s\n%s\n", actions[change.Action], change.Kind, change.Name, change.Diff)
+	}
+
+	switch {
+	case len(changes) == 0:
+		fmt.Fprintln(stderr, "无变更")
+	case dryRun:
+		fmt.Fprintf(stderr, "共 %d 项变更（dry-run，未修改数据库）\n", len(changes))
+	default:
+		fmt.Fprintf(stderr, "已应用 %d 项变更\n", len(changes))
+	}
+	return nil
+}
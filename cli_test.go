@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunCLI(t *testing.T) {
+	dir := t.TempDir()
+	taskFile := filepath.Join(dir, "users.yaml")
+	os.WriteFile(taskFile, []byte(`name: users
+type: csv
+count: 1000
+jsonSchema:
+  - {name: id, type: int}
+  - {name: name, type: varchar}
+fieldRules:
+  id: {type: sequence, value: 1}
+outputType: csv
+outputPath: users.csv
+`), 0644)
+	invalidFile := filepath.Join(dir, "invalid.yaml")
+	os.WriteFile(invalidFile, []byte("name: invalid\ntype: csv\ncount: 10\njsonSchema: [{name: id, type: int}]\noutputType: fax\noutputPath: invalid.fax\n"), 0644)
+
+	cli := func(args ...string) (int, string, string) {
+		var stdout, stderr bytes.Buffer
+		code := runCLI(args, &stdout, &stderr)
+		return code, stdout.String(), stderr.String()
+	}
+
+	// 1. validate reports a valid task on stderr and fails with exit code 1 for an invalid one
+	code, stdout, stderr := cli("validate", taskFile)
+	if code != 0 || stdout != "" || !strings.Contains(stderr, "任务配置有效: users") {
+		t.Errorf("validate: unexpected result %d %q %q", code, stdout, stderr)
+	}
+	code, _, stderr = cli("validate", invalidFile)
+	if code != 1 || stderr != "错误: 不支持的输出类型: fax\n" {
+		t.Errorf("validate invalid: expected exit code 1 with the unknown output type, got %d %q", code, stderr)
+	}
+
+	// 2. preview writes only the generated records to stdout
+	code, stdout, stderr = cli("preview", taskFile, "--count", "3")
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if code != 0 || stderr != "" || len(lines) != 3 {
+		t.Fatalf("preview: unexpected result %d %q %q", code, stdout, stderr)
+	}
+	for i, line := range lines {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil || record["id"] != fmt.Sprint(i+1) {
+			t.Errorf("preview: unexpected record %q", line)
+		}
+	}
+	code, stdout, _ = cli("preview", "--format", "csv", "--count", "2", taskFile)
+	if code != 0 || len(strings.Split(strings.TrimSpace(stdout), "\n")) != 3 || !strings.Contains(stdout, "id") {
+		t.Errorf("preview csv: expected a header and 2 rows, got %d %q", code, stdout)
+	}
+
+	// 3. run writes the file, reports progress and the result on stderr and nothing on stdout
+	out := filepath.Join(dir, "out", "users.csv")
+	code, stdout, stderr = cli("run", taskFile, "--count", "10", "--out", out)
+	if code != 0 || stdout != "" {
+		t.Fatalf("run: unexpected result %d %q %q", code, stdout, stderr)
+	}
+	if !strings.Contains(stderr, "进度: 100%") || !strings.Contains(stderr, "完成: 共 10 行") || !strings.Contains(stderr, "文件: "+out) {
+		t.Errorf("run: unexpected stderr %q", stderr)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil || len(strings.Split(strings.TrimSpace(string(data)), "\n")) != 11 {
+		t.Errorf("run: expected a header and 10 rows in %s, got %q %v", out, data, err)
+	}
+	code, _, stderr = cli("run", filepath.Join(dir, "missing.yaml"))
+	if code != 1 || !strings.HasPrefix(stderr, "错误: ") {
+		t.Errorf("run missing file: expected exit code 1, got %d %q", code, stderr)
+	}
+
+	// 4. Usage errors exit with code 2
+	for _, args := range [][]string{{"generate", taskFile}, {"run"}, {"run", taskFile, "extra.yaml"}, {"preview", taskFile, "--count", "x"}} {
+		if code, _, _ := cli(args...); code != 2 {
+			t.Errorf("%v: expected exit code 2, got %d", args, code)
+		}
+	}
+
+	fmt.Println("TestRunCLI Passed!")
+}
@@ -29,7 +29,22 @@ export const taskApi = {
 
   // 执行任务
   execute(id) {
-    return request.post(`/tasks/${id}/execute`)
+    return request.post(`/tasks/${id}/execute`, null, { params: { trigger: 'manual' } })
+  },
+
+  // 获取执行记录
+  getRuns(id) {
+    return request.get(`/tasks/${id}/runs`)
+  },
+
+  // 获取执行日志，params 支持 level、field、q
+  getRunLogs(id, runId, params) {
+    return request.get(`/tasks/${id}/runs/${runId}/logs`, { params })
+  },
+
+  // 按历史执行的配置重新执行
+  rerun(id, runId) {
+    return request.post(`/tasks/${id}/runs/${runId}/rerun`, null, { params: { trigger: 'manual' } })
   },
 
   // 获取任务状态
@@ -4,25 +4,43 @@ import (
 	"generateTestData/backend/config"
 	"generateTestData/backend/controllers"
 	"generateTestData/backend/models"
+	"generateTestData/backend/services"
 	"log"
+	"os"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
+	// 带子命令时以命令行模式运行，不启动Web服务
+	if len(os.Args) > 1 {
+		os.Exit(runCLI(os.Args[1:], os.Stdout, os.Stderr))
+	}
+
 	// 初始化配置
 	config.InitConfig()
 
 	// 初始化数据库
 	models.InitDB()
 
+	// 恢复执行队列，继续执行上次退出时排队中的任务
+	if err := services.StartTaskQueue(); err != nil {
+		log.Printf("恢复执行队列失败: %v", err)
+	}
+
+	// 启动定时调度，补跑停机期间错过的执行，并每天清理过期的执行日志
+	scheduler := services.NewSchedulerService()
+	if err := scheduler.Start(); err != nil {
+		log.Printf("启动定时调度失败: %v", err)
+	}
+
 	// 创建Gin引擎
 	r := gin.Default()
 
 	// 设置CORS中间件
 	r.Use(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -39,6 +57,9 @@ func main() {
 	dataSourceController := controllers.NewDataSourceController()
 	taskController := controllers.NewTaskController()
 	fileController := controllers.NewFileController()
+	mockController := controllers.NewMockController()
+	generateController := controllers.NewGenerateController()
+	scheduleController := controllers.NewScheduleController(scheduler)
 
 	// API路由组
 	api := r.Group("/api")
@@ -65,6 +86,11 @@ func main() {
 			tasks.PUT("/:id", taskController.Update)
 			tasks.POST("/:id/execute", taskController.Execute)
 			tasks.GET("/:id/status", taskController.GetStatus)
+			tasks.GET("/:id/events", taskController.Events)
+			tasks.GET("/:id/runs", taskController.Runs)
+			tasks.GET("/:id/runs/:run", taskController.GetRun)
+			tasks.POST("/:id/runs/:run/rerun", taskController.Rerun)
+			tasks.GET("/:id/runs/:run/logs", taskController.RunLogs)
 			tasks.DELETE("/:id", taskController.Delete)
 			tasks.POST("/preview", taskController.Preview)
 			tasks.POST("/:id/export-template", taskController.ExportTemplate)
@@ -78,8 +104,41 @@ func main() {
 			templates.DELETE("/:id", taskController.DeleteTemplate)
 		}
 
+		// 执行队列和任务事件
+		api.GET("/queue", taskController.Queue)
+		api.GET("/events", taskController.AllEvents)
+
+		// 定时调度
+		schedules := api.Group("/schedules")
+		{
+			schedules.GET("", scheduleController.List)
+			schedules.POST("", scheduleController.Create)
+			schedules.PUT("/:id", scheduleController.Update)
+			schedules.DELETE("/:id", scheduleController.Delete)
+		}
+
 		// 文件下载
 		api.GET("/download/:filename", fileController.Download)
+		// 上传结构定义文件（.proto、.avsc）
+		api.POST("/upload", fileController.Upload)
+
+		// 流式生成数据
+		api.POST("/generate/stream", generateController.Stream)
+
+		// 内置Mock接口的数据集管理
+		api.GET("/mock", mockController.Collections)
+		api.DELETE("/mock/:name", mockController.DeleteCollection)
+	}
+
+	// 内置Mock接口，数据由 mock_api 输出的任务发布
+	mock := r.Group("/mock/:name", mockController.Simulate)
+	{
+		mock.GET("", mockController.List)
+		mock.POST("", mockController.Create)
+		mock.GET("/:id", mockController.Get)
+		mock.PUT("/:id", mockController.Update)
+		mock.PATCH("/:id", mockController.Update)
+		mock.DELETE("/:id", mockController.Delete)
 	}
 
 	log.Printf("服务器启动在端口 :%s", config.AppConfig.Port)